@@ -0,0 +1,165 @@
+package proto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// The ServerAddress of a Handshake is not always a plain hostname: mod loaders
+// and proxies append data to it, separated by NUL characters.
+
+// ModLoaderMarker is the marker appended to the server address by Forge clients.
+type ModLoaderMarker string
+
+const (
+	// ForgeFML is appended by Forge clients up to 1.12.
+	ForgeFML ModLoaderMarker = "FML"
+	// ForgeFML2 is appended by Forge clients from 1.13 to 1.17.
+	ForgeFML2 ModLoaderMarker = "FML2"
+	// ForgeFML3 is appended by Forge clients from 1.18.
+	ForgeFML3 ModLoaderMarker = "FML3"
+)
+
+// bungeeCordExtraData is the name of the forwarded property that carries the
+// data appended by mod loaders, with NUL characters replaced by \x01.
+const bungeeCordExtraData = "extraData"
+
+// --- HandshakeAddress ---
+
+// HandshakeAddress is the ServerAddress of a Handshake split into its parts.
+type HandshakeAddress struct {
+	// Host is the hostname the client connected to, without trailing dot.
+	Host string
+	// ModLoader is the marker appended by the mod loader of the client, if any.
+	ModLoader ModLoaderMarker
+	// Forwarding is the player information forwarded by a BungeeCord proxy, if any.
+	Forwarding *BungeeCordForwarding
+	// Extra holds the unrecognized parts of the address.
+	Extra []string
+}
+
+// BungeeCordForwarding is the player information forwarded by a BungeeCord
+// proxy in legacy IP forwarding mode: host\0clientIP\0uuid\0propertiesJSON.
+type BungeeCordForwarding struct {
+	ClientIP   net.IP
+	UUID       UUID
+	Properties []ProfileProperty
+}
+
+// ParseHandshakeAddress parses the ServerAddress of a Handshake.
+func ParseHandshakeAddress(address string) (*HandshakeAddress, error) {
+	parts := strings.Split(address, "\x00")
+	a := &HandshakeAddress{Host: strings.TrimSuffix(parts[0], ".")}
+	parts = parts[1:]
+
+	// Markers are terminated by a NUL character
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	if len(parts) >= 2 {
+		if id, err := uuid.Parse(parts[1]); err == nil {
+			f, extra, err := parseBungeeCordForwarding(parts[0], id, parts[2:])
+			if err != nil {
+				return nil, err
+			}
+			a.Forwarding = f
+			parts = extra
+		}
+	}
+
+	for _, part := range parts {
+		switch marker := ModLoaderMarker(part); marker {
+		case "":
+		case ForgeFML, ForgeFML2, ForgeFML3:
+			a.ModLoader = marker
+		default:
+			a.Extra = append(a.Extra, part)
+		}
+	}
+
+	return a, nil
+}
+
+// parseBungeeCordForwarding parses the forwarded client IP, UUID and properties,
+// and returns the parts of the address following them.
+func parseBungeeCordForwarding(ip string, id uuid.UUID, parts []string) (*BungeeCordForwarding, []string, error) {
+	f := &BungeeCordForwarding{ClientIP: net.ParseIP(ip), UUID: UUID(id)}
+	if f.ClientIP == nil {
+		return nil, nil, fmt.Errorf("bungeecord forwarding: invalid client IP %q", ip)
+	}
+
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "[") {
+		return f, parts, nil
+	}
+
+	var properties []ProfileProperty
+	if err := json.Unmarshal([]byte(parts[0]), &properties); err != nil {
+		return nil, nil, fmt.Errorf("bungeecord forwarding: invalid properties: %w", err)
+	}
+	parts = parts[1:]
+
+	for _, property := range properties {
+		if property.Name != bungeeCordExtraData {
+			f.Properties = append(f.Properties, property)
+			continue
+		}
+		for _, part := range strings.Split(property.Value, "\x01") {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	return f, parts, nil
+}
+
+// String builds the ServerAddress of a Handshake.
+func (a *HandshakeAddress) String() string {
+	var extra string
+	if a.ModLoader != "" {
+		extra += "\x00" + string(a.ModLoader)
+	}
+	for _, part := range a.Extra {
+		extra += "\x00" + part
+	}
+	if extra != "" {
+		extra += "\x00"
+	}
+
+	if a.Forwarding == nil {
+		return a.Host + extra
+	}
+
+	f := a.Forwarding
+	address := a.Host + "\x00" + f.ClientIP.String() + "\x00" + hex.EncodeToString(f.UUID[:])
+
+	properties := f.Properties
+	if extra != "" {
+		properties = append(properties[:len(properties):len(properties)], ProfileProperty{
+			Name:  bungeeCordExtraData,
+			Value: strings.ReplaceAll(extra, "\x00", "\x01"),
+		})
+	}
+	if len(properties) > 0 {
+		b, _ := json.Marshal(properties)
+		address += "\x00" + string(b)
+	}
+
+	return address
+}
+
+// Address parses the ServerAddress of the Handshake.
+func (h *Handshake) Address() (*HandshakeAddress, error) {
+	return ParseHandshakeAddress(string(h.ServerAddress))
+}
+
+// SetAddress sets the ServerAddress of the Handshake.
+func (h *Handshake) SetAddress(a *HandshakeAddress) {
+	h.ServerAddress = String(a.String())
+}
@@ -0,0 +1,78 @@
+package proto
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestHandshakeAddress(t *testing.T) {
+	id := UUID{0x06, 0x9a, 0x79, 0xf4, 0x44, 0xe9, 0x47, 0x26, 0xa5, 0xbe, 0xfc, 0xa9, 0x0e, 0x38, 0xaa, 0xf5}
+	textures := ProfileProperty{Name: "textures", Value: "e30=", Signature: "c2ln"}
+	forwarding := "example.com\x00192.168.0.5\x00069a79f444e94726a5befca90e38aaf5"
+
+	tests := []struct {
+		address string
+		want    HandshakeAddress
+		// built is the address built from want, if different from address
+		built string
+	}{
+		{"example.com", HandshakeAddress{Host: "example.com"}, ""},
+		{"example.com.", HandshakeAddress{Host: "example.com"}, "example.com"},
+		{"example.com\x00FML\x00", HandshakeAddress{Host: "example.com", ModLoader: ForgeFML}, ""},
+		{"example.com.\x00FML2\x00", HandshakeAddress{Host: "example.com", ModLoader: ForgeFML2}, "example.com\x00FML2\x00"},
+		{"example.com\x00FML3\x00", HandshakeAddress{Host: "example.com", ModLoader: ForgeFML3}, ""},
+		{"example.com\x00FML3\x00other\x00", HandshakeAddress{Host: "example.com", ModLoader: ForgeFML3, Extra: []string{"other"}}, ""},
+		{"example.com\x00other", HandshakeAddress{Host: "example.com", Extra: []string{"other"}}, "example.com\x00other\x00"},
+
+		// BungeeCord legacy forwarding
+		{forwarding, HandshakeAddress{Host: "example.com", Forwarding: &BungeeCordForwarding{ClientIP: net.IPv4(192, 168, 0, 5), UUID: id}}, ""},
+		{forwarding + "\x00" + `[{"name":"textures","value":"e30=","signature":"c2ln"}]`,
+			HandshakeAddress{Host: "example.com", Forwarding: &BungeeCordForwarding{ClientIP: net.IPv4(192, 168, 0, 5), UUID: id, Properties: []ProfileProperty{textures}}}, ""},
+		{"example.com\x002001:db8::1\x00069a79f4-44e9-4726-a5be-fca90e38aaf5\x00[]",
+			HandshakeAddress{Host: "example.com", Forwarding: &BungeeCordForwarding{ClientIP: net.ParseIP("2001:db8::1"), UUID: id}},
+			"example.com\x002001:db8::1\x00069a79f444e94726a5befca90e38aaf5"},
+		// The data of mod loaders are forwarded in a property
+		{forwarding + "\x00" + `[{"name":"textures","value":"e30=","signature":"c2ln"},{"name":"extraData","value":"\u0001FML2\u0001"}]`,
+			HandshakeAddress{Host: "example.com", ModLoader: ForgeFML2, Forwarding: &BungeeCordForwarding{ClientIP: net.IPv4(192, 168, 0, 5), UUID: id, Properties: []ProfileProperty{textures}}}, ""},
+	}
+	for _, test := range tests {
+		got, err := ParseHandshakeAddress(test.address)
+		if err != nil {
+			t.Errorf("%q: %v", test.address, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.address, *got, test.want)
+		}
+
+		built := test.built
+		if built == "" {
+			built = test.address
+		}
+		if s := test.want.String(); s != built {
+			t.Errorf("%q: built %q, want %q", test.address, s, built)
+		}
+	}
+
+	for _, address := range []string{
+		"example.com\x00not an IP\x00069a79f444e94726a5befca90e38aaf5",
+		forwarding + "\x00[{",
+	} {
+		if _, err := ParseHandshakeAddress(address); err == nil {
+			t.Errorf("%q: no error", address)
+		}
+	}
+}
+
+func TestHandshakeSetAddress(t *testing.T) {
+	var h Handshake
+	h.SetAddress(&HandshakeAddress{Host: "example.com", ModLoader: ForgeFML3})
+	if h.ServerAddress != "example.com\x00FML3\x00" {
+		t.Errorf("got %q", h.ServerAddress)
+	}
+	a, err := h.Address()
+	if err != nil || a.Host != "example.com" || a.ModLoader != ForgeFML3 {
+		t.Errorf("got %+v %v", a, err)
+	}
+}
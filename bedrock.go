@@ -0,0 +1,350 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bedrock Edition servers answer the status requests of clients with the
+// unconnected ping/pong exchange of RakNet, over UDP.
+
+// DefaultBedrockPort is the default port of Bedrock Edition servers.
+const DefaultBedrockPort = 19132
+
+// RakNet offline message IDs.
+const (
+	RakNetUnconnectedPing                = 0x01
+	RakNetUnconnectedPingOpenConnections = 0x02
+	RakNetUnconnectedPong                = 0x1C
+)
+
+// rakNetMagic identifies the offline messages of RakNet.
+var rakNetMagic = [16]byte{
+	0x00, 0xFF, 0xFF, 0x00, 0xFE, 0xFE, 0xFE, 0xFE,
+	0xFD, 0xFD, 0xFD, 0xFD, 0x12, 0x34, 0x56, 0x78,
+}
+
+const (
+	// rakNetPingLength is the length of an unconnected ping: ID, time, magic and client GUID.
+	rakNetPingLength = 1 + 8 + 16 + 8
+	// rakNetPongHeaderLength is the length of an unconnected pong before its string:
+	// ID, time, server GUID, magic and string length.
+	rakNetPongHeaderLength = 1 + 8 + 8 + 16 + 2
+	// rakNetMaxPacketSize is the maximum size of an offline message.
+	rakNetMaxPacketSize = 1500
+)
+
+// defaultBedrockTimeout is the timeout of a ping of a zero BedrockPinger.
+const defaultBedrockTimeout = 5 * time.Second
+
+// ErrInvalidRakNet is returned when a RakNet offline message is malformed.
+var ErrInvalidRakNet = errors.New("invalid RakNet offline message")
+
+// --- BedrockStatus ---
+
+// BedrockStatus is the status of a Bedrock Edition server, sent as a
+// semicolon-separated string in unconnected pongs.
+type BedrockStatus struct {
+	// Edition is MCPE for Bedrock Edition, MCEE for Education Edition.
+	Edition         string
+	MOTD            string
+	ProtocolVersion int
+	Version         string
+	Online          int
+	Max             int
+	// ServerGUID is the GUID of the server, as sent in the string.
+	ServerGUID int64
+	// SubMOTD is the second line of the MOTD, usually the level name.
+	SubMOTD    string
+	GameMode   string
+	GameModeID int
+	PortV4     int
+	PortV6     int
+}
+
+// String encodes the status to the string of unconnected pongs.
+func (s *BedrockStatus) String() string {
+	edition := s.Edition
+	if edition == "" {
+		edition = "MCPE"
+	}
+	fields := []string{
+		bedrockField(edition),
+		bedrockField(s.MOTD),
+		strconv.Itoa(s.ProtocolVersion),
+		bedrockField(s.Version),
+		strconv.Itoa(s.Online),
+		strconv.Itoa(s.Max),
+		strconv.FormatUint(uint64(s.ServerGUID), 10),
+		bedrockField(s.SubMOTD),
+		bedrockField(s.GameMode),
+		strconv.Itoa(s.GameModeID),
+		strconv.Itoa(s.PortV4),
+		strconv.Itoa(s.PortV6),
+	}
+	return strings.Join(fields, ";") + ";"
+}
+
+// bedrockField removes the separators from a field, as clients do not
+// unescape them
+func bedrockField(s string) string {
+	return strings.Replace(s, ";", "", -1)
+}
+
+// ParseBedrockStatus parses the string of unconnected pongs.
+// Old servers send fewer fields: missing fields are left zero.
+func ParseBedrockStatus(s string) (*BedrockStatus, error) {
+	fields := strings.Split(s, ";")
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("%w: %d fields in status", ErrInvalidRakNet, len(fields))
+	}
+
+	status := &BedrockStatus{
+		Edition: fields[0],
+		MOTD:    fields[1],
+		Version: fields[3],
+	}
+	var err error
+	if status.ProtocolVersion, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, fmt.Errorf("%w: protocol version: %v", ErrInvalidRakNet, err)
+	}
+	if status.Online, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, fmt.Errorf("%w: online players: %v", ErrInvalidRakNet, err)
+	}
+	if status.Max, err = strconv.Atoi(fields[5]); err != nil {
+		return nil, fmt.Errorf("%w: max players: %v", ErrInvalidRakNet, err)
+	}
+
+	// Optional fields are decoded leniently
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	guid, _ := strconv.ParseUint(field(6), 10, 64)
+	status.ServerGUID = int64(guid)
+	status.SubMOTD = field(7)
+	status.GameMode = field(8)
+	status.GameModeID, _ = strconv.Atoi(field(9))
+	status.PortV4, _ = strconv.Atoi(field(10))
+	status.PortV6, _ = strconv.Atoi(field(11))
+	return status, nil
+}
+
+// --- Unconnected ping/pong ---
+
+// appendRakNetPing appends an unconnected ping
+func appendRakNetPing(b []byte, timestamp, clientGUID int64) []byte {
+	b = append(b, RakNetUnconnectedPing)
+	b = appendInt64(b, timestamp)
+	b = append(b, rakNetMagic[:]...)
+	return appendInt64(b, clientGUID)
+}
+
+// parseRakNetPing parses an unconnected ping, with or without open connections
+func parseRakNetPing(b []byte) (timestamp int64, err error) {
+	if len(b) < rakNetPingLength-8 ||
+		(b[0] != RakNetUnconnectedPing && b[0] != RakNetUnconnectedPingOpenConnections) ||
+		!bytes.Equal(b[9:25], rakNetMagic[:]) {
+		return 0, ErrInvalidRakNet
+	}
+	// The client GUID is not sent by every client
+	return int64(binary.BigEndian.Uint64(b[1:])), nil
+}
+
+// appendRakNetPong appends an unconnected pong
+func appendRakNetPong(b []byte, timestamp, serverGUID int64, status string) []byte {
+	b = append(b, RakNetUnconnectedPong)
+	b = appendInt64(b, timestamp)
+	b = appendInt64(b, serverGUID)
+	b = append(b, rakNetMagic[:]...)
+	b = append(b, byte(len(status)>>8), byte(len(status)))
+	return append(b, status...)
+}
+
+// parseRakNetPong parses an unconnected pong
+func parseRakNetPong(b []byte) (timestamp, serverGUID int64, status string, err error) {
+	if len(b) < rakNetPongHeaderLength || b[0] != RakNetUnconnectedPong || !bytes.Equal(b[17:33], rakNetMagic[:]) {
+		return 0, 0, "", ErrInvalidRakNet
+	}
+	timestamp = int64(binary.BigEndian.Uint64(b[1:]))
+	serverGUID = int64(binary.BigEndian.Uint64(b[9:]))
+	length := int(binary.BigEndian.Uint16(b[33:]))
+	if len(b) < rakNetPongHeaderLength+length {
+		return 0, 0, "", ErrInvalidRakNet
+	}
+	return timestamp, serverGUID, string(b[rakNetPongHeaderLength : rakNetPongHeaderLength+length]), nil
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// randomGUID returns a random RakNet GUID
+func randomGUID() int64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(b[:]))
+}
+
+// --- BedrockResponder ---
+
+// BedrockResponder answers the unconnected pings of Bedrock Edition clients.
+type BedrockResponder struct {
+	// GUID is the GUID of the server. If zero, a random GUID is used.
+	GUID int64
+	// Status returns the status sent to the client at the given address.
+	// The ServerGUID of the status is set to GUID if zero.
+	Status func(addr net.Addr) (*BedrockStatus, error)
+
+	guidOnce sync.Once
+	guid     int64
+}
+
+// Serve answers the unconnected pings received on the connection until reading from it fails.
+// Other packets are ignored.
+func (r *BedrockResponder) Serve(conn net.PacketConn) error {
+	buf := make([]byte, rakNetMaxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if response := r.handle(buf[:n], addr); response != nil {
+			// Like any UDP packet, the response may be lost
+			conn.WriteTo(response, addr)
+		}
+	}
+}
+
+// ListenAndServe listens on the UDP address and answers the unconnected pings.
+func (r *BedrockResponder) ListenAndServe(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return r.Serve(conn)
+}
+
+// handle returns the pong answering the ping, or nil if it must be ignored
+func (r *BedrockResponder) handle(ping []byte, addr net.Addr) []byte {
+	timestamp, err := parseRakNetPing(ping)
+	if err != nil || r.Status == nil {
+		return nil
+	}
+	status, err := r.Status(addr)
+	if err != nil || status == nil {
+		return nil
+	}
+	guid := r.serverGUID()
+	if status.ServerGUID == 0 {
+		s := *status
+		s.ServerGUID = guid
+		status = &s
+	}
+	return appendRakNetPong(nil, timestamp, guid, status.String())
+}
+
+// serverGUID returns GUID, or the random GUID used instead of zero
+func (r *BedrockResponder) serverGUID() int64 {
+	r.guidOnce.Do(func() {
+		r.guid = r.GUID
+		if r.guid == 0 {
+			r.guid = randomGUID()
+		}
+	})
+	return r.guid
+}
+
+// --- BedrockPinger ---
+
+// BedrockPingResult is the result of an unconnected ping.
+type BedrockPingResult struct {
+	Status *BedrockStatus
+	// ServerGUID is the GUID of the server, as sent in the pong.
+	ServerGUID int64
+	Latency    time.Duration
+}
+
+// BedrockPinger gets the status of Bedrock Edition servers.
+// The zero value is ready to use.
+type BedrockPinger struct {
+	// Dialer dials the UDP connections. If nil, a zero net.Dialer is used.
+	Dialer ContextDialer
+	// Timeout is the timeout of a ping, if the context has no earlier deadline.
+	// If zero, 5 seconds is used.
+	Timeout time.Duration
+	// GUID is the client GUID sent in pings. If zero, a random GUID is used.
+	GUID int64
+}
+
+// Ping gets the status of the Bedrock Edition server at the given address.
+// The address is a host with an optional port, 19132 by default.
+func (p *BedrockPinger) Ping(ctx context.Context, address string) (*BedrockPingResult, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultBedrockPort))
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultBedrockTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer ContextDialer = &net.Dialer{}
+	if p.Dialer != nil {
+		dialer = p.Dialer
+	}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	stop := watchContext(ctx, conn)
+	defer stop()
+
+	guid := p.GUID
+	if guid == 0 {
+		guid = randomGUID()
+	}
+
+	start := time.Now()
+	timestamp := start.UnixNano() / int64(time.Millisecond)
+	if _, err := conn.Write(appendRakNetPing(nil, timestamp, guid)); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, rakNetMaxPacketSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, contextError(ctx, err)
+		}
+		pongTimestamp, serverGUID, s, err := parseRakNetPong(buf[:n])
+		if err != nil || pongTimestamp != timestamp {
+			// Ignore the answers to other pings
+			continue
+		}
+		latency := time.Since(start)
+
+		status, err := ParseBedrockStatus(s)
+		if err != nil {
+			return nil, err
+		}
+		return &BedrockPingResult{Status: status, ServerGUID: serverGUID, Latency: latency}, nil
+	}
+}
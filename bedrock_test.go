@@ -0,0 +1,219 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBedrockStatus(t *testing.T) {
+	status := &BedrockStatus{
+		MOTD:            "A;Bedrock;Server",
+		ProtocolVersion: 685,
+		Version:         "1.21.0",
+		Online:          2,
+		Max:             10,
+		ServerGUID:      -1,
+		SubMOTD:         "Bedrock level",
+		GameMode:        "Survival",
+		GameModeID:      1,
+		PortV4:          19132,
+		PortV6:          19133,
+	}
+	s := status.String()
+	want := "MCPE;ABedrockServer;685;1.21.0;2;10;18446744073709551615;Bedrock level;Survival;1;19132;19133;"
+	if s != want {
+		t.Fatalf("got %q, want %q", s, want)
+	}
+
+	got, err := ParseBedrockStatus(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status.Edition, status.MOTD = "MCPE", "ABedrockServer"
+	if !reflect.DeepEqual(got, status) {
+		t.Errorf("got %+v, want %+v", got, status)
+	}
+}
+
+func TestParseBedrockStatus(t *testing.T) {
+	tests := []struct {
+		s    string
+		want BedrockStatus
+	}{
+		// Backslashes are not escapes
+		{"MCPE;Dedicated Server\\;390;1.14.60;0;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;", BedrockStatus{
+			Edition: "MCPE", MOTD: "Dedicated Server\\", ProtocolVersion: 390, Version: "1.14.60", Online: 0, Max: 10,
+			ServerGUID: -5192883181380620751, SubMOTD: "Bedrock level", GameMode: "Survival", GameModeID: 1, PortV4: 19132, PortV6: 19133,
+		}},
+		// Old servers send fewer fields
+		{"MCPE;Old Server;70;0.11.0;3;20", BedrockStatus{Edition: "MCPE", MOTD: "Old Server", ProtocolVersion: 70, Version: "0.11.0", Online: 3, Max: 20}},
+		{"MCEE;;589;1.20.0;0;40;;;;;;", BedrockStatus{Edition: "MCEE", ProtocolVersion: 589, Version: "1.20.0", Max: 40}},
+	}
+	for _, test := range tests {
+		got, err := ParseBedrockStatus(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+		} else if *got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.s, *got, test.want)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"MCPE;motd;390;1.14.60;0",
+		"MCPE;motd;x;1.14.60;0;10",
+		"MCPE;motd;390;1.14.60;x;10",
+		"MCPE;motd;390;1.14.60;0;x",
+	} {
+		if _, err := ParseBedrockStatus(s); !errors.Is(err, ErrInvalidRakNet) {
+			t.Errorf("%q: got %v, want ErrInvalidRakNet", s, err)
+		}
+	}
+}
+
+func TestRakNetPing(t *testing.T) {
+	ping := appendRakNetPing(nil, 0x0102030405060708, -2)
+	want := append([]byte{RakNetUnconnectedPing, 1, 2, 3, 4, 5, 6, 7, 8}, rakNetMagic[:]...)
+	want = append(want, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE)
+	if !bytes.Equal(ping, want) {
+		t.Fatalf("got % X, want % X", ping, want)
+	}
+
+	// The client GUID is optional, and pings may ask for open connections
+	openConnections := append([]byte{RakNetUnconnectedPingOpenConnections}, ping[1:]...)
+	for _, b := range [][]byte{ping, ping[:rakNetPingLength-8], openConnections} {
+		if timestamp, err := parseRakNetPing(b); err != nil || timestamp != 0x0102030405060708 {
+			t.Errorf("% X: got %d %v", b, timestamp, err)
+		}
+	}
+
+	badMagic := append([]byte(nil), ping...)
+	badMagic[12] ^= 1
+	for _, b := range [][]byte{ping[:rakNetPingLength-9], append([]byte{RakNetUnconnectedPong}, ping[1:]...), badMagic} {
+		if _, err := parseRakNetPing(b); !errors.Is(err, ErrInvalidRakNet) {
+			t.Errorf("% X: got %v, want ErrInvalidRakNet", b, err)
+		}
+	}
+}
+
+func TestRakNetPong(t *testing.T) {
+	pong := appendRakNetPong(nil, 42, -3, "MCPE;motd;390;1.14.60;0;10;")
+	if len(pong) != rakNetPongHeaderLength+27 || pong[0] != RakNetUnconnectedPong || !bytes.Equal(pong[17:33], rakNetMagic[:]) {
+		t.Fatalf("got % X", pong)
+	}
+
+	timestamp, guid, status, err := parseRakNetPong(pong)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp != 42 || guid != -3 || status != "MCPE;motd;390;1.14.60;0;10;" {
+		t.Errorf("got %d %d %q", timestamp, guid, status)
+	}
+
+	for _, b := range [][]byte{pong[:rakNetPongHeaderLength-1], pong[:len(pong)-1], append([]byte{RakNetUnconnectedPing}, pong[1:]...)} {
+		if _, _, _, err := parseRakNetPong(b); !errors.Is(err, ErrInvalidRakNet) {
+			t.Errorf("% X: got %v, want ErrInvalidRakNet", b, err)
+		}
+	}
+}
+
+func TestBedrockResponder(t *testing.T) {
+	r := &BedrockResponder{Status: func(net.Addr) (*BedrockStatus, error) {
+		return &BedrockStatus{MOTD: "motd", ProtocolVersion: 685, Version: "1.21.0", Max: 10}, nil
+	}}
+	ping := appendRakNetPing(nil, 42, 1)
+
+	// The random GUID is chosen once, even by concurrent pings
+	guids := make([]int64, 8)
+	var wg sync.WaitGroup
+	for i := range guids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, guids[i], _, _ = parseRakNetPong(r.handle(ping, nil))
+		}(i)
+	}
+	wg.Wait()
+	for _, guid := range guids {
+		if guid == 0 || guid != guids[0] {
+			t.Fatalf("got GUIDs %v", guids)
+		}
+	}
+	if r.GUID != 0 {
+		t.Errorf("GUID set to %d", r.GUID)
+	}
+
+	timestamp, guid, s, err := parseRakNetPong(r.handle(ping, nil))
+	if err != nil || timestamp != 42 {
+		t.Fatalf("got %d %v", timestamp, err)
+	}
+	status, err := ParseBedrockStatus(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ServerGUID != guid || status.Edition != "MCPE" || status.MOTD != "motd" {
+		t.Errorf("got %+v, want the server GUID %d", status, guid)
+	}
+
+	if pong := r.handle(ping[:10], nil); pong != nil {
+		t.Errorf("got % X for a malformed ping", pong)
+	}
+}
+
+// bedrockDialer connects the BedrockPinger to the BedrockResponder through a
+// pipe, each write being read as a datagram
+type bedrockDialer struct {
+	responder *BedrockResponder
+	// other is a pong sent before the answer, to another ping
+	other []byte
+}
+
+func (d bedrockDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		// Like a UDP socket, the connection is not closed by the server
+		defer func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+		buf := make([]byte, rakNetMaxPacketSize)
+		n, err := server.Read(buf)
+		if err != nil {
+			return
+		}
+		if d.other != nil {
+			server.Write(d.other)
+		}
+		if pong := d.responder.handle(buf[:n], nil); pong != nil {
+			server.Write(pong)
+		}
+	}()
+	return client, nil
+}
+
+func TestBedrockPinger(t *testing.T) {
+	r := &BedrockResponder{GUID: 7, Status: func(net.Addr) (*BedrockStatus, error) {
+		return &BedrockStatus{MOTD: "motd", ProtocolVersion: 685, Version: "1.21.0", Online: 1, Max: 10}, nil
+	}}
+	p := BedrockPinger{Dialer: bedrockDialer{responder: r, other: appendRakNetPong(nil, 1, 8, "other")}}
+
+	result, err := p.Ping(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ServerGUID != 7 || result.Status.ServerGUID != 7 || result.Status.MOTD != "motd" || result.Status.Online != 1 {
+		t.Errorf("got %+v, status %+v", result, result.Status)
+	}
+
+	// A server that does not answer times out
+	p = BedrockPinger{Dialer: bedrockDialer{responder: &BedrockResponder{}}, Timeout: 50 * time.Millisecond}
+	if _, err := p.Ping(context.Background(), "127.0.0.1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,296 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// --- TextComponent ---
+
+// TextComponent is a JSON text component, used by chat messages, MOTDs,
+// disconnect reasons...
+// It is decoded leniently: a plain string, a number or an array of
+// components are accepted anywhere a component is expected.
+type TextComponent struct {
+	Text      string          `json:"text,omitempty"`
+	Translate string          `json:"translate,omitempty"`
+	With      []TextComponent `json:"with,omitempty"`
+	Keybind   string          `json:"keybind,omitempty"`
+
+	Color         string `json:"color,omitempty"`
+	Font          string `json:"font,omitempty"`
+	Bold          *bool  `json:"bold,omitempty"`
+	Italic        *bool  `json:"italic,omitempty"`
+	Underlined    *bool  `json:"underlined,omitempty"`
+	Strikethrough *bool  `json:"strikethrough,omitempty"`
+	Obfuscated    *bool  `json:"obfuscated,omitempty"`
+	Insertion     string `json:"insertion,omitempty"`
+
+	ClickEvent *ClickEvent `json:"clickEvent,omitempty"`
+	HoverEvent *HoverEvent `json:"hoverEvent,omitempty"`
+
+	Extra []TextComponent `json:"extra,omitempty"`
+}
+
+// ClickEvent is the action run when a text component is clicked.
+type ClickEvent struct {
+	Action string `json:"action"`
+	Value  string `json:"value"`
+}
+
+// HoverEvent is the tooltip shown when a text component is hovered.
+type HoverEvent struct {
+	Action   string          `json:"action"`
+	Contents json.RawMessage `json:"contents,omitempty"`
+	// Value is the legacy form of Contents.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// textComponent has the fields of TextComponent without its methods
+type textComponent TextComponent
+
+// MarshalJSON encodes the text component.
+// A component without content is encoded with an empty text.
+func (c TextComponent) MarshalJSON() ([]byte, error) {
+	if c.Text == "" && c.Translate == "" && c.Keybind == "" {
+		return json.Marshal(struct {
+			Text string `json:"text"`
+			textComponent
+		}{"", textComponent(c)})
+	}
+	return json.Marshal(textComponent(c))
+}
+
+// NBT returns the text component as the NBT tag it is sent as from 1.20.3.
+func (c TextComponent) NBT() (NBTTag, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return NBTTag{}, err
+	}
+	v, err := nbtFromJSON(b)
+	return NBTTag{Value: v}, err
+}
+
+// UnmarshalJSON decodes the text component leniently.
+func (c *TextComponent) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil
+	}
+
+	switch b[0] {
+	case 'n': // null
+		*c = TextComponent{}
+		return nil
+	case '"':
+		var text string
+		if err := json.Unmarshal(b, &text); err != nil {
+			return err
+		}
+		*c = TextComponent{Text: text}
+		return nil
+	case '[':
+		var components []TextComponent
+		if err := json.Unmarshal(b, &components); err != nil {
+			return err
+		}
+		*c = TextComponent{}
+		if len(components) > 0 {
+			*c = components[0]
+			c.Extra = append(c.Extra[:len(c.Extra):len(c.Extra)], components[1:]...)
+		}
+		return nil
+	case '{':
+		var component struct {
+			Text json.RawMessage `json:"text"`
+			textComponent
+		}
+		if err := json.Unmarshal(b, &component); err != nil {
+			return err
+		}
+		*c = TextComponent(component.textComponent)
+		c.Text = lenientString(component.Text)
+		return nil
+	default: // number or boolean
+		*c = TextComponent{Text: string(b)}
+		return nil
+	}
+}
+
+// lenientString decodes a JSON string, or returns the raw JSON of other values
+func lenientString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// lenientInt decodes a JSON number, or a string containing a number
+func lenientInt(raw json.RawMessage) (int, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		raw = []byte(strings.TrimSpace(s))
+	}
+
+	f, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return 0, err
+	}
+	return int(f), nil
+}
+
+// PlainText returns the text of the component and of its children, without formatting.
+// Translatable components are rendered as their translation key.
+func (c *TextComponent) PlainText() string {
+	var b strings.Builder
+	c.writePlainText(&b)
+	return b.String()
+}
+
+func (c *TextComponent) writePlainText(b *strings.Builder) {
+	switch {
+	case c.Text != "":
+		b.WriteString(c.Text)
+	case c.Translate != "":
+		b.WriteString(c.Translate)
+	case c.Keybind != "":
+		b.WriteString(c.Keybind)
+	}
+	for i := range c.Extra {
+		c.Extra[i].writePlainText(b)
+	}
+}
+
+// legacyColors are the named colors of text components, by legacy formatting code
+var legacyColors = [...]struct {
+	name string
+	rgb  [3]byte
+}{
+	{"black", [3]byte{0x00, 0x00, 0x00}},
+	{"dark_blue", [3]byte{0x00, 0x00, 0xAA}},
+	{"dark_green", [3]byte{0x00, 0xAA, 0x00}},
+	{"dark_aqua", [3]byte{0x00, 0xAA, 0xAA}},
+	{"dark_red", [3]byte{0xAA, 0x00, 0x00}},
+	{"dark_purple", [3]byte{0xAA, 0x00, 0xAA}},
+	{"gold", [3]byte{0xFF, 0xAA, 0x00}},
+	{"gray", [3]byte{0xAA, 0xAA, 0xAA}},
+	{"dark_gray", [3]byte{0x55, 0x55, 0x55}},
+	{"blue", [3]byte{0x55, 0x55, 0xFF}},
+	{"green", [3]byte{0x55, 0xFF, 0x55}},
+	{"aqua", [3]byte{0x55, 0xFF, 0xFF}},
+	{"red", [3]byte{0xFF, 0x55, 0x55}},
+	{"light_purple", [3]byte{0xFF, 0x55, 0xFF}},
+	{"yellow", [3]byte{0xFF, 0xFF, 0x55}},
+	{"white", [3]byte{0xFF, 0xFF, 0xFF}},
+}
+
+// legacyStyle is the formatting of a text component, as legacy formatting codes
+type legacyStyle struct {
+	color byte // 0 for the default color
+	// formats are the codes of bold, italic, underlined, strikethrough and obfuscated
+	formats [5]bool
+}
+
+// legacyFormatCodes are the codes of the formats of a legacyStyle
+const legacyFormatCodes = "lonmk"
+
+// Legacy returns the text of the component with legacy formatting codes (§),
+// as used by legacy pings and LAN world announcements.
+// Hex colors are replaced by the closest named color.
+// Translatable components are rendered as their translation key.
+func (c *TextComponent) Legacy() string {
+	var b strings.Builder
+	var current legacyStyle
+	c.writeLegacy(&b, legacyStyle{}, &current)
+	return b.String()
+}
+
+func (c *TextComponent) writeLegacy(b *strings.Builder, parent legacyStyle, current *legacyStyle) {
+	style := parent
+	if c.Color != "" {
+		style.color = legacyColorCode(c.Color)
+	}
+	for i, format := range [...]*bool{c.Bold, c.Italic, c.Underlined, c.Strikethrough, c.Obfuscated} {
+		if format != nil {
+			style.formats[i] = *format
+		}
+	}
+
+	text := c.Text
+	switch {
+	case text != "":
+	case c.Translate != "":
+		text = c.Translate
+	case c.Keybind != "":
+		text = c.Keybind
+	}
+
+	if text != "" && style != *current {
+		removed := false
+		for i := range style.formats {
+			removed = removed || (current.formats[i] && !style.formats[i])
+		}
+		if style.color != current.color || removed {
+			// Colors reset the formats
+			if style.color != 0 {
+				b.WriteString("§")
+				b.WriteByte(style.color)
+			} else {
+				b.WriteString("§r")
+			}
+			*current = legacyStyle{color: style.color}
+		}
+		for i, set := range style.formats {
+			if set && !current.formats[i] {
+				b.WriteString("§")
+				b.WriteByte(legacyFormatCodes[i])
+			}
+		}
+		*current = style
+	}
+	b.WriteString(text)
+
+	for i := range c.Extra {
+		c.Extra[i].writeLegacy(b, style, current)
+	}
+}
+
+// legacyColorCode returns the legacy code of the color, 0 if it is unknown
+func legacyColorCode(color string) byte {
+	const codes = "0123456789abcdef"
+
+	if len(color) == 7 && color[0] == '#' {
+		rgb, err := strconv.ParseUint(color[1:], 16, 32)
+		if err != nil {
+			return 0
+		}
+		r, g, b := int(rgb>>16&0xFF), int(rgb>>8&0xFF), int(rgb&0xFF)
+
+		closest, distance := 0, -1
+		for i, c := range legacyColors {
+			dr, dg, db := r-int(c.rgb[0]), g-int(c.rgb[1]), b-int(c.rgb[2])
+			if d := dr*dr + dg*dg + db*db; distance < 0 || d < distance {
+				closest, distance = i, d
+			}
+		}
+		return codes[closest]
+	}
+
+	for i, c := range legacyColors {
+		if c.name == color {
+			return codes[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package proto
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTextComponentUnmarshal(t *testing.T) {
+	bold := true
+	tests := []struct {
+		json string
+		want TextComponent
+	}{
+		{`"plain"`, TextComponent{Text: "plain"}},
+		{`42`, TextComponent{Text: "42"}},
+		{`true`, TextComponent{Text: "true"}},
+		{`null`, TextComponent{}},
+		{`[]`, TextComponent{}},
+		// The first component of an array is the parent of the others
+		{`["a", {"text": "b", "color": "red"}, 3]`, TextComponent{Text: "a", Extra: []TextComponent{{Text: "b", Color: "red"}, {Text: "3"}}}},
+		{`[{"text": "a", "extra": ["b"]}, "c"]`, TextComponent{Text: "a", Extra: []TextComponent{{Text: "b"}, {Text: "c"}}}},
+		{`{"text": 5, "bold": true}`, TextComponent{Text: "5", Bold: &bold}},
+		{`{"text": null, "extra": ["x", ["y", "z"]]}`, TextComponent{Extra: []TextComponent{{Text: "x"}, {Text: "y", Extra: []TextComponent{{Text: "z"}}}}}},
+		{`{"translate": "chat.type.text", "with": ["Steve", {"text": "hi"}]}`, TextComponent{Translate: "chat.type.text", With: []TextComponent{{Text: "Steve"}, {Text: "hi"}}}},
+		{` {"keybind": "key.jump"} `, TextComponent{Keybind: "key.jump"}},
+	}
+	for _, test := range tests {
+		got := TextComponent{Text: "stale", Color: "blue"}
+		if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+			t.Errorf("%s: %v", test.json, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.json, got, test.want)
+		}
+	}
+
+	for _, s := range []string{`{"text":`, `{"extra": "x", "color": 1}`, `["a", {]`} {
+		var c TextComponent
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestTextComponentMarshal(t *testing.T) {
+	bold := false
+	tests := []struct {
+		c    TextComponent
+		want string
+	}{
+		{TextComponent{}, `{"text":""}`},
+		{TextComponent{Color: "red", Extra: []TextComponent{{Text: "a"}}}, `{"text":"","color":"red","extra":[{"text":"a"}]}`},
+		{TextComponent{Text: "a", Bold: &bold}, `{"text":"a","bold":false}`},
+		{TextComponent{Translate: "chat.type.text"}, `{"translate":"chat.type.text"}`},
+	}
+	for _, test := range tests {
+		b, err := json.Marshal(test.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.want {
+			t.Errorf("got %s, want %s", b, test.want)
+		}
+	}
+}
+
+func TestTextComponentPlainText(t *testing.T) {
+	c := TextComponent{Text: "a", Color: "red", Extra: []TextComponent{
+		{Translate: "b"},
+		{Keybind: "c", Extra: []TextComponent{{Text: "d"}}},
+	}}
+	if got := c.PlainText(); got != "abcd" {
+		t.Errorf("got %q, want abcd", got)
+	}
+}
+
+func TestTextComponentLegacy(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		c    TextComponent
+		want string
+	}{
+		{TextComponent{Text: "A world"}, "A world"},
+		{TextComponent{Text: "A world", Color: "green"}, "§aA world"},
+		// Hex colors are replaced by the closest named color
+		{TextComponent{Text: "a", Color: "#FE0000"}, "§4a"},
+		{TextComponent{Text: "a", Color: "#5555FF"}, "§9a"},
+		{TextComponent{Text: "a", Color: "unknown"}, "a"},
+		// The styles are inherited, and colors reset the formats
+		{TextComponent{Text: "a", Bold: &yes, Extra: []TextComponent{{Text: "b", Color: "red"}, {Text: "c"}}}, "§la§c§lb§r§lc"},
+		{TextComponent{Text: "a", Bold: &yes, Extra: []TextComponent{{Text: "b", Bold: &no, Italic: &yes}}}, "§la§r§ob"},
+		{TextComponent{Text: "a", Color: "gold", Extra: []TextComponent{{Text: "b", Underlined: &yes, Strikethrough: &yes, Obfuscated: &yes}}}, "§6a§n§m§kb"},
+		// Components without text do not change the formatting
+		{TextComponent{Color: "red", Extra: []TextComponent{{Text: "x"}, {Color: "blue"}, {Text: "y"}}}, "§cxy"},
+		{TextComponent{Translate: "menu.game", Color: "yellow"}, "§emenu.game"},
+	}
+	for _, test := range tests {
+		if got := test.c.Legacy(); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.c, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultConfigurationTimeout is the timeout of the configuration of a ConfigurationHandler.
+const defaultConfigurationTimeout = 30 * time.Second
+
+// BrandChannel is the channel of the plugin messages sending the brand of the
+// client or server, such as vanilla.
+const BrandChannel = "minecraft:brand"
+
+// --- ConfigurationHandler ---
+
+// ConfigurationHandler drives the configuration state on the server side:
+// the brand and FeatureFlags, the known packs exchange from 1.20.5, RegistryData,
+// UpdateTags, and FinishConfiguration, until the client acknowledges it.
+// It supports the clients from 1.20.2.
+type ConfigurationHandler struct {
+	// Brand is the brand of the server sent to the client. If empty, it is not sent.
+	Brand string
+	// FeatureFlags are the enabled feature flags. If nil, minecraft:vanilla is enabled.
+	FeatureFlags []Identifier
+	// KnownPacks are the data packs offered to the client, from 1.20.5.
+	KnownPacks []KnownPack
+	// Registries returns the registries sent to the client, given the packs it knows.
+	// The entries of known packs may be sent without data.
+	// Before 1.20.5, the client knows no pack, and the registries are sent
+	// as a single codec: see Registries.RegistryData.
+	Registries func(conn *Conn, known []KnownPack) ([]RegistryData, error)
+	// Tags are the tags sent to the client, such as built by a TagResolver.
+	// If empty, UpdateTags is not sent.
+	Tags TagRegistries
+
+	// Configure, if set, sends other packets to the client, such as resource
+	// packs, cookie requests or server links, before FinishConfiguration.
+	Configure func(conn *Conn) error
+	// HandlePacket, if set, receives the serverbound packets the handler does
+	// not handle itself: plugin messages, cookie and resource pack responses.
+	// Returning an error aborts the configuration.
+	HandlePacket func(conn *Conn, p *RawPacket) error
+
+	// Timeout is the maximum duration of the configuration. If zero, 30 seconds is used.
+	Timeout time.Duration
+}
+
+// HandleConfiguration configures the client whose login was acknowledged.
+// It returns the settings of the client, or nil if it did not send them,
+// once the connection reaches the play state.
+// If the configuration fails, the client is sent a ConfigDisconnect with the
+// reason, but the connection is not closed.
+func (h *ConfigurationHandler) HandleConfiguration(ctx context.Context, conn *Conn) (*ConfigClientInformation, error) {
+	if conn.Protocol != 0 && conn.Protocol < Protocol1_20_2 {
+		return nil, fmt.Errorf("%w: configuration of protocol %d", ErrUnsupportedProtocol, conn.Protocol)
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultConfigurationTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c := configuration{handler: h, conn: conn}
+	if err := c.run(deadline); err != nil {
+		h.disconnect(conn, err)
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return c.info, nil
+}
+
+// configuration is the state of the configuration of a client
+type configuration struct {
+	handler *ConfigurationHandler
+	conn    *Conn
+	info    *ConfigClientInformation
+}
+
+func (c *configuration) run(deadline time.Time) error {
+	h, conn := c.handler, c.conn
+
+	if h.Brand != "" {
+		var data bytes.Buffer
+		String(h.Brand).WriteTo(&data)
+		err := conn.Send(&ConfigClientboundPluginMessage{Channel: BrandChannel, Data: data.Bytes()})
+		if err != nil {
+			return err
+		}
+	}
+
+	flags := Identifiers(h.FeatureFlags)
+	if flags == nil {
+		flags = Identifiers{"minecraft:vanilla"}
+	}
+	if err := conn.Send(&FeatureFlags{Flags: flags}); err != nil {
+		return err
+	}
+
+	var known ServerboundKnownPacks
+	if conn.Protocol == 0 || conn.Protocol >= Protocol1_20_5 {
+		if err := conn.Send(&ClientboundKnownPacks{Packs: h.KnownPacks}); err != nil {
+			return err
+		}
+		if err := c.receive(&known, serverboundKnownPacksIDs); err != nil {
+			return err
+		}
+	}
+
+	if h.Registries != nil {
+		registries, err := h.Registries(conn, known.Packs)
+		if err != nil {
+			return err
+		}
+		for i := range registries {
+			if err := conn.Send(&registries[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(h.Tags) > 0 {
+		if err := conn.Send(&UpdateTags{Registries: h.Tags}); err != nil {
+			return err
+		}
+	}
+
+	if h.Configure != nil {
+		if err := h.Configure(conn); err != nil {
+			return err
+		}
+		// The hook may change the deadline
+		conn.SetDeadline(deadline)
+	}
+
+	if err := conn.Send(&FinishConfiguration{}); err != nil {
+		return err
+	}
+	var ack AcknowledgeFinishConfiguration
+	return c.receive(&ack, acknowledgeFinishConfigurationIDs)
+}
+
+// receive reads the packets of the client until the packet with the given IDs
+// by protocol version, which is decoded to pk. The packets sent by the client
+// at any time are handled.
+func (c *configuration) receive(pk Packet, ids []versionedID) error {
+	var p RawPacket
+	for {
+		if err := c.conn.ReadPacket(&p); err != nil {
+			return err
+		}
+
+		is := func(ids []versionedID) bool {
+			id, err := packetID("", p.Protocol, ids)
+			return err == nil && id == p.ID
+		}
+		switch {
+		case is(ids):
+			return pk.FromRaw(&p)
+
+		case is(configClientInformationIDs):
+			var info ConfigClientInformation
+			if err := info.FromRaw(&p); err != nil {
+				return err
+			}
+			c.info = &info
+
+		case is(configServerboundKeepAliveIDs), is(configPongIDs):
+			// Answers to packets sent by Configure
+
+		case is(configServerboundPluginMessageIDs), is(configCookieResponseIDs), is(configResourcePackResponseIDs):
+			if c.handler.HandlePacket != nil {
+				if err := c.handler.HandlePacket(c.conn, &p); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("%w: packet 0x%02X in configuration state", ErrUnexpectedPacket, p.ID)
+		}
+	}
+}
+
+// disconnect sends the reason of the failed configuration to the client
+func (h *ConfigurationHandler) disconnect(conn *Conn, err error) {
+	reason := TextComponent{Translate: "multiplayer.disconnect.generic"}
+	var disconnectErr *DisconnectError
+	if errors.As(err, &disconnectErr) {
+		reason = disconnectErr.Reason
+	}
+
+	tag, err := reason.NBT()
+	if err != nil {
+		return
+	}
+	// The connection may be broken already, or the configuration timed out
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	conn.Send(&ConfigDisconnect{Reason: tag})
+}
@@ -0,0 +1,1220 @@
+package proto
+
+import (
+	"fmt"
+	"io"
+)
+
+// The configuration state is entered from 1.20.2, when the client sends
+// LoginAcknowledged, and left for the play state with FinishConfiguration.
+// The IDs and layouts of its packets depend on RawPacket.Protocol: the _ID
+// constants are the IDs from 1.20.5, also used when the protocol is zero.
+// The packets a protocol version does not have fail with ErrUnsupportedProtocol.
+
+// --- ConfigCookieRequest ---
+
+// ConfigCookieRequest is a packet asking the client for a cookie it stored.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigCookieRequest struct {
+	Key Identifier
+}
+
+// ConfigCookieRequest_ID is the ConfigCookieRequest packet ID.
+const ConfigCookieRequest_ID = 0x00
+
+// configCookieRequestIDs are the ConfigCookieRequest packet IDs by protocol version, newest first.
+var configCookieRequestIDs = []versionedID{{Protocol1_20_5, ConfigCookieRequest_ID}}
+
+// ToRaw marshals the ConfigCookieRequest Packet to the given RawPacket.
+func (pi *ConfigCookieRequest) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigCookieRequest", p.Protocol, configCookieRequestIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Key)
+}
+
+// FromRaw unmarshals the ConfigCookieRequest Packet from the given RawPacket.
+func (pi *ConfigCookieRequest) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigCookieRequest", configCookieRequestIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Key)
+}
+
+// --- ConfigClientboundPluginMessage ---
+
+// ConfigClientboundPluginMessage is a plugin message sent to the client on a channel.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigClientboundPluginMessage struct {
+	Channel Identifier
+	Data    RemainingBytes
+}
+
+// ConfigClientboundPluginMessage_ID is the ConfigClientboundPluginMessage packet ID.
+const ConfigClientboundPluginMessage_ID = 0x01
+
+// configClientboundPluginMessageIDs are the ConfigClientboundPluginMessage packet IDs by protocol version, newest first.
+var configClientboundPluginMessageIDs = []versionedID{{Protocol1_20_5, ConfigClientboundPluginMessage_ID}, {Protocol1_20_2, 0x00}}
+
+// ToRaw marshals the ConfigClientboundPluginMessage Packet to the given RawPacket.
+func (pi *ConfigClientboundPluginMessage) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigClientboundPluginMessage", p.Protocol, configClientboundPluginMessageIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Channel, &pi.Data)
+}
+
+// FromRaw unmarshals the ConfigClientboundPluginMessage Packet from the given RawPacket.
+func (pi *ConfigClientboundPluginMessage) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigClientboundPluginMessage", configClientboundPluginMessageIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Channel, &pi.Data)
+}
+
+// --- ConfigDisconnect ---
+
+// ConfigDisconnect is a packet that tells the user they have been disconnected.
+// Reason is a text component, see TextComponent.NBT. It is sent as JSON in 1.20.2.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigDisconnect struct {
+	Reason NBTTag
+}
+
+// ConfigDisconnect_ID is the ConfigDisconnect packet ID.
+const ConfigDisconnect_ID = 0x02
+
+// configDisconnectIDs are the ConfigDisconnect packet IDs by protocol version, newest first.
+var configDisconnectIDs = []versionedID{{Protocol1_20_5, ConfigDisconnect_ID}, {Protocol1_20_2, 0x01}}
+
+// ToRaw marshals the ConfigDisconnect Packet to the given RawPacket.
+func (pi *ConfigDisconnect) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigDisconnect", p.Protocol, configDisconnectIDs); err != nil {
+		return err
+	}
+	return p.Marshal(pi.types(p.Protocol)...)
+}
+
+// FromRaw unmarshals the ConfigDisconnect Packet from the given RawPacket.
+func (pi *ConfigDisconnect) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigDisconnect", configDisconnectIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *ConfigDisconnect) types(protocol int32) []Type {
+	if protocol != 0 && protocol < Protocol1_20_3 {
+		return []Type{jsonText{&pi.Reason, false}}
+	}
+	return []Type{&pi.Reason}
+}
+
+// --- FinishConfiguration ---
+
+// FinishConfiguration is a packet telling the client the configuration is done.
+// The client answers with AcknowledgeFinishConfiguration and enters the play state.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type FinishConfiguration struct{}
+
+// FinishConfiguration_ID is the FinishConfiguration packet ID.
+const FinishConfiguration_ID = 0x03
+
+// finishConfigurationIDs are the FinishConfiguration packet IDs by protocol version, newest first.
+var finishConfigurationIDs = []versionedID{{Protocol1_20_5, FinishConfiguration_ID}, {Protocol1_20_2, 0x02}}
+
+// ToRaw marshals the FinishConfiguration Packet to the given RawPacket.
+func (pi *FinishConfiguration) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("FinishConfiguration", p.Protocol, finishConfigurationIDs); err != nil {
+		return err
+	}
+	return p.Marshal()
+}
+
+// FromRaw unmarshals the FinishConfiguration Packet from the given RawPacket.
+func (pi *FinishConfiguration) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("FinishConfiguration", finishConfigurationIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi)
+}
+
+// --- ConfigClientboundKeepAlive ---
+
+// ConfigClientboundKeepAlive is a packet the client must answer with
+// a ConfigServerboundKeepAlive of the same ID.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigClientboundKeepAlive struct {
+	KeepAliveID Long
+}
+
+// ConfigClientboundKeepAlive_ID is the ConfigClientboundKeepAlive packet ID.
+const ConfigClientboundKeepAlive_ID = 0x04
+
+// configClientboundKeepAliveIDs are the ConfigClientboundKeepAlive packet IDs by protocol version, newest first.
+var configClientboundKeepAliveIDs = []versionedID{{Protocol1_20_5, ConfigClientboundKeepAlive_ID}, {Protocol1_20_2, 0x03}}
+
+// ToRaw marshals the ConfigClientboundKeepAlive Packet to the given RawPacket.
+func (pi *ConfigClientboundKeepAlive) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigClientboundKeepAlive", p.Protocol, configClientboundKeepAliveIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.KeepAliveID)
+}
+
+// FromRaw unmarshals the ConfigClientboundKeepAlive Packet from the given RawPacket.
+func (pi *ConfigClientboundKeepAlive) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigClientboundKeepAlive", configClientboundKeepAliveIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.KeepAliveID)
+}
+
+// --- ConfigPing ---
+
+// ConfigPing is a packet the client must answer with a ConfigPong of the same ID.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigPing struct {
+	PingID Int
+}
+
+// ConfigPing_ID is the ConfigPing packet ID.
+const ConfigPing_ID = 0x05
+
+// configPingIDs are the ConfigPing packet IDs by protocol version, newest first.
+var configPingIDs = []versionedID{{Protocol1_20_5, ConfigPing_ID}, {Protocol1_20_2, 0x04}}
+
+// ToRaw marshals the ConfigPing Packet to the given RawPacket.
+func (pi *ConfigPing) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigPing", p.Protocol, configPingIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.PingID)
+}
+
+// FromRaw unmarshals the ConfigPing Packet from the given RawPacket.
+func (pi *ConfigPing) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigPing", configPingIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.PingID)
+}
+
+// --- ConfigResetChat ---
+
+// ConfigResetChat is a packet telling the client to forget the chat session of the player.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigResetChat struct{}
+
+// ConfigResetChat_ID is the ConfigResetChat packet ID.
+const ConfigResetChat_ID = 0x06
+
+// configResetChatIDs are the ConfigResetChat packet IDs by protocol version, newest first.
+var configResetChatIDs = []versionedID{{Protocol1_20_5, ConfigResetChat_ID}}
+
+// ToRaw marshals the ConfigResetChat Packet to the given RawPacket.
+func (pi *ConfigResetChat) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigResetChat", p.Protocol, configResetChatIDs); err != nil {
+		return err
+	}
+	return p.Marshal()
+}
+
+// FromRaw unmarshals the ConfigResetChat Packet from the given RawPacket.
+func (pi *ConfigResetChat) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigResetChat", configResetChatIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi)
+}
+
+// --- RegistryData ---
+
+// RegistryData is a packet sending the entries of a registry to the client,
+// such as minecraft:dimension_type or minecraft:worldgen/biome.
+// Before 1.20.5, all the registries are sent at once as Codec instead, see
+// Registries.Codec.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type RegistryData struct {
+	RegistryID Identifier
+	Entries    RegistryEntries
+	// Codec is the registry codec, sent instead of RegistryID and Entries before 1.20.5.
+	Codec NBTTag
+}
+
+// RegistryData_ID is the RegistryData packet ID.
+const RegistryData_ID = 0x07
+
+// registryDataIDs are the RegistryData packet IDs by protocol version, newest first.
+var registryDataIDs = []versionedID{{Protocol1_20_5, RegistryData_ID}, {Protocol1_20_2, 0x05}}
+
+// ToRaw marshals the RegistryData Packet to the given RawPacket.
+func (pi *RegistryData) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("RegistryData", p.Protocol, registryDataIDs); err != nil {
+		return err
+	}
+	return p.Marshal(pi.types(p.Protocol)...)
+}
+
+// FromRaw unmarshals the RegistryData Packet from the given RawPacket.
+func (pi *RegistryData) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("RegistryData", registryDataIDs); err != nil {
+		return err
+	}
+	pi.RegistryID, pi.Entries, pi.Codec = "", nil, NBTTag{}
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *RegistryData) types(protocol int32) []Type {
+	if protocol != 0 && protocol < Protocol1_20_5 {
+		return []Type{&pi.Codec}
+	}
+	return []Type{&pi.RegistryID, &pi.Entries}
+}
+
+// RegistryEntry is an entry of a registry sent in RegistryData.
+type RegistryEntry struct {
+	ID Identifier
+	// Data is the NBT compound of the entry. If its Value is nil, the entry is
+	// sent without data, and the client uses the data of its known packs.
+	Data NBTTag
+}
+
+// --- RegistryEntries ---
+
+// RegistryEntries is a length-prefixed array of registry entries.
+// Implements proto.Type interface (Minecraft protocol data type).
+type RegistryEntries []RegistryEntry
+
+// ReadFrom reads RegistryEntries data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (es *RegistryEntries) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	// An entry is at least 2 bytes long: an empty identifier and a boolean
+	if err := checkArrayLength(r, count, 2); err != nil {
+		return n, err
+	}
+
+	entries := make(RegistryEntries, count)
+	for i := range entries {
+		nn, err := readTypes(r, &entries[i].ID, optionalNBT{&entries[i].Data})
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	*es = entries
+	return n, nil
+}
+
+// WriteTo writes RegistryEntries data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (es RegistryEntries) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(es)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	for _, e := range es {
+		nn, err := writeTypes(w, e.ID, optionalNBT{&e.Data})
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// --- ConfigRemoveResourcePack ---
+
+// ConfigRemoveResourcePack is a packet telling the client to remove a resource pack
+// pushed with ConfigAddResourcePack.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigRemoveResourcePack struct {
+	// UUID is the UUID of the resource pack to remove. If nil, all of them are removed.
+	UUID *UUID
+}
+
+// ConfigRemoveResourcePack_ID is the ConfigRemoveResourcePack packet ID.
+const ConfigRemoveResourcePack_ID = 0x08
+
+// configRemoveResourcePackIDs are the ConfigRemoveResourcePack packet IDs by protocol version, newest first.
+var configRemoveResourcePackIDs = []versionedID{{Protocol1_20_5, ConfigRemoveResourcePack_ID}, {Protocol1_20_3, 0x06}}
+
+// ToRaw marshals the ConfigRemoveResourcePack Packet to the given RawPacket.
+func (pi *ConfigRemoveResourcePack) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigRemoveResourcePack", p.Protocol, configRemoveResourcePackIDs); err != nil {
+		return err
+	}
+	return p.Marshal(optionalUUID{&pi.UUID, true})
+}
+
+// FromRaw unmarshals the ConfigRemoveResourcePack Packet from the given RawPacket.
+func (pi *ConfigRemoveResourcePack) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigRemoveResourcePack", configRemoveResourcePackIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, optionalUUID{&pi.UUID, true})
+}
+
+// --- ConfigAddResourcePack ---
+
+// ConfigAddResourcePack is a packet telling the client to download and apply a resource pack.
+// The client answers with ConfigResourcePackResponse packets.
+// In 1.20.2, UUID is not sent, and Prompt is sent as JSON.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigAddResourcePack struct {
+	UUID UUID
+	URL  String
+	// Hash is the hex-encoded SHA-1 hash of the resource pack, or empty.
+	Hash   String
+	Forced Boolean
+	// Prompt is the text component shown in the prompt, if its Value is not nil.
+	// See TextComponent.NBT.
+	Prompt NBTTag
+}
+
+// ConfigAddResourcePack_ID is the ConfigAddResourcePack packet ID.
+const ConfigAddResourcePack_ID = 0x09
+
+// configAddResourcePackIDs are the ConfigAddResourcePack packet IDs by protocol version, newest first.
+var configAddResourcePackIDs = []versionedID{{Protocol1_20_5, ConfigAddResourcePack_ID}, {Protocol1_20_3, 0x07}, {Protocol1_20_2, 0x06}}
+
+// ToRaw marshals the ConfigAddResourcePack Packet to the given RawPacket.
+func (pi *ConfigAddResourcePack) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigAddResourcePack", p.Protocol, configAddResourcePackIDs); err != nil {
+		return err
+	}
+	return p.Marshal(pi.types(p.Protocol)...)
+}
+
+// FromRaw unmarshals the ConfigAddResourcePack Packet from the given RawPacket.
+func (pi *ConfigAddResourcePack) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigAddResourcePack", configAddResourcePackIDs); err != nil {
+		return err
+	}
+	pi.UUID = UUID{}
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *ConfigAddResourcePack) types(protocol int32) []Type {
+	if protocol != 0 && protocol < Protocol1_20_3 {
+		return []Type{&pi.URL, &pi.Hash, &pi.Forced, jsonText{&pi.Prompt, true}}
+	}
+	return []Type{&pi.UUID, &pi.URL, &pi.Hash, &pi.Forced, optionalNBT{&pi.Prompt}}
+}
+
+// --- ConfigStoreCookie ---
+
+// ConfigStoreCookie is a packet asking the client to store a cookie.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigStoreCookie struct {
+	Key Identifier
+	// Payload is the data of the cookie, at most 5 KiB.
+	Payload ByteArray
+}
+
+// ConfigStoreCookie_ID is the ConfigStoreCookie packet ID.
+const ConfigStoreCookie_ID = 0x0A
+
+// configStoreCookieIDs are the ConfigStoreCookie packet IDs by protocol version, newest first.
+var configStoreCookieIDs = []versionedID{{Protocol1_20_5, ConfigStoreCookie_ID}}
+
+// ToRaw marshals the ConfigStoreCookie Packet to the given RawPacket.
+func (pi *ConfigStoreCookie) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigStoreCookie", p.Protocol, configStoreCookieIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Key, &pi.Payload)
+}
+
+// FromRaw unmarshals the ConfigStoreCookie Packet from the given RawPacket.
+func (pi *ConfigStoreCookie) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigStoreCookie", configStoreCookieIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Key, &pi.Payload)
+}
+
+// --- ConfigTransfer ---
+
+// ConfigTransfer is a packet telling the client to connect to another server.
+// The client sends the Handshake with StateTransfer as next state.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigTransfer struct {
+	Host String
+	Port VarInt
+}
+
+// ConfigTransfer_ID is the ConfigTransfer packet ID.
+const ConfigTransfer_ID = 0x0B
+
+// configTransferIDs are the ConfigTransfer packet IDs by protocol version, newest first.
+var configTransferIDs = []versionedID{{Protocol1_20_5, ConfigTransfer_ID}}
+
+// ToRaw marshals the ConfigTransfer Packet to the given RawPacket.
+func (pi *ConfigTransfer) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigTransfer", p.Protocol, configTransferIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Host, &pi.Port)
+}
+
+// FromRaw unmarshals the ConfigTransfer Packet from the given RawPacket.
+func (pi *ConfigTransfer) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigTransfer", configTransferIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Host, &pi.Port)
+}
+
+// --- FeatureFlags ---
+
+// FeatureFlags is a packet sending the enabled feature flags to the client,
+// such as minecraft:vanilla.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type FeatureFlags struct {
+	Flags Identifiers
+}
+
+// FeatureFlags_ID is the FeatureFlags packet ID.
+const FeatureFlags_ID = 0x0C
+
+// featureFlagsIDs are the FeatureFlags packet IDs by protocol version, newest first.
+var featureFlagsIDs = []versionedID{{Protocol1_20_5, FeatureFlags_ID}, {Protocol1_20_3, 0x08}, {Protocol1_20_2, 0x07}}
+
+// ToRaw marshals the FeatureFlags Packet to the given RawPacket.
+func (pi *FeatureFlags) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("FeatureFlags", p.Protocol, featureFlagsIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Flags)
+}
+
+// FromRaw unmarshals the FeatureFlags Packet from the given RawPacket.
+func (pi *FeatureFlags) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("FeatureFlags", featureFlagsIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Flags)
+}
+
+// --- Identifiers ---
+
+// Identifiers is a length-prefixed array of identifiers.
+// Implements proto.Type interface (Minecraft protocol data type).
+type Identifiers []Identifier
+
+// ReadFrom reads Identifiers data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (ids *Identifiers) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	if err := checkArrayLength(r, count, 1); err != nil {
+		return n, err
+	}
+
+	identifiers := make(Identifiers, count)
+	for i := range identifiers {
+		nn, err := identifiers[i].ReadFrom(r)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	*ids = identifiers
+	return n, nil
+}
+
+// WriteTo writes Identifiers data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (ids Identifiers) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(ids)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	for _, id := range ids {
+		nn, err := id.WriteTo(w)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// --- ClientboundKnownPacks ---
+
+// ClientboundKnownPacks is a packet sending the data packs of the server to the client.
+// The client answers with ServerboundKnownPacks, the packs it knows too:
+// the entries of their registries are not sent with data.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ClientboundKnownPacks struct {
+	Packs KnownPacks
+}
+
+// ClientboundKnownPacks_ID is the ClientboundKnownPacks packet ID.
+const ClientboundKnownPacks_ID = 0x0E
+
+// clientboundKnownPacksIDs are the ClientboundKnownPacks packet IDs by protocol version, newest first.
+var clientboundKnownPacksIDs = []versionedID{{Protocol1_20_5, ClientboundKnownPacks_ID}}
+
+// ToRaw marshals the ClientboundKnownPacks Packet to the given RawPacket.
+func (pi *ClientboundKnownPacks) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ClientboundKnownPacks", p.Protocol, clientboundKnownPacksIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Packs)
+}
+
+// FromRaw unmarshals the ClientboundKnownPacks Packet from the given RawPacket.
+func (pi *ClientboundKnownPacks) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ClientboundKnownPacks", clientboundKnownPacksIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Packs)
+}
+
+// KnownPack is a data pack, such as the minecraft:core pack of a version.
+type KnownPack struct {
+	Namespace String
+	ID        String
+	Version   String
+}
+
+// --- KnownPacks ---
+
+// KnownPacks is a length-prefixed array of known packs.
+// Implements proto.Type interface (Minecraft protocol data type).
+type KnownPacks []KnownPack
+
+// ReadFrom reads KnownPacks data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (ps *KnownPacks) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	// A pack is at least 3 bytes long: three empty strings
+	if err := checkArrayLength(r, count, 3); err != nil {
+		return n, err
+	}
+
+	packs := make(KnownPacks, count)
+	for i := range packs {
+		nn, err := readTypes(r, &packs[i].Namespace, &packs[i].ID, &packs[i].Version)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	*ps = packs
+	return n, nil
+}
+
+// WriteTo writes KnownPacks data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (ps KnownPacks) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(ps)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	for _, pack := range ps {
+		nn, err := writeTypes(w, pack.Namespace, pack.ID, pack.Version)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// --- ConfigServerLinks ---
+
+// ConfigServerLinks is a packet sending links shown in the pause menu of the client.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type ConfigServerLinks struct {
+	Links ServerLinks
+}
+
+// ConfigServerLinks_ID is the ConfigServerLinks packet ID.
+const ConfigServerLinks_ID = 0x10
+
+// configServerLinksIDs are the ConfigServerLinks packet IDs by protocol version, newest first.
+var configServerLinksIDs = []versionedID{{Protocol1_21, ConfigServerLinks_ID}}
+
+// ToRaw marshals the ConfigServerLinks Packet to the given RawPacket.
+func (pi *ConfigServerLinks) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigServerLinks", p.Protocol, configServerLinksIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Links)
+}
+
+// FromRaw unmarshals the ConfigServerLinks Packet from the given RawPacket.
+func (pi *ConfigServerLinks) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigServerLinks", configServerLinksIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Links)
+}
+
+// Built-in labels of server links, translated by the client.
+const (
+	ServerLinkBugReport = iota
+	ServerLinkCommunityGuidelines
+	ServerLinkSupport
+	ServerLinkStatus
+	ServerLinkFeedback
+	ServerLinkCommunity
+	ServerLinkWebsite
+	ServerLinkForums
+	ServerLinkNews
+	ServerLinkAnnouncements
+)
+
+// ServerLink is a link sent in ConfigServerLinks.
+type ServerLink struct {
+	// Label is the text component of the label, if its Value is not nil.
+	// See TextComponent.NBT.
+	Label NBTTag
+	// BuiltinLabel is the built-in label, used if Label has no Value.
+	BuiltinLabel VarInt
+	URL          String
+}
+
+// --- ServerLinks ---
+
+// ServerLinks is a length-prefixed array of server links.
+// Each link is encoded as a Boolean telling whether its label is built-in,
+// the label and the URL.
+// Implements proto.Type interface (Minecraft protocol data type).
+type ServerLinks []ServerLink
+
+// ReadFrom reads ServerLinks data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (ls *ServerLinks) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	// A link is at least 3 bytes long: a boolean, a label and an empty URL
+	if err := checkArrayLength(r, count, 3); err != nil {
+		return n, err
+	}
+
+	links := make(ServerLinks, count)
+	for i := range links {
+		var builtin Boolean
+		nn, err := builtin.ReadFrom(r)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		if builtin {
+			nn, err = readTypes(r, &links[i].BuiltinLabel, &links[i].URL)
+		} else {
+			nn, err = readTypes(r, &links[i].Label, &links[i].URL)
+		}
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	*ls = links
+	return n, nil
+}
+
+// WriteTo writes ServerLinks data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (ls ServerLinks) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(ls)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	for _, link := range ls {
+		var nn int64
+		if link.Label.Value == nil {
+			nn, err = writeTypes(w, Boolean(true), link.BuiltinLabel, link.URL)
+		} else {
+			nn, err = writeTypes(w, Boolean(false), link.Label, link.URL)
+		}
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// --- ConfigClientInformation ---
+
+// ConfigClientInformation is a packet sending the settings of the client.
+// ParticleStatus is sent from 1.21.2.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigClientInformation struct {
+	// Locale is the language of the client, such as en_us.
+	Locale       String
+	ViewDistance Byte
+	// ChatMode is 0 for full chat, 1 for commands only and 2 for hidden.
+	ChatMode   VarInt
+	ChatColors Boolean
+	// DisplayedSkinParts is a bit mask of the displayed parts of the skin.
+	DisplayedSkinParts UnsignedByte
+	// MainHand is 0 for left, 1 for right.
+	MainHand            VarInt
+	EnableTextFiltering Boolean
+	AllowServerListings Boolean
+	// ParticleStatus is 0 for all particles, 1 for decreased and 2 for minimal.
+	ParticleStatus VarInt
+}
+
+// ConfigClientInformation_ID is the ConfigClientInformation packet ID.
+const ConfigClientInformation_ID = 0x00
+
+// configClientInformationIDs are the ConfigClientInformation packet IDs by protocol version, newest first.
+var configClientInformationIDs = []versionedID{{Protocol1_20_2, ConfigClientInformation_ID}}
+
+// ToRaw marshals the ConfigClientInformation Packet to the given RawPacket.
+func (pi *ConfigClientInformation) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigClientInformation", p.Protocol, configClientInformationIDs); err != nil {
+		return err
+	}
+	return p.Marshal(pi.types(p.Protocol)...)
+}
+
+// FromRaw unmarshals the ConfigClientInformation Packet from the given RawPacket.
+func (pi *ConfigClientInformation) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigClientInformation", configClientInformationIDs); err != nil {
+		return err
+	}
+	pi.ParticleStatus = 0
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *ConfigClientInformation) types(protocol int32) []Type {
+	types := []Type{
+		&pi.Locale, &pi.ViewDistance, &pi.ChatMode, &pi.ChatColors,
+		&pi.DisplayedSkinParts, &pi.MainHand, &pi.EnableTextFiltering, &pi.AllowServerListings,
+	}
+	if protocol >= Protocol1_21_2 {
+		types = append(types, &pi.ParticleStatus)
+	}
+	return types
+}
+
+// --- ConfigCookieResponse ---
+
+// ConfigCookieResponse is a packet answering a ConfigCookieRequest.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigCookieResponse struct {
+	Key Identifier
+	// Payload is the data of the cookie, or nil if the client has no such cookie.
+	Payload ByteArray
+}
+
+// ConfigCookieResponse_ID is the ConfigCookieResponse packet ID.
+const ConfigCookieResponse_ID = 0x01
+
+// configCookieResponseIDs are the ConfigCookieResponse packet IDs by protocol version, newest first.
+var configCookieResponseIDs = []versionedID{{Protocol1_20_5, ConfigCookieResponse_ID}}
+
+// ToRaw marshals the ConfigCookieResponse Packet to the given RawPacket.
+func (pi *ConfigCookieResponse) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigCookieResponse", p.Protocol, configCookieResponseIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Key, optionalByteArray{&pi.Payload})
+}
+
+// FromRaw unmarshals the ConfigCookieResponse Packet from the given RawPacket.
+func (pi *ConfigCookieResponse) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigCookieResponse", configCookieResponseIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Key, optionalByteArray{&pi.Payload})
+}
+
+// --- ConfigServerboundPluginMessage ---
+
+// ConfigServerboundPluginMessage is a plugin message sent to the server on a channel.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigServerboundPluginMessage struct {
+	Channel Identifier
+	Data    RemainingBytes
+}
+
+// ConfigServerboundPluginMessage_ID is the ConfigServerboundPluginMessage packet ID.
+const ConfigServerboundPluginMessage_ID = 0x02
+
+// configServerboundPluginMessageIDs are the ConfigServerboundPluginMessage packet IDs by protocol version, newest first.
+var configServerboundPluginMessageIDs = []versionedID{{Protocol1_20_5, ConfigServerboundPluginMessage_ID}, {Protocol1_20_2, 0x01}}
+
+// ToRaw marshals the ConfigServerboundPluginMessage Packet to the given RawPacket.
+func (pi *ConfigServerboundPluginMessage) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigServerboundPluginMessage", p.Protocol, configServerboundPluginMessageIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Channel, &pi.Data)
+}
+
+// FromRaw unmarshals the ConfigServerboundPluginMessage Packet from the given RawPacket.
+func (pi *ConfigServerboundPluginMessage) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigServerboundPluginMessage", configServerboundPluginMessageIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Channel, &pi.Data)
+}
+
+// --- AcknowledgeFinishConfiguration ---
+
+// AcknowledgeFinishConfiguration is a packet answering FinishConfiguration.
+// The connection is in the play state after it.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type AcknowledgeFinishConfiguration struct{}
+
+// AcknowledgeFinishConfiguration_ID is the AcknowledgeFinishConfiguration packet ID.
+const AcknowledgeFinishConfiguration_ID = 0x03
+
+// acknowledgeFinishConfigurationIDs are the AcknowledgeFinishConfiguration packet IDs by protocol version, newest first.
+var acknowledgeFinishConfigurationIDs = []versionedID{{Protocol1_20_5, AcknowledgeFinishConfiguration_ID}, {Protocol1_20_2, 0x02}}
+
+// ToRaw marshals the AcknowledgeFinishConfiguration Packet to the given RawPacket.
+func (pi *AcknowledgeFinishConfiguration) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("AcknowledgeFinishConfiguration", p.Protocol, acknowledgeFinishConfigurationIDs); err != nil {
+		return err
+	}
+	return p.Marshal()
+}
+
+// FromRaw unmarshals the AcknowledgeFinishConfiguration Packet from the given RawPacket.
+func (pi *AcknowledgeFinishConfiguration) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("AcknowledgeFinishConfiguration", acknowledgeFinishConfigurationIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi)
+}
+
+// --- ConfigServerboundKeepAlive ---
+
+// ConfigServerboundKeepAlive is a packet answering a ConfigClientboundKeepAlive.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigServerboundKeepAlive struct {
+	KeepAliveID Long
+}
+
+// ConfigServerboundKeepAlive_ID is the ConfigServerboundKeepAlive packet ID.
+const ConfigServerboundKeepAlive_ID = 0x04
+
+// configServerboundKeepAliveIDs are the ConfigServerboundKeepAlive packet IDs by protocol version, newest first.
+var configServerboundKeepAliveIDs = []versionedID{{Protocol1_20_5, ConfigServerboundKeepAlive_ID}, {Protocol1_20_2, 0x03}}
+
+// ToRaw marshals the ConfigServerboundKeepAlive Packet to the given RawPacket.
+func (pi *ConfigServerboundKeepAlive) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigServerboundKeepAlive", p.Protocol, configServerboundKeepAliveIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.KeepAliveID)
+}
+
+// FromRaw unmarshals the ConfigServerboundKeepAlive Packet from the given RawPacket.
+func (pi *ConfigServerboundKeepAlive) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigServerboundKeepAlive", configServerboundKeepAliveIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.KeepAliveID)
+}
+
+// --- ConfigPong ---
+
+// ConfigPong is a packet answering a ConfigPing.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigPong struct {
+	PingID Int
+}
+
+// ConfigPong_ID is the ConfigPong packet ID.
+const ConfigPong_ID = 0x05
+
+// configPongIDs are the ConfigPong packet IDs by protocol version, newest first.
+var configPongIDs = []versionedID{{Protocol1_20_5, ConfigPong_ID}, {Protocol1_20_2, 0x04}}
+
+// ToRaw marshals the ConfigPong Packet to the given RawPacket.
+func (pi *ConfigPong) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigPong", p.Protocol, configPongIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.PingID)
+}
+
+// FromRaw unmarshals the ConfigPong Packet from the given RawPacket.
+func (pi *ConfigPong) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigPong", configPongIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.PingID)
+}
+
+// --- ConfigResourcePackResponse ---
+
+// Results of resource packs sent in ConfigResourcePackResponse.
+const (
+	ResourcePackLoaded = iota
+	ResourcePackDeclined
+	ResourcePackFailedDownload
+	ResourcePackAccepted
+	ResourcePackDownloaded
+	ResourcePackInvalidURL
+	ResourcePackFailedReload
+	ResourcePackDiscarded
+)
+
+// ConfigResourcePackResponse is a packet telling the progress of a resource pack
+// pushed with ConfigAddResourcePack. In 1.20.2, UUID is not sent.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ConfigResourcePackResponse struct {
+	UUID   UUID
+	Result VarInt
+}
+
+// ConfigResourcePackResponse_ID is the ConfigResourcePackResponse packet ID.
+const ConfigResourcePackResponse_ID = 0x06
+
+// configResourcePackResponseIDs are the ConfigResourcePackResponse packet IDs by protocol version, newest first.
+var configResourcePackResponseIDs = []versionedID{{Protocol1_20_5, ConfigResourcePackResponse_ID}, {Protocol1_20_2, 0x05}}
+
+// ToRaw marshals the ConfigResourcePackResponse Packet to the given RawPacket.
+func (pi *ConfigResourcePackResponse) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ConfigResourcePackResponse", p.Protocol, configResourcePackResponseIDs); err != nil {
+		return err
+	}
+	return p.Marshal(pi.types(p.Protocol)...)
+}
+
+// FromRaw unmarshals the ConfigResourcePackResponse Packet from the given RawPacket.
+func (pi *ConfigResourcePackResponse) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ConfigResourcePackResponse", configResourcePackResponseIDs); err != nil {
+		return err
+	}
+	pi.UUID = UUID{}
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *ConfigResourcePackResponse) types(protocol int32) []Type {
+	if protocol != 0 && protocol < Protocol1_20_3 {
+		return []Type{&pi.Result}
+	}
+	return []Type{&pi.UUID, &pi.Result}
+}
+
+// --- ServerboundKnownPacks ---
+
+// ServerboundKnownPacks is a packet answering ClientboundKnownPacks with the
+// packs known by the client.
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type ServerboundKnownPacks struct {
+	Packs KnownPacks
+}
+
+// ServerboundKnownPacks_ID is the ServerboundKnownPacks packet ID.
+const ServerboundKnownPacks_ID = 0x07
+
+// serverboundKnownPacksIDs are the ServerboundKnownPacks packet IDs by protocol version, newest first.
+var serverboundKnownPacksIDs = []versionedID{{Protocol1_20_5, ServerboundKnownPacks_ID}}
+
+// ToRaw marshals the ServerboundKnownPacks Packet to the given RawPacket.
+func (pi *ServerboundKnownPacks) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("ServerboundKnownPacks", p.Protocol, serverboundKnownPacksIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Packs)
+}
+
+// FromRaw unmarshals the ServerboundKnownPacks Packet from the given RawPacket.
+func (pi *ServerboundKnownPacks) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("ServerboundKnownPacks", serverboundKnownPacksIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Packs)
+}
+
+// --- Optional fields ---
+
+// optionalNBT is an NBTTag field preceded by a Boolean telling whether it is present.
+// It is absent if its Value is nil.
+// Implements proto.Type interface (Minecraft protocol data type).
+type optionalNBT struct {
+	v *NBTTag
+}
+
+// ReadFrom reads optionalNBT data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (o optionalNBT) ReadFrom(r io.Reader) (n int64, err error) {
+	var present Boolean
+	n, err = present.ReadFrom(r)
+	if err != nil || !present {
+		o.v.Value = nil
+		return n, err
+	}
+	nn, err := o.v.ReadFrom(r)
+	return n + nn, err
+}
+
+// WriteTo writes optionalNBT data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (o optionalNBT) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = Boolean(o.v.Value != nil).WriteTo(w)
+	if err != nil || o.v.Value == nil {
+		return n, err
+	}
+	nn, err := o.v.WriteTo(w)
+	return n + nn, err
+}
+
+func (o optionalNBT) field() interface{} {
+	return o.v
+}
+
+// jsonText is a text component NBTTag field sent as a JSON Chat, before 1.20.3.
+// If optional, it is preceded by a Boolean telling whether it is present, and
+// it is absent if its Value is nil.
+// Implements proto.Type interface (Minecraft protocol data type).
+type jsonText struct {
+	v        *NBTTag
+	optional bool
+}
+
+// ReadFrom reads jsonText data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (t jsonText) ReadFrom(r io.Reader) (n int64, err error) {
+	if t.optional {
+		var present Boolean
+		n, err = present.ReadFrom(r)
+		if err != nil || !present {
+			t.v.Value = nil
+			return n, err
+		}
+	}
+	var text Chat
+	nn, err := text.ReadFrom(r)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	value, err := nbtFromJSON([]byte(text))
+	if err != nil {
+		return n, fmt.Errorf("%w: %v", ErrInvalidNBT, err)
+	}
+	t.v.Value = value
+	return n, nil
+}
+
+// WriteTo writes jsonText data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (t jsonText) WriteTo(w io.Writer) (n int64, err error) {
+	if t.optional {
+		n, err = Boolean(t.v.Value != nil).WriteTo(w)
+		if err != nil || t.v.Value == nil {
+			return n, err
+		}
+	}
+	text, err := nbtToJSON(t.v.Value)
+	if err != nil {
+		return n, err
+	}
+	nn, err := Chat(text).WriteTo(w)
+	return n + nn, err
+}
+
+func (t jsonText) field() interface{} {
+	return t.v
+}
+
+// optionalByteArray is a ByteArray field preceded by a Boolean telling whether it is present.
+// It is absent if nil.
+// Implements proto.Type interface (Minecraft protocol data type).
+type optionalByteArray struct {
+	v *ByteArray
+}
+
+// ReadFrom reads optionalByteArray data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (o optionalByteArray) ReadFrom(r io.Reader) (n int64, err error) {
+	var present Boolean
+	n, err = present.ReadFrom(r)
+	if err != nil || !present {
+		*o.v = nil
+		return n, err
+	}
+	nn, err := o.v.ReadFrom(r)
+	if *o.v == nil {
+		// Present but empty
+		*o.v = ByteArray{}
+	}
+	return n + nn, err
+}
+
+// WriteTo writes optionalByteArray data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (o optionalByteArray) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = Boolean(*o.v != nil).WriteTo(w)
+	if err != nil || *o.v == nil {
+		return n, err
+	}
+	nn, err := o.v.WriteTo(w)
+	return n + nn, err
+}
+
+func (o optionalByteArray) field() interface{} {
+	return o.v
+}
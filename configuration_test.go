@@ -0,0 +1,232 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConfigPacketIDs(t *testing.T) {
+	tests := []struct {
+		packet Packet
+		// ids are the IDs in 1.20.2, 1.20.3 and 1.20.5
+		ids [3]int32
+	}{
+		{&ConfigClientboundPluginMessage{}, [3]int32{0x00, 0x00, ConfigClientboundPluginMessage_ID}},
+		{&ConfigDisconnect{}, [3]int32{0x01, 0x01, ConfigDisconnect_ID}},
+		{&FinishConfiguration{}, [3]int32{0x02, 0x02, FinishConfiguration_ID}},
+		{&RegistryData{}, [3]int32{0x05, 0x05, RegistryData_ID}},
+		{&ConfigAddResourcePack{}, [3]int32{0x06, 0x07, ConfigAddResourcePack_ID}},
+		{&FeatureFlags{}, [3]int32{0x07, 0x08, FeatureFlags_ID}},
+		{&UpdateTags{}, [3]int32{0x08, 0x09, UpdateTags_ID}},
+		{&ConfigServerboundPluginMessage{}, [3]int32{0x01, 0x01, ConfigServerboundPluginMessage_ID}},
+		{&AcknowledgeFinishConfiguration{}, [3]int32{0x02, 0x02, AcknowledgeFinishConfiguration_ID}},
+		{&ConfigResourcePackResponse{}, [3]int32{0x05, 0x05, ConfigResourcePackResponse_ID}},
+	}
+	for _, test := range tests {
+		for i, protocol := range []int32{Protocol1_20_2, Protocol1_20_3, Protocol1_20_5} {
+			raw := roundTrip(t, protocol, test.packet, test.packet)
+			if raw.ID != test.ids[i] {
+				t.Errorf("%T in protocol %d: got ID 0x%02X, want 0x%02X", test.packet, protocol, raw.ID, test.ids[i])
+			}
+		}
+		// The documented layout is the latest
+		if raw := roundTrip(t, 0, test.packet, test.packet); raw.ID != test.ids[2] {
+			t.Errorf("%T in protocol 0: got ID 0x%02X, want 0x%02X", test.packet, raw.ID, test.ids[2])
+		}
+	}
+}
+
+func TestConfigPacketUnsupported(t *testing.T) {
+	tests := []struct {
+		packet   Packet
+		protocol int32
+	}{
+		{&ConfigServerLinks{}, Protocol1_20_5},
+		{&ClientboundKnownPacks{}, Protocol1_20_3},
+		{&ConfigRemoveResourcePack{}, Protocol1_20_2},
+		{&FinishConfiguration{}, Protocol1_20},
+	}
+	for _, test := range tests {
+		raw := &RawPacket{Protocol: test.protocol}
+		if err := test.packet.ToRaw(raw); !errors.Is(err, ErrUnsupportedProtocol) {
+			t.Errorf("%T in protocol %d: ToRaw: got %v, want ErrUnsupportedProtocol", test.packet, test.protocol, err)
+		}
+		if err := test.packet.FromRaw(raw); !errors.Is(err, ErrUnsupportedProtocol) {
+			t.Errorf("%T in protocol %d: FromRaw: got %v, want ErrUnsupportedProtocol", test.packet, test.protocol, err)
+		}
+	}
+}
+
+func TestConfigPacketWrongID(t *testing.T) {
+	raw := &RawPacket{ID: FinishConfiguration_ID, Protocol: Protocol1_20_2}
+	err := (&FinishConfiguration{}).FromRaw(raw)
+	var idErr *PacketIDError
+	if !errors.As(err, &idErr) || idErr.Expect != 0x02 || idErr.Get != FinishConfiguration_ID {
+		t.Errorf("got %v, want wrong packet id 0x02", err)
+	}
+}
+
+func TestRegistryData(t *testing.T) {
+	in := RegistryData{
+		RegistryID: "minecraft:dimension_type",
+		Entries: RegistryEntries{
+			{ID: "minecraft:overworld", Data: NBTTag{Value: NBTCompound{"height": int32(384)}}},
+			{ID: "minecraft:the_nether"},
+		},
+	}
+	var out RegistryData
+	roundTrip(t, Protocol1_20_5, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+
+	// Before 1.20.5, only the codec is sent
+	in = RegistryData{Codec: NBTTag{Value: NBTCompound{"minecraft:dimension_type": NBTCompound{}}}}
+	out = RegistryData{RegistryID: "stale"}
+	roundTrip(t, Protocol1_20_3, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestConfigDisconnectJSON(t *testing.T) {
+	in := ConfigDisconnect{Reason: NBTTag{Value: NBTCompound{"text": "bye", "bold": int8(1)}}}
+	var out ConfigDisconnect
+	raw := roundTrip(t, Protocol1_20_2, &in, &out)
+
+	var reason Chat
+	if err := raw.Unmarshal(&reason); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"bold":true,"text":"bye"}`; string(reason) != want {
+		t.Errorf("got JSON %s, want %s", reason, want)
+	}
+	want := NBTCompound{"text": "bye", "bold": true}
+	if !reflect.DeepEqual(out.Reason.Value, want) {
+		t.Errorf("got %#v, want %#v", out.Reason.Value, want)
+	}
+
+	raw.Data = []byte{3, '{', 'x', '}'}
+	if err := out.FromRaw(raw); !errors.Is(err, ErrInvalidNBT) {
+		t.Errorf("malformed JSON: got %v, want ErrInvalidNBT", err)
+	}
+}
+
+func TestConfigAddResourcePack(t *testing.T) {
+	prompt := NBTTag{Value: NBTCompound{"text": "please"}}
+	for _, protocol := range []int32{Protocol1_20_2, Protocol1_20_3, Protocol1_20_5} {
+		for _, in := range []ConfigAddResourcePack{
+			{URL: "https://example.com/pack.zip", Forced: true, Prompt: prompt},
+			{URL: "https://example.com/pack.zip", Hash: "0123456789abcdef0123456789abcdef01234567"},
+		} {
+			if protocol >= Protocol1_20_3 {
+				in.UUID = UUID{1, 2, 3}
+			}
+			out := ConfigAddResourcePack{UUID: UUID{9}, Prompt: NBTTag{Value: "stale"}}
+			roundTrip(t, protocol, &in, &out)
+			if !reflect.DeepEqual(out, in) {
+				t.Errorf("protocol %d: got %#v, want %#v", protocol, out, in)
+			}
+		}
+	}
+}
+
+func TestConfigResourcePackResponse(t *testing.T) {
+	in := ConfigResourcePackResponse{Result: ResourcePackDeclined}
+	var out ConfigResourcePackResponse
+	raw := roundTrip(t, Protocol1_20_2, &in, &out)
+	if !bytes.Equal(raw.Data, []byte{ResourcePackDeclined}) {
+		t.Errorf("got % X, want the result only", raw.Data)
+	}
+
+	in.UUID = UUID{1}
+	roundTrip(t, Protocol1_20_3, &in, &out)
+	if out != in {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestConfigCookieResponse(t *testing.T) {
+	for _, payload := range []ByteArray{nil, {}, {1, 2}} {
+		in := ConfigCookieResponse{Key: "example:cookie", Payload: payload}
+		out := ConfigCookieResponse{Payload: ByteArray{9}}
+		roundTrip(t, Protocol1_20_5, &in, &out)
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("got %#v, want %#v", out, in)
+		}
+	}
+}
+
+func TestServerLinks(t *testing.T) {
+	in := ConfigServerLinks{Links: ServerLinks{
+		{BuiltinLabel: ServerLinkBugReport, URL: "https://example.com/bugs"},
+		{Label: NBTTag{Value: NBTCompound{"text": "Map"}}, URL: "https://example.com/map"},
+	}}
+	var out ConfigServerLinks
+	roundTrip(t, Protocol1_21, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestKnownPacks(t *testing.T) {
+	in := ClientboundKnownPacks{Packs: KnownPacks{{Namespace: "minecraft", ID: "core", Version: "1.21"}}}
+	var out ClientboundKnownPacks
+	roundTrip(t, Protocol1_21, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestUpdateTags(t *testing.T) {
+	in := UpdateTags{Registries: TagRegistries{
+		{Registry: "minecraft:block", Tags: []Tag{
+			{Name: "minecraft:logs", Entries: []VarInt{1, 2, 300}},
+			{Name: "minecraft:empty", Entries: []VarInt{}},
+		}},
+		{Registry: "minecraft:item", Tags: []Tag{}},
+	}}
+	var out UpdateTags
+	roundTrip(t, Protocol1_20_5, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestConfigTypesMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  Type
+		data []byte
+		err  error
+	}{
+		{"registry entries over MaxArrayLength", &RegistryEntries{}, []byte{0x80, 0x80, 0x80, 0x01}, ErrLimitExceeded},
+		{"registry entries truncated", &RegistryEntries{}, []byte{0x10, 0x00}, ErrTruncated},
+		{"registry entry NBT truncated", &RegistryEntries{}, []byte{0x01, 0x00, 0x01, TagCompound}, ErrTruncated},
+		{"known packs truncated", &KnownPacks{}, []byte{0x02, 0x00, 0x00, 0x00}, ErrTruncated},
+		{"server links negative count", &ServerLinks{}, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, ErrLimitExceeded},
+		{"server link label invalid", &ServerLinks{}, []byte{0x01, 0x00, 13, 0x00}, ErrInvalidNBT},
+		{"tags truncated", &TagRegistries{}, []byte{0x01, 0x00, 0x05}, ErrTruncated},
+		{"tag entries over MaxArrayLength", &TagRegistries{}, []byte{0x01, 0x00, 0x01, 0x00, 0x80, 0x80, 0x80, 0x01}, ErrLimitExceeded},
+	}
+	for _, test := range tests {
+		raw := &RawPacket{Data: test.data}
+		if err := raw.Unmarshal(test.typ); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestConfigPacketFieldError(t *testing.T) {
+	raw := &RawPacket{ID: RegistryData_ID, Data: []byte{0x00, 0x01, 0x00, 0x01, 13}}
+	err := (&RegistryData{}).FromRaw(raw)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("got %v, want a *FieldError", err)
+	}
+	if fieldErr.Packet != "RegistryData" || fieldErr.Field != "Entries" || fieldErr.Offset != 1 {
+		t.Errorf("got %s.%s at offset %d, want RegistryData.Entries at offset 1", fieldErr.Packet, fieldErr.Field, fieldErr.Offset)
+	}
+}
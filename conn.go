@@ -0,0 +1,157 @@
+package proto
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"time"
+)
+
+// Conn is a network connection speaking the Minecraft protocol.
+// It frames packets with the current compression threshold and applies its
+// decoding options to every packet it reads.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer io.Writer
+
+	// stream is the last packet read by ReadPacketStream
+	stream *PacketReader
+
+	// Threshold is the compression threshold.
+	// A negative value means compression is disabled.
+	Threshold int
+	// Limits restricts the sizes accepted when reading packets.
+	// If nil, DefaultLimits are used.
+	Limits *Limits
+	// Strict makes decoding fail on packets that have trailing bytes.
+	// See RawPacket.Strict.
+	Strict bool
+	// Protocol is the protocol version of the packets read and sent by Receive and Send.
+	// See RawPacket.Protocol.
+	Protocol int32
+}
+
+// NewConn creates a new Conn over the given network connection,
+// with compression disabled.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		writer:    conn,
+		Threshold: -1,
+	}
+}
+
+// ReadPacket reads the next packet from the connection.
+// The Limits, Strict and Protocol fields of p are set to the ones of the connection.
+func (c *Conn) ReadPacket(p *RawPacket) error {
+	if err := c.discardStream(); err != nil {
+		return err
+	}
+	p.Limits, p.Strict, p.Protocol = c.Limits, c.Strict, c.Protocol
+	return p.Unpack(c.reader, c.Threshold)
+}
+
+// WritePacket writes the packet to the connection.
+func (c *Conn) WritePacket(p *RawPacket) error {
+	return p.Pack(c.writer, c.Threshold)
+}
+
+// ReadFrame reads the next frame from the connection without decoding it.
+func (c *Conn) ReadFrame(f *Frame) error {
+	if err := c.discardStream(); err != nil {
+		return err
+	}
+	f.Limits = c.Limits
+	return f.Unpack(c.reader, c.Threshold)
+}
+
+// ReadPacketStream reads the header of the next packet from the connection,
+// and returns a reader streaming its data.
+// The data left unread are discarded when the next packet is read.
+// The returned reader is only valid until then.
+func (c *Conn) ReadPacketStream() (*PacketReader, error) {
+	if err := c.discardStream(); err != nil {
+		return nil, err
+	}
+	if c.stream == nil {
+		c.stream = NewPacketReader()
+	}
+	c.stream.Limits = c.Limits
+
+	err := c.stream.Unpack(c.reader, c.Threshold)
+	if err != nil {
+		c.stream.Close()
+		return nil, err
+	}
+
+	return c.stream, nil
+}
+
+// discardStream discards the data left unread of the last packet read by ReadPacketStream
+func (c *Conn) discardStream() error {
+	if c.stream == nil {
+		return nil
+	}
+	return c.stream.Close()
+}
+
+// WriteFrame writes the frame to the connection.
+// The frame is forwarded byte-for-byte if it is encoded with the threshold of the connection.
+func (c *Conn) WriteFrame(f *Frame) error {
+	return f.Pack(c.writer, c.Threshold)
+}
+
+// Receive reads the next packet from the connection and decodes it to pk.
+func (c *Conn) Receive(pk Packet) error {
+	var p RawPacket
+	if err := c.ReadPacket(&p); err != nil {
+		return err
+	}
+	return pk.FromRaw(&p)
+}
+
+// Send encodes pk and writes it to the connection.
+func (c *Conn) Send(pk Packet) error {
+	p := RawPacket{Protocol: c.Protocol}
+	if err := pk.ToRaw(&p); err != nil {
+		return err
+	}
+	return c.WritePacket(&p)
+}
+
+// NetConn returns the underlying network connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,91 @@
+package proto
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"fmt"
+	"math/big"
+)
+
+// SharedSecretLength is the length of the shared secret of encrypted connections.
+const SharedSecretLength = 16
+
+// EnableEncryption encrypts the rest of the connection with the shared secret,
+// using AES/CFB8 with the secret as key and IV.
+// It must be called right after the EncryptionResponse is written or read.
+func (c *Conn) EnableEncryption(secret []byte) error {
+	if len(secret) != SharedSecretLength {
+		return fmt.Errorf("invalid shared secret length %d", len(secret))
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return err
+	}
+
+	// The bytes already buffered by the reader are encrypted too
+	c.reader = bufio.NewReader(cipher.StreamReader{S: newCFB8(block, secret, true), R: c.reader})
+	c.writer = cipher.StreamWriter{S: newCFB8(block, secret, false), W: c.writer}
+	return nil
+}
+
+// cfb8 implements the 8-bit cipher feedback mode, missing from crypto/cipher
+type cfb8 struct {
+	block   cipher.Block
+	sr      []byte // shift register
+	out     []byte
+	decrypt bool
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) *cfb8 {
+	size := block.BlockSize()
+	sr := make([]byte, size)
+	copy(sr, iv)
+	return &cfb8{block: block, sr: sr, out: make([]byte, size), decrypt: decrypt}
+}
+
+// XORKeyStream implements cipher.Stream.
+func (x *cfb8) XORKeyStream(dst, src []byte) {
+	for i, b := range src {
+		x.block.Encrypt(x.out, x.sr)
+		dst[i] = b ^ x.out[0]
+
+		copy(x.sr, x.sr[1:])
+		if x.decrypt {
+			x.sr[len(x.sr)-1] = b
+		} else {
+			x.sr[len(x.sr)-1] = dst[i]
+		}
+	}
+}
+
+// AuthDigest returns the server hash sent to the session server by clients
+// and servers to authenticate a player: the SHA-1 of the server ID, the shared
+// secret and the DER-encoded public key of the server, as a signed hexadecimal number.
+func AuthDigest(serverID string, secret, publicKey []byte) string {
+	h := sha1.New()
+	h.Write([]byte(serverID))
+	h.Write(secret)
+	h.Write(publicKey)
+	sum := h.Sum(nil)
+
+	negative := sum[0]&0x80 != 0
+	if negative {
+		// Two's complement
+		carry := true
+		for i := len(sum) - 1; i >= 0; i-- {
+			sum[i] = ^sum[i]
+			if carry {
+				sum[i]++
+				carry = sum[i] == 0
+			}
+		}
+	}
+
+	digest := new(big.Int).SetBytes(sum).Text(16)
+	if negative {
+		return "-" + digest
+	}
+	return digest
+}
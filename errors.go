@@ -1,16 +1,197 @@
 package proto
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
-type wrongPacketErr struct {
-	expect int32
-	get    int32
+// Errors matched with errors.Is by the typed errors of this package.
+var (
+	// ErrWrongPacket is matched by every *PacketIDError.
+	ErrWrongPacket = errors.New("wrong packet id")
+	// ErrTruncated is returned when a packet ends in the middle of a field.
+	ErrTruncated = errors.New("truncated")
+	// ErrTrailingBytes is matched by every *TrailingBytesError.
+	ErrTrailingBytes = errors.New("trailing bytes")
+	// ErrOutOfRange is matched by every *RangeError.
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrLimitExceeded is matched by every *LimitError.
+	ErrLimitExceeded = errors.New("limit exceeded")
+	// ErrCompression is matched by every *CompressionError.
+	ErrCompression = errors.New("compression error")
+	// ErrUnexpectedPacket is returned when a packet is received in the wrong order.
+	ErrUnexpectedPacket = errors.New("unexpected packet")
+	// ErrUnsupportedProtocol is returned when a protocol version is not supported.
+	ErrUnsupportedProtocol = errors.New("unsupported protocol version")
+	// ErrInvalidTag is returned when a tag cannot be loaded or resolved.
+	ErrInvalidTag = errors.New("invalid tag")
+	// ErrDisconnected is matched by every *DisconnectError.
+	ErrDisconnected = errors.New("disconnected")
+)
+
+// --- PacketIDError ---
+
+// PacketIDError is returned when a packet is decoded from a RawPacket with another ID.
+type PacketIDError struct {
+	// Packet is the name of the packet being decoded.
+	Packet string
+	Expect int32
+	Get    int32
+}
+
+func (e *PacketIDError) Error() string {
+	if e.Packet == "" {
+		return fmt.Sprintf("wrong packet id: expect 0x%02X, get 0x%02X", e.Expect, e.Get)
+	}
+	return fmt.Sprintf("wrong packet id for %s: expect 0x%02X, get 0x%02X", e.Packet, e.Expect, e.Get)
 }
 
-func (w wrongPacketErr) Error() string {
-	return fmt.Sprintf("wrong packet id: expect %#02X, get %#02X", w.expect, w.get)
+// Is reports whether target is ErrWrongPacket.
+func (e *PacketIDError) Is(target error) bool {
+	return target == ErrWrongPacket
 }
 
+// WrongPacketError returns a *PacketIDError.
 func WrongPacketError(expect, get int32) error {
-	return wrongPacketErr{expect, get}
+	return &PacketIDError{Expect: expect, Get: get}
+}
+
+// --- FieldError ---
+
+// FieldError is returned when a field of a packet cannot be decoded.
+// It wraps the cause: ErrTruncated, a *LimitError, a *RangeError...
+type FieldError struct {
+	// Packet is the name of the packet, if known.
+	Packet string
+	// Field is the name of the field, if known.
+	Field string
+	// Index is the index of the field in the decoded types.
+	Index int
+	// Offset is the offset in the packet data of the field that could not be decoded.
+	Offset int64
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	field := e.Field
+	if field == "" {
+		field = fmt.Sprintf("field #%d", e.Index)
+	}
+	if e.Packet != "" {
+		field = e.Packet + "." + field
+	}
+	return fmt.Sprintf("%s: %v at offset %d", field, e.Err, e.Offset)
+}
+
+// Unwrap returns the cause of the error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// --- TrailingBytesError ---
+
+// TrailingBytesError is returned when a packet has data left after its last field.
+type TrailingBytesError struct {
+	// Packet is the name of the packet, if known.
+	Packet string
+	// Offset is the offset of the first unread byte.
+	Offset int64
+	// Remaining is the number of unread bytes.
+	Remaining int
+}
+
+func (e *TrailingBytesError) Error() string {
+	packet := e.Packet
+	if packet == "" {
+		packet = "packet"
+	}
+	return fmt.Sprintf("%s: %d trailing bytes at offset %d", packet, e.Remaining, e.Offset)
+}
+
+// Is reports whether target is ErrTrailingBytes.
+func (e *TrailingBytesError) Is(target error) bool {
+	return target == ErrTrailingBytes
+}
+
+// --- RangeError ---
+
+// RangeError is returned when a decoded value is outside of its valid range.
+type RangeError struct {
+	// Name describes the value.
+	Name  string
+	Value int64
+	Min   int64
+	Max   int64
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s out of range: %d not in [%d, %d]", e.Name, e.Value, e.Min, e.Max)
+}
+
+// Is reports whether target is ErrOutOfRange.
+func (e *RangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
+// --- LimitError ---
+
+// LimitError is returned when a decoded value exceeds one of the Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded Limits field.
+	Limit string
+	// Value is the decoded value.
+	Value int64
+	// Max is the value of the limit.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Value < 0 {
+		return fmt.Sprintf("%s: invalid negative length %d", e.Limit, e.Value)
+	}
+	return fmt.Sprintf("%s exceeded: %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// --- CompressionError ---
+
+// CompressionError is returned when a compressed packet cannot be decoded.
+type CompressionError struct {
+	Err error
+}
+
+func (e *CompressionError) Error() string {
+	return "compressed packet error: " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *CompressionError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrCompression.
+func (e *CompressionError) Is(target error) bool {
+	return target == ErrCompression
+}
+
+// --- DisconnectError ---
+
+// DisconnectError is the reason a connection is closed by the server.
+// Clients return it when they receive a disconnect packet, and servers send
+// its reason when a callback returns it.
+type DisconnectError struct {
+	Reason TextComponent
+}
+
+func (e *DisconnectError) Error() string {
+	return "disconnected: " + e.Reason.PlainText()
+}
+
+// Is reports whether target is ErrDisconnected.
+func (e *DisconnectError) Is(target error) bool {
+	return target == ErrDisconnected
 }
@@ -0,0 +1,86 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestErrorsIs(t *testing.T) {
+	sentinels := []error{ErrWrongPacket, ErrTruncated, ErrTrailingBytes, ErrOutOfRange, ErrLimitExceeded, ErrCompression, ErrDisconnected}
+	tests := []struct {
+		err  error
+		want error
+		msg  string
+	}{
+		{WrongPacketError(0x00, 0x01), ErrWrongPacket, "wrong packet id: expect 0x00, get 0x01"},
+		{&PacketIDError{Packet: "Handshake", Expect: 0x00, Get: 0x7F}, ErrWrongPacket, "wrong packet id for Handshake: expect 0x00, get 0x7F"},
+		{&FieldError{Packet: "LoginStart", Field: "Name", Offset: 3, Err: ErrTruncated}, ErrTruncated, "LoginStart.Name: truncated at offset 3"},
+		{&FieldError{Index: 2, Offset: 5, Err: &RangeError{Name: "VarInt length", Value: 6, Min: 1, Max: 5}}, ErrOutOfRange,
+			"field #2: VarInt length out of range: 6 not in [1, 5] at offset 5"},
+		{&TrailingBytesError{Packet: "Request", Offset: 0, Remaining: 2}, ErrTrailingBytes, "Request: 2 trailing bytes at offset 0"},
+		{&TrailingBytesError{Offset: 4, Remaining: 1}, ErrTrailingBytes, "packet: 1 trailing bytes at offset 4"},
+		{&LimitError{Limit: "MaxStringLength", Value: 40000, Max: 32767}, ErrLimitExceeded, "MaxStringLength exceeded: 40000 > 32767"},
+		{&LimitError{Limit: "MaxArrayLength", Value: -1}, ErrLimitExceeded, "MaxArrayLength: invalid negative length -1"},
+		{&CompressionError{io.ErrUnexpectedEOF}, ErrCompression, "compressed packet error: unexpected EOF"},
+		{&DisconnectError{Reason: TextComponent{Text: "Server closed"}}, ErrDisconnected, "disconnected: Server closed"},
+	}
+	for _, test := range tests {
+		if msg := test.err.Error(); msg != test.msg {
+			t.Errorf("got message %q, want %q", msg, test.msg)
+		}
+		for _, sentinel := range sentinels {
+			if got := errors.Is(test.err, sentinel); got != (sentinel == test.want) {
+				t.Errorf("%v: errors.Is(%v) = %v", test.err, sentinel, got)
+			}
+		}
+	}
+
+	// The causes of wrapping errors are matched too
+	err := &FieldError{Err: &LimitError{Limit: "MaxNBTDepth"}}
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxNBTDepth" {
+		t.Errorf("got %v, want the *LimitError", limitErr)
+	}
+	if err := (&CompressionError{io.ErrUnexpectedEOF}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("%v does not wrap io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	// Handshake with a truncated port
+	raw := &RawPacket{ID: Handshake_ID, Data: []byte{0x01, 0x02, 'a', 'b', 0x63}}
+	err := (&Handshake{}).FromRaw(raw)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Err != ErrTruncated {
+		t.Fatalf("got %v, want a truncated *FieldError", err)
+	}
+	if msg := err.Error(); msg != "Handshake.ServerPort: truncated at offset 4" {
+		t.Errorf("got %q", msg)
+	}
+
+	// Handshake with a VarInt too long
+	raw.Data = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}
+	err = (&Handshake{}).FromRaw(raw)
+	var rangeErr *RangeError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "ProtocolVersion" || !errors.As(err, &rangeErr) || rangeErr.Max != MaxVarIntLen {
+		t.Errorf("got %v, want a *RangeError on ProtocolVersion", err)
+	}
+
+	// Handshake with another ID
+	raw.ID = 0x01
+	var idErr *PacketIDError
+	if err := (&Handshake{}).FromRaw(raw); !errors.As(err, &idErr) || idErr.Packet != "Handshake" || idErr.Get != 0x01 {
+		t.Errorf("got %v, want a *PacketIDError", err)
+	}
+
+	// Compressed frames with a size below the threshold, and corrupted data
+	for _, data := range [][]byte{frame([]byte{0x78, 0x9C}, 3, 10), frame([]byte{0x00, 0x00}, 4, 300)} {
+		var p RawPacket
+		var compressionErr *CompressionError
+		if err := p.Unpack(bytes.NewReader(data), 256); !errors.As(err, &compressionErr) {
+			t.Errorf("% X: got %v, want a *CompressionError", data, err)
+		}
+	}
+}
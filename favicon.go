@@ -0,0 +1,161 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// FaviconSize is the width and height of a server favicon, in pixels.
+const FaviconSize = 64
+
+// faviconPrefix is the prefix of the data URI of a favicon
+const faviconPrefix = "data:image/png;base64,"
+
+// ErrInvalidFavicon is returned when a favicon is not a data URI of a PNG image.
+var ErrInvalidFavicon = errors.New("invalid favicon")
+
+// EncodeFavicon encodes the image to a favicon data URI.
+// The image is resized to 64x64 if needed.
+func EncodeFavicon(img image.Image) (string, error) {
+	if size := img.Bounds().Size(); size.X != FaviconSize || size.Y != FaviconSize {
+		resized, err := resizeImage(img, FaviconSize, FaviconSize)
+		if err != nil {
+			return "", err
+		}
+		img = resized
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return "", err
+	}
+	return faviconPrefix + base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// EncodeFaviconPNG encodes the PNG image to a favicon data URI.
+// A 64x64 image is used as is, other images are resized.
+func EncodeFaviconPNG(b []byte) (string, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+	if config.Width == FaviconSize && config.Height == FaviconSize {
+		return faviconPrefix + base64.StdEncoding.EncodeToString(b), nil
+	}
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+	return EncodeFavicon(img)
+}
+
+// DecodeFavicon decodes a favicon data URI to an image.
+// Line breaks, sent in the base64 data by some old servers, are ignored.
+// Images other than 64x64 are rejected before being decoded.
+func DecodeFavicon(favicon string) (image.Image, error) {
+	if !strings.HasPrefix(favicon, faviconPrefix) {
+		return nil, ErrInvalidFavicon
+	}
+
+	data := strings.NewReplacer("\n", "", "\r", "").Replace(favicon[len(faviconPrefix):])
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+
+	// The size is checked first, as the image may come from an untrusted server
+	config, err := png.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+	if config.Width != FaviconSize || config.Height != FaviconSize {
+		return nil, fmt.Errorf("%w: size is %dx%d instead of %dx%d", ErrInvalidFavicon, config.Width, config.Height, FaviconSize, FaviconSize)
+	}
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+	return img, nil
+}
+
+// ValidateFavicon checks that the favicon is a data URI of a 64x64 PNG image.
+func ValidateFavicon(favicon string) error {
+	_, err := DecodeFavicon(favicon)
+	return err
+}
+
+// SetFavicon sets the favicon of the status to the image, resized to 64x64 if needed.
+func (s *StatusResponse) SetFavicon(img image.Image) error {
+	favicon, err := EncodeFavicon(img)
+	if err != nil {
+		return err
+	}
+	s.Favicon = favicon
+	return nil
+}
+
+// FaviconImage decodes the favicon of the status.
+func (s *StatusResponse) FaviconImage() (image.Image, error) {
+	return DecodeFavicon(s.Favicon)
+}
+
+// Favicon decodes the favicon of the status carried by the Response.
+func (pi *Response) Favicon() (image.Image, error) {
+	if pi.Status == nil {
+		return nil, ErrInvalidFavicon
+	}
+	return pi.Status.FaviconImage()
+}
+
+// resizeImage resizes the image by area averaging:
+// each destination pixel is the average of the source pixels it covers.
+// An empty source image cannot be resized.
+func resizeImage(src image.Image, width, height int) (*image.NRGBA, error) {
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("%w: empty image", ErrInvalidFavicon)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		y0, y1 := float64(y)*scaleY, float64(y+1)*scaleY
+		for x := 0; x < width; x++ {
+			x0, x1 := float64(x)*scaleX, float64(x+1)*scaleX
+
+			var r, g, b, a, total float64
+			for sy := int(y0); float64(sy) < y1; sy++ {
+				wy := math.Min(y1, float64(sy+1)) - math.Max(y0, float64(sy))
+				for sx := int(x0); float64(sx) < x1; sx++ {
+					wx := math.Min(x1, float64(sx+1)) - math.Max(x0, float64(sx))
+					w := wx * wy
+					// Premultiplied colors
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r += float64(cr) * w
+					g += float64(cg) * w
+					b += float64(cb) * w
+					a += float64(ca) * w
+					total += w
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / total),
+				G: uint16(g / total),
+				B: uint16(b / total),
+				A: uint16(a / total),
+			})
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,171 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func uniformImage(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFaviconResize(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	halves := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	stripes := image.NewNRGBA(image.Rect(0, 0, 128, 64))
+	transparent := image.NewNRGBA(image.Rect(0, 0, 128, 64))
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if x < 64 {
+				halves.Set(x, y, red)
+			} else {
+				halves.Set(x, y, blue)
+			}
+			if x%2 == 0 {
+				stripes.Set(x, y, color.NRGBA{A: 255})
+				transparent.Set(x, y, red)
+			} else {
+				stripes.Set(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+		}
+	}
+	// The bounds of a sub-image do not start at 0
+	offset := uniformImage(100, 100, blue).SubImage(image.Rect(10, 20, 90, 60))
+
+	tests := []struct {
+		name string
+		img  image.Image
+		// want returns the expected color of a destination pixel
+		want func(x, y int) color.NRGBA
+	}{
+		{"downscaled", halves, func(x, y int) color.NRGBA {
+			if x < 32 {
+				return red
+			}
+			return blue
+		}},
+		{"upscaled", uniformImage(16, 16, red), func(x, y int) color.NRGBA { return red }},
+		{"non integer", uniformImage(100, 30, red), func(x, y int) color.NRGBA { return red }},
+		{"averaged", stripes, func(x, y int) color.NRGBA { return color.NRGBA{R: 127, G: 127, B: 127, A: 255} }},
+		// Colors are weighted by their alpha
+		{"alpha", transparent, func(x, y int) color.NRGBA { return color.NRGBA{R: 255, A: 127} }},
+		{"offset", offset, func(x, y int) color.NRGBA { return blue }},
+	}
+	for _, test := range tests {
+		favicon, err := EncodeFavicon(test.img)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		img, err := DecodeFavicon(favicon)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if size := img.Bounds().Size(); size != image.Pt(FaviconSize, FaviconSize) {
+			t.Errorf("%s: got size %v", test.name, size)
+			continue
+		}
+	pixels:
+		for y := 0; y < FaviconSize; y++ {
+			for x := 0; x < FaviconSize; x++ {
+				got := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if want := test.want(x, y); got != want {
+					t.Errorf("%s: pixel (%d, %d): got %v, want %v", test.name, x, y, got, want)
+					break pixels
+				}
+			}
+		}
+	}
+
+	if _, err := EncodeFavicon(image.NewNRGBA(image.Rect(0, 0, 0, 10))); !errors.Is(err, ErrInvalidFavicon) {
+		t.Errorf("empty image: got %v, want ErrInvalidFavicon", err)
+	}
+}
+
+func TestFaviconPNG(t *testing.T) {
+	// A 64x64 PNG is not encoded again
+	b := encodePNG(t, uniformImage(64, 64, color.NRGBA{G: 255, A: 255}))
+	favicon, err := EncodeFaviconPNG(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := faviconPrefix + base64.StdEncoding.EncodeToString(b); favicon != want {
+		t.Errorf("got %q, want %q", favicon, want)
+	}
+
+	favicon, err = EncodeFaviconPNG(encodePNG(t, uniformImage(32, 48, color.NRGBA{G: 255, A: 255})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateFavicon(favicon); err != nil {
+		t.Errorf("resized: %v", err)
+	}
+
+	if _, err := EncodeFaviconPNG([]byte("GIF89a")); !errors.Is(err, ErrInvalidFavicon) {
+		t.Errorf("not a PNG: got %v, want ErrInvalidFavicon", err)
+	}
+}
+
+func TestDecodeFavicon(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString(encodePNG(t, uniformImage(64, 64, color.NRGBA{A: 255})))
+
+	// Some old servers break the base64 data in lines
+	var wrapped strings.Builder
+	for i := 0; i < len(data); i += 76 {
+		end := i + 76
+		if end > len(data) {
+			end = len(data)
+		}
+		wrapped.WriteString(data[i:end] + "\r\n")
+	}
+	if err := ValidateFavicon(faviconPrefix + wrapped.String()); err != nil {
+		t.Errorf("line breaks: %v", err)
+	}
+
+	for name, favicon := range map[string]string{
+		"empty":     "",
+		"prefix":    "data:image/jpeg;base64," + data,
+		"base64":    faviconPrefix + "not base64!",
+		"not PNG":   faviconPrefix + base64.StdEncoding.EncodeToString([]byte("GIF89a")),
+		"size":      faviconPrefix + base64.StdEncoding.EncodeToString(encodePNG(t, uniformImage(32, 32, color.NRGBA{A: 255}))),
+		"truncated": faviconPrefix + base64.StdEncoding.EncodeToString(encodePNG(t, uniformImage(64, 64, color.NRGBA{A: 255}))[:60]),
+	} {
+		if err := ValidateFavicon(favicon); !errors.Is(err, ErrInvalidFavicon) {
+			t.Errorf("%s: got %v, want ErrInvalidFavicon", name, err)
+		}
+	}
+
+	var s StatusResponse
+	if err := s.SetFavicon(uniformImage(10, 10, color.NRGBA{A: 255})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Response{Status: &s}).Favicon(); err != nil {
+		t.Errorf("Response: %v", err)
+	}
+	if _, err := (&Response{JSONResponse: "{}"}).Favicon(); !errors.Is(err, ErrInvalidFavicon) {
+		t.Errorf("Response without status: got %v, want ErrInvalidFavicon", err)
+	}
+}
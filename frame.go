@@ -0,0 +1,161 @@
+package proto
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"net"
+)
+
+// Frame is a packet as it is sent over the network: after decryption but
+// before decompression and decoding.
+// Frames allow a proxy to forward packets it does not need to look at:
+// a frame read with a given compression threshold is re-emitted byte-for-byte
+// when it is packed with the same threshold, and is only inflated/deflated
+// when its contents are actually needed.
+// Encryption is applied to the whole stream, below framing, so the cipher state
+// of either side does not affect the frame contents.
+type Frame struct {
+	// Threshold is the compression threshold the frame is encoded with.
+	// A negative value means the frame is not compressed.
+	Threshold int
+	// Data is the frame without its leading Packet Length.
+	Data []byte
+
+	// Limits restricts the frame length accepted by Unpack.
+	// If nil, DefaultLimits are used.
+	Limits *Limits
+}
+
+// NewFrame creates a new Frame
+func NewFrame() *Frame {
+	return &Frame{Threshold: -1}
+}
+
+// Unpack reads the frame from the reader without decoding it
+func (f *Frame) Unpack(reader io.Reader, threshold int) error {
+	var length VarInt
+	_, err := length.ReadFrom(reader)
+	if err != nil {
+		return err
+	}
+
+	limits := f.Limits
+	if limits == nil {
+		limits = &DefaultLimits
+	}
+	err = checkFrameLength(length, limits)
+	if err != nil {
+		return err
+	}
+
+	if cap(f.Data) < int(length) {
+		f.Data = make([]byte, length)
+	} else {
+		f.Data = f.Data[:length]
+	}
+	f.Threshold = threshold
+
+	_, err = io.ReadFull(reader, f.Data)
+	return err
+}
+
+// Pack packs the frame to the writer.
+// The frame is written as is if it is encoded with the given threshold,
+// otherwise it is decoded and encoded again.
+func (f *Frame) Pack(writer io.Writer, threshold int) error {
+	buffers, err := f.Buffers(threshold)
+	if err != nil {
+		return err
+	}
+
+	_, err = buffers.WriteTo(writer)
+	return err
+}
+
+// Buffers returns the packed frame as a sequence of buffers that can be
+// written with a single vectored write (writev) by net.Buffers.WriteTo.
+// The returned buffers share memory with f.Data if the frame is encoded with
+// the given threshold.
+func (f *Frame) Buffers(threshold int) (net.Buffers, error) {
+	if !f.encodedWith(threshold) {
+		var p RawPacket
+		if err := f.ToRaw(&p); err != nil {
+			return nil, err
+		}
+		return p.Buffers(threshold)
+	}
+
+	header := appendVarInt(make([]byte, 0, MaxVarIntLen), int32(len(f.Data)))
+	return net.Buffers{header, f.Data}, nil
+}
+
+// encodedWith reports whether the frame is encoded with the given threshold.
+func (f *Frame) encodedWith(threshold int) bool {
+	if f.Threshold < 0 {
+		return threshold < 0
+	}
+	return f.Threshold == threshold
+}
+
+// ID returns the packet ID of the frame.
+// Only the first bytes of a compressed frame are inflated.
+func (f *Frame) ID() (int32, error) {
+	var reader io.Reader = bytes.NewReader(f.Data)
+
+	if f.Threshold >= 0 {
+		var dataLength VarInt
+		_, err := dataLength.ReadFrom(reader)
+		if err != nil {
+			return 0, err
+		}
+
+		if dataLength != 0 {
+			zlibReader, err := zlib.NewReader(reader)
+			if err != nil {
+				return 0, err
+			}
+			defer zlibReader.Close()
+
+			reader = zlibReader
+		}
+	}
+
+	var id VarInt
+	_, err := id.ReadFrom(reader)
+	return int32(id), err
+}
+
+// ToRaw decodes the frame to the given RawPacket, inflating it if needed.
+func (f *Frame) ToRaw(p *RawPacket) error {
+	if f.Threshold >= 0 {
+		return p.decodeCompressed(f.Data, f.Threshold)
+	}
+	return p.decodeUncompressed(f.Data)
+}
+
+// FromRaw encodes the given RawPacket to the frame with the given threshold,
+// deflating it if needed.
+func (f *Frame) FromRaw(p *RawPacket, threshold int) error {
+	buffers, err := p.Buffers(threshold)
+	if err != nil {
+		return err
+	}
+
+	// Strip the Packet Length from the header
+	header := bytes.NewReader(buffers[0])
+	var length VarInt
+	_, err = length.ReadFrom(header)
+	if err != nil {
+		return err
+	}
+
+	f.Data = append(f.Data[:0], buffers[0][len(buffers[0])-header.Len():]...)
+	// The body is not in a separate buffer if the packet is empty
+	for _, buffer := range buffers[1:] {
+		f.Data = append(f.Data, buffer...)
+	}
+	f.Threshold = threshold
+
+	return nil
+}
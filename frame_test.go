@@ -0,0 +1,131 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameEmptyPacket(t *testing.T) {
+	for _, threshold := range []int{-1, 0, 256} {
+		var f Frame
+		if err := f.FromRaw(&RawPacket{ID: 1}, threshold); err != nil {
+			t.Fatalf("threshold %d: FromRaw: %v", threshold, err)
+		}
+
+		var buf bytes.Buffer
+		if err := f.Pack(&buf, threshold); err != nil {
+			t.Fatalf("threshold %d: Pack: %v", threshold, err)
+		}
+		var p RawPacket
+		if err := p.Unpack(&buf, threshold); err != nil {
+			t.Fatalf("threshold %d: Unpack: %v", threshold, err)
+		}
+		if p.ID != 1 || len(p.Data) != 0 {
+			t.Errorf("threshold %d: got packet 0x%02X with % X", threshold, p.ID, p.Data)
+		}
+	}
+}
+
+func TestFrameThreshold(t *testing.T) {
+	p := RawPacket{ID: 0x10, Data: bytes.Repeat([]byte("data"), 25)}
+	tests := []struct {
+		threshold  int
+		compressed bool
+	}{
+		{-1, false},
+		{0, true},
+		{99, true},
+		{100, true},
+		{101, false},
+	}
+	for _, test := range tests {
+		var f Frame
+		if err := f.FromRaw(&p, test.threshold); err != nil {
+			t.Fatalf("threshold %d: FromRaw: %v", test.threshold, err)
+		}
+		compressed := test.threshold >= 0 && f.Data[0] != 0
+		if compressed != test.compressed {
+			t.Errorf("threshold %d: compressed %v, want %v", test.threshold, compressed, test.compressed)
+		}
+
+		// The frame is the packed packet without its length, and is packed as is
+		var packed bytes.Buffer
+		if err := p.Pack(&packed, test.threshold); err != nil {
+			t.Fatal(err)
+		}
+		buffers, err := f.Buffers(test.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buffers) != 2 || &buffers[1][0] != &f.Data[0] {
+			t.Errorf("threshold %d: the frame data is copied", test.threshold)
+		}
+		var buf bytes.Buffer
+		if err := f.Pack(&buf, test.threshold); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), packed.Bytes()) {
+			t.Errorf("threshold %d: got % X, want % X", test.threshold, buf.Bytes(), packed.Bytes())
+		}
+
+		var unpacked Frame
+		if err := unpacked.Unpack(&buf, test.threshold); err != nil {
+			t.Fatalf("threshold %d: Unpack: %v", test.threshold, err)
+		}
+		if !bytes.Equal(unpacked.Data, f.Data) || unpacked.Threshold != test.threshold {
+			t.Errorf("threshold %d: unpacked a different frame", test.threshold)
+		}
+		if id, err := unpacked.ID(); err != nil || id != p.ID {
+			t.Errorf("threshold %d: got ID 0x%02X %v, want 0x%02X", test.threshold, id, err, p.ID)
+		}
+		var raw RawPacket
+		if err := unpacked.ToRaw(&raw); err != nil {
+			t.Fatalf("threshold %d: ToRaw: %v", test.threshold, err)
+		}
+		if raw.ID != p.ID || !bytes.Equal(raw.Data, p.Data) {
+			t.Errorf("threshold %d: decoded a different packet", test.threshold)
+		}
+
+		// With another threshold, the frame is encoded again
+		for _, other := range []int{-1, 0, 256} {
+			if other == test.threshold {
+				continue
+			}
+			buf.Reset()
+			if err := f.Pack(&buf, other); err != nil {
+				t.Fatalf("threshold %d to %d: Pack: %v", test.threshold, other, err)
+			}
+			var raw RawPacket
+			if err := raw.Unpack(&buf, other); err != nil {
+				t.Fatalf("threshold %d to %d: Unpack: %v", test.threshold, other, err)
+			}
+			if raw.ID != p.ID || !bytes.Equal(raw.Data, p.Data) {
+				t.Errorf("threshold %d to %d: decoded a different packet", test.threshold, other)
+			}
+		}
+	}
+}
+
+func TestFrameEncodedWith(t *testing.T) {
+	tests := []struct {
+		frame, threshold int
+		want             bool
+	}{
+		{-1, -1, true},
+		// Any negative threshold disables compression
+		{-1, -2, true},
+		{-2, -1, true},
+		{-1, 0, false},
+		{0, -1, false},
+		{256, 256, true},
+		{256, 255, false},
+		{256, 257, false},
+		{0, 256, false},
+	}
+	for _, test := range tests {
+		f := Frame{Threshold: test.frame}
+		if got := f.encodedWith(test.threshold); got != test.want {
+			t.Errorf("frame threshold %d, threshold %d: got %v, want %v", test.frame, test.threshold, got, test.want)
+		}
+	}
+}
@@ -1,9 +1,5 @@
 package proto
 
-import (
-	"fmt"
-)
-
 // --- Handshake ---
 
 // Handshake is a packet that causes the server to switch into the target state.
@@ -19,6 +15,13 @@ type Handshake struct {
 // Handshake_ID is the Handshake packet ID.
 const Handshake_ID = 0x00
 
+// Handshake next states.
+const (
+	StateStatus   = 1
+	StateLogin    = 2
+	StateTransfer = 3
+)
+
 // ToRaw marshals the Handshake Packet to the given RawPacket.
 func (h *Handshake) ToRaw(p *RawPacket) (err error) {
 	p.ID = Handshake_ID
@@ -28,9 +31,9 @@ func (h *Handshake) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the Handshake Packet from the given RawPacket.
 func (h *Handshake) FromRaw(p *RawPacket) (err error) {
 	if p.ID != Handshake_ID {
-		return fmt.Errorf("invalid packet ID for Handshake: %d", p.ID)
+		return &PacketIDError{Packet: "Handshake", Expect: Handshake_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&h.ProtocolVersion, &h.ServerAddress, &h.ServerPort, &h.NextState)
+	return p.unmarshalPacket(h, &h.ProtocolVersion, &h.ServerAddress, &h.ServerPort, &h.NextState)
 }
 
 // --- LegacyServerPingList ---
@@ -39,6 +42,9 @@ func (h *Handshake) FromRaw(p *RawPacket) (err error) {
 // While not technically part of the current protocol, modern servers should handle it correctly.
 // Serverbound (C -> S)
 // Implements proto.Packet interface.
+//
+// Deprecated: legacy pings are not framed like other packets,
+// use LegacyPing and Conn.ReadLegacyPing instead.
 type LegacyServerListPing struct {
 	Payload UnsignedByte
 }
@@ -55,7 +61,7 @@ func (l *LegacyServerListPing) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the LegacyServerListPing Packet from the given RawPacket.
 func (l *LegacyServerListPing) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LegacyServerListPing_ID {
-		return fmt.Errorf("invalid packet ID for LegacyServerListPing: %d", p.ID)
+		return &PacketIDError{Packet: "LegacyServerListPing", Expect: LegacyServerListPing_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&l.Payload)
+	return p.unmarshalPacket(l, &l.Payload)
 }
@@ -0,0 +1,256 @@
+package proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LANAddress is the multicast address LAN worlds are announced to.
+const LANAddress = "224.0.2.60:4445"
+
+// defaultLANInterval is the interval between announcements of a LANBroadcaster, like the vanilla client.
+const defaultLANInterval = 1500 * time.Millisecond
+
+// defaultLANExpiry is the duration after which a LANListener forgets a silent server.
+const defaultLANExpiry = 5 * time.Second
+
+// lanMaxPacketSize is the size of the buffer of the vanilla client.
+const lanMaxPacketSize = 1024
+
+// ErrInvalidLANAnnouncement is returned when a LAN announcement is malformed.
+var ErrInvalidLANAnnouncement = errors.New("invalid LAN announcement")
+
+// AppendLANAnnouncement appends the announcement of a LAN world with the given
+// MOTD, in legacy format, and address, usually only a port.
+func AppendLANAnnouncement(b []byte, motd, address string) []byte {
+	b = append(b, "[MOTD]"...)
+	b = append(b, motd...)
+	b = append(b, "[/MOTD][AD]"...)
+	b = append(b, address...)
+	return append(b, "[/AD]"...)
+}
+
+// ParseLANAnnouncement parses the announcement of a LAN world.
+func ParseLANAnnouncement(b []byte) (motd, address string, err error) {
+	s := string(b)
+	motd, ok := between(s, "[MOTD]", "[/MOTD]")
+	if !ok {
+		return "", "", ErrInvalidLANAnnouncement
+	}
+	address, ok = between(s, "[AD]", "[/AD]")
+	if !ok {
+		return "", "", ErrInvalidLANAnnouncement
+	}
+	return motd, address, nil
+}
+
+// between returns the part of s between the start and end markers
+func between(s, start, end string) (string, bool) {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return "", false
+	}
+	s = s[i+len(start):]
+	j := strings.Index(s, end)
+	if j < 0 {
+		return "", false
+	}
+	return s[:j], true
+}
+
+// --- LANBroadcaster ---
+
+// LANBroadcaster announces a server as a LAN world, so that it shows up in
+// the server list of the clients on the local network.
+type LANBroadcaster struct {
+	// Port is the port of the announced server.
+	Port int
+	// Status returns the status of the server, whose description is announced.
+	Status func() (*StatusResponse, error)
+	// Interval is the interval between announcements. If zero, 1.5 seconds is used.
+	Interval time.Duration
+	// Address is the address the announcements are sent to. If empty, LANAddress is used.
+	Address string
+}
+
+// Run announces the server until the context is done or sending fails.
+func (lb *LANBroadcaster) Run(ctx context.Context) error {
+	address := lb.Address
+	if address == "" {
+		address = LANAddress
+	}
+	interval := lb.Interval
+	if interval <= 0 {
+		interval = defaultLANInterval
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp4", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := lb.announce(conn); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (lb *LANBroadcaster) announce(conn net.Conn) error {
+	status, err := lb.Status()
+	if err != nil {
+		return err
+	}
+	motd := status.Description.Legacy()
+	_, err = conn.Write(AppendLANAnnouncement(nil, motd, strconv.Itoa(lb.Port)))
+	return err
+}
+
+// --- LANListener ---
+
+// LANServer is a server discovered by a LANListener.
+type LANServer struct {
+	// MOTD is the announced MOTD, in legacy format.
+	MOTD string
+	// Address is the address to connect to, host and port.
+	Address  string
+	LastSeen time.Time
+}
+
+// LANListener collects the servers announced on the local network.
+type LANListener struct {
+	// Expiry is the duration after which a server that stopped announcing
+	// itself is forgotten. If zero, 5 seconds is used.
+	Expiry time.Duration
+	// OnDiscover, if set, is called when a server is announced for the first
+	// time, or again after it expired.
+	OnDiscover func(server LANServer)
+
+	mu      sync.Mutex
+	servers map[string]*LANServer
+}
+
+// ListenLAN joins the multicast group of LAN worlds and collects the
+// announced servers until the context is done or reading fails.
+func (l *LANListener) ListenLAN(ctx context.Context) error {
+	addr, err := net.ResolveUDPAddr("udp4", LANAddress)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return err
+	}
+	return l.Serve(ctx, conn)
+}
+
+// Serve collects the servers announced on the connection until the context
+// is done or reading fails, and closes the connection.
+func (l *LANListener) Serve(ctx context.Context, conn net.PacketConn) error {
+	defer conn.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	buf := make([]byte, lanMaxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		l.handle(buf[:n], addr, time.Now())
+	}
+}
+
+func (l *LANListener) handle(b []byte, addr net.Addr, now time.Time) {
+	motd, address, err := ParseLANAnnouncement(b)
+	if err != nil {
+		return
+	}
+
+	// Like the vanilla client, the server is reached at the IP of the sender,
+	// on the advertised port, even if a host is advertised too
+	port := address
+	if _, p, err := net.SplitHostPort(address); err == nil {
+		port = p
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return
+	}
+	ip, _, _ := proxyAddrIP(addr)
+	if ip == nil {
+		return
+	}
+	address = net.JoinHostPort(ip.String(), port)
+
+	l.mu.Lock()
+	if l.servers == nil {
+		l.servers = make(map[string]*LANServer)
+	}
+	l.expire(now)
+	server, known := l.servers[address]
+	if !known {
+		server = &LANServer{Address: address}
+		l.servers[address] = server
+	}
+	server.MOTD, server.LastSeen = motd, now
+	discovered := *server
+	l.mu.Unlock()
+
+	if !known && l.OnDiscover != nil {
+		l.OnDiscover(discovered)
+	}
+}
+
+// expire forgets the expired servers
+func (l *LANListener) expire(now time.Time) {
+	expiry := l.Expiry
+	if expiry <= 0 {
+		expiry = defaultLANExpiry
+	}
+	for address, server := range l.servers {
+		if now.Sub(server.LastSeen) > expiry {
+			delete(l.servers, address)
+		}
+	}
+}
+
+// Servers returns the servers announced recently, sorted by address.
+func (l *LANListener) Servers() []LANServer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.expire(time.Now())
+	servers := make([]LANServer, 0, len(l.servers))
+	for _, server := range l.servers {
+		servers = append(servers, *server)
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Address < servers[j].Address
+	})
+	return servers
+}
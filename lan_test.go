@@ -0,0 +1,108 @@
+package proto
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLANAnnouncement(t *testing.T) {
+	b := AppendLANAnnouncement([]byte("x"), "§aA world", "25565")
+	if want := "x[MOTD]§aA world[/MOTD][AD]25565[/AD]"; string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+	motd, address, err := ParseLANAnnouncement(b)
+	if err != nil || motd != "§aA world" || address != "25565" {
+		t.Errorf("got %q %q %v", motd, address, err)
+	}
+
+	// The markers are searched anywhere in the announcement
+	motd, address, err = ParseLANAnnouncement([]byte("[AD]1234[/AD] [MOTD][/MOTD]"))
+	if err != nil || motd != "" || address != "1234" {
+		t.Errorf("got %q %q %v", motd, address, err)
+	}
+
+	for _, s := range []string{"", "[MOTD]motd[/MOTD]", "[AD]25565[/AD]", "[MOTD]motd[AD]25565[/AD]", "[MOTD]motd[/MOTD][AD]25565"} {
+		if _, _, err := ParseLANAnnouncement([]byte(s)); !errors.Is(err, ErrInvalidLANAnnouncement) {
+			t.Errorf("%q: got %v, want ErrInvalidLANAnnouncement", s, err)
+		}
+	}
+}
+
+func TestLANListener(t *testing.T) {
+	var discovered []LANServer
+	l := &LANListener{OnDiscover: func(server LANServer) { discovered = append(discovered, server) }}
+	sender := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 4445}
+	now := time.Now()
+
+	tests := []struct {
+		announcement string
+		// want is the address of the server, empty if ignored
+		want string
+	}{
+		{"[MOTD]A world[/MOTD][AD]25565[/AD]", "192.168.0.2:25565"},
+		// Like the vanilla client, only the advertised port is used
+		{"[MOTD]A world[/MOTD][AD]10.0.0.1:25566[/AD]", "192.168.0.2:25566"},
+		{"[MOTD]A world[/MOTD][AD][::1]:25567[/AD]", "192.168.0.2:25567"},
+		{"[MOTD]A world[/MOTD][AD]example.com[/AD]", ""},
+		{"[MOTD]A world[/MOTD][AD]65536[/AD]", ""},
+		{"[MOTD]A world[/MOTD]", ""},
+	}
+	for _, test := range tests {
+		discovered = nil
+		l.handle([]byte(test.announcement), sender, now)
+		switch {
+		case test.want == "" && discovered != nil:
+			t.Errorf("%q: discovered %+v", test.announcement, discovered)
+		case test.want != "" && (len(discovered) != 1 || discovered[0].Address != test.want || discovered[0].MOTD != "A world"):
+			t.Errorf("%q: discovered %+v, want %s", test.announcement, discovered, test.want)
+		}
+	}
+
+	// Known servers are only updated
+	discovered = nil
+	l.handle([]byte("[MOTD]Another world[/MOTD][AD]25565[/AD]"), sender, now.Add(time.Second))
+	if discovered != nil {
+		t.Errorf("discovered %+v again", discovered)
+	}
+	servers := l.Servers()
+	if len(servers) != 3 || servers[0].Address != "192.168.0.2:25565" || servers[0].MOTD != "Another world" {
+		t.Errorf("got servers %+v", servers)
+	}
+
+	// Silent servers expire, and are discovered again
+	l.handle([]byte("[MOTD]A world[/MOTD][AD]25566[/AD]"), sender, now.Add(defaultLANExpiry+2*time.Second))
+	if len(discovered) != 1 || discovered[0].Address != "192.168.0.2:25566" {
+		t.Errorf("discovered %+v", discovered)
+	}
+	l.mu.Lock()
+	n := len(l.servers)
+	l.mu.Unlock()
+	if n != 1 {
+		t.Errorf("got %d servers, want 1", n)
+	}
+}
+
+func TestLANBroadcaster(t *testing.T) {
+	lb := &LANBroadcaster{Port: 25565, Status: func() (*StatusResponse, error) {
+		return &StatusResponse{Description: TextComponent{Text: "A world", Color: "green"}}, nil
+	}}
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		if err := lb.announce(client); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	buf := make([]byte, lanMaxPacketSize)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[MOTD]§aA world[/MOTD][AD]25565[/AD]"; string(buf[:n]) != want {
+		t.Errorf("got %q, want %q", buf[:n], want)
+	}
+}
@@ -0,0 +1,300 @@
+package proto
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Legacy (pre-Netty) server list pings are not framed like other packets:
+// they are raw bytes starting with 0xFE, sent by clients older than 1.7 as the
+// first bytes of a connection, and answered with a raw 0xFF kick packet.
+
+// LegacyPingVariant is the variant of a legacy server list ping.
+type LegacyPingVariant int
+
+const (
+	// LegacyPingBeta is a ping sent by Beta 1.8 to 1.3 clients: 0xFE.
+	LegacyPingBeta LegacyPingVariant = iota
+	// LegacyPing14 is a ping sent by 1.4 to 1.5 clients: 0xFE 0x01.
+	LegacyPing14
+	// LegacyPing16 is a ping sent by 1.6 clients: 0xFE 0x01 0xFA "MC|PingHost"...
+	LegacyPing16
+)
+
+const (
+	legacyPingID        = 0xFE
+	legacyPingPayload   = 0x01
+	legacyPluginID      = 0xFA
+	legacyKickID        = 0xFF
+	legacyPingChannel   = "MC|PingHost"
+	legacyStatusPrefix  = "§1\x00"
+	legacyBetaSeparator = "§"
+)
+
+// ErrNotLegacyPing is returned when a connection does not start with a legacy ping.
+var ErrNotLegacyPing = errors.New("not a legacy server list ping")
+
+// --- LegacyPing ---
+
+// LegacyPing is a legacy server list ping.
+// Serverbound (C -> S)
+type LegacyPing struct {
+	Variant LegacyPingVariant
+	// ProtocolVersion, Hostname and Port are only sent by 1.6 clients.
+	ProtocolVersion byte
+	Hostname        string
+	Port            int
+}
+
+// IsLegacyPing reports whether the next byte of the reader starts a legacy ping.
+// It should only be called on a fresh connection, before the Handshake.
+func IsLegacyPing(r *bufio.Reader) (bool, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	return b[0] == legacyPingID, nil
+}
+
+// ReadLegacyPing reads a legacy ping from the reader.
+// As Beta clients only send 0xFE, the variant is detected from the bytes
+// already received along with it.
+func ReadLegacyPing(r *bufio.Reader) (*LegacyPing, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if id != legacyPingID {
+		return nil, ErrNotLegacyPing
+	}
+
+	p := &LegacyPing{Variant: LegacyPingBeta}
+	if r.Buffered() == 0 {
+		return p, nil
+	}
+
+	payload, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if payload != legacyPingPayload {
+		return nil, fmt.Errorf("legacy ping: unexpected payload %#02x", payload)
+	}
+
+	p.Variant = LegacyPing14
+	if r.Buffered() == 0 {
+		return p, nil
+	}
+
+	plugin, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if plugin != legacyPluginID {
+		return nil, fmt.Errorf("legacy ping: unexpected packet %#02x", plugin)
+	}
+
+	channel, err := readLegacyString(r)
+	if err != nil {
+		return nil, err
+	}
+	if channel != legacyPingChannel {
+		return nil, fmt.Errorf("legacy ping: unexpected channel %q", channel)
+	}
+
+	var length Short
+	var protocol UnsignedByte
+	var port Int
+	if _, err := length.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	if _, err := protocol.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	hostname, err := readLegacyString(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := port.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	if int(length) != 7+2*len(utf16.Encode([]rune(hostname))) {
+		return nil, fmt.Errorf("legacy ping: invalid data length %d", length)
+	}
+
+	p.Variant = LegacyPing16
+	p.ProtocolVersion = byte(protocol)
+	p.Hostname = hostname
+	p.Port = int(port)
+
+	return p, nil
+}
+
+// WriteTo writes the legacy ping to w.
+func (p *LegacyPing) WriteTo(w io.Writer) (int64, error) {
+	b := []byte{legacyPingID}
+	if p.Variant >= LegacyPing14 {
+		b = append(b, legacyPingPayload)
+	}
+	if p.Variant >= LegacyPing16 {
+		b = append(b, legacyPluginID)
+		b = appendLegacyString(b, legacyPingChannel)
+		hostname := utf16.Encode([]rune(p.Hostname))
+		length := 7 + 2*len(hostname)
+		b = append(b, byte(length>>8), byte(length), p.ProtocolVersion)
+		b = appendLegacyString(b, p.Hostname)
+		b = append(b, byte(p.Port>>24), byte(p.Port>>16), byte(p.Port>>8), byte(p.Port))
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// --- LegacyStatus ---
+
+// LegacyStatus is the answer to a legacy ping, sent as a kick packet.
+// Clientbound (S -> C)
+type LegacyStatus struct {
+	// ProtocolVersion and Version are not sent to Beta clients.
+	ProtocolVersion int
+	Version         string
+	MOTD            string
+	Online          int
+	Max             int
+}
+
+// Pack writes the status to w, in the format expected by the given ping variant.
+func (s *LegacyStatus) Pack(w io.Writer, variant LegacyPingVariant) error {
+	var reason string
+	if variant == LegacyPingBeta {
+		reason = strings.Join([]string{
+			s.MOTD,
+			strconv.Itoa(s.Online),
+			strconv.Itoa(s.Max),
+		}, legacyBetaSeparator)
+	} else {
+		reason = legacyStatusPrefix + strings.Join([]string{
+			strconv.Itoa(s.ProtocolVersion),
+			s.Version,
+			s.MOTD,
+			strconv.Itoa(s.Online),
+			strconv.Itoa(s.Max),
+		}, "\x00")
+	}
+
+	_, err := w.Write(appendLegacyString([]byte{legacyKickID}, reason))
+	return err
+}
+
+// Unpack reads the status from r, in any of the legacy formats.
+func (s *LegacyStatus) Unpack(r io.Reader) error {
+	id, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	if id != legacyKickID {
+		return fmt.Errorf("legacy status: unexpected packet %#02x", id)
+	}
+
+	reason, err := readLegacyString(r)
+	if err != nil {
+		return err
+	}
+
+	var online, max string
+	if strings.HasPrefix(reason, legacyStatusPrefix) {
+		fields := strings.Split(reason[len(legacyStatusPrefix):], "\x00")
+		if len(fields) != 5 {
+			return fmt.Errorf("legacy status: invalid status %q", reason)
+		}
+		s.ProtocolVersion, err = strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("legacy status: invalid protocol version: %w", err)
+		}
+		s.Version, s.MOTD, online, max = fields[1], fields[2], fields[3], fields[4]
+	} else {
+		fields := strings.Split(reason, legacyBetaSeparator)
+		if len(fields) < 3 {
+			return fmt.Errorf("legacy status: invalid status %q", reason)
+		}
+		n := len(fields)
+		s.ProtocolVersion, s.Version = 0, ""
+		s.MOTD = strings.Join(fields[:n-2], legacyBetaSeparator)
+		online, max = fields[n-2], fields[n-1]
+	}
+
+	if s.Online, err = strconv.Atoi(online); err != nil {
+		return fmt.Errorf("legacy status: invalid online players: %w", err)
+	}
+	if s.Max, err = strconv.Atoi(max); err != nil {
+		return fmt.Errorf("legacy status: invalid max players: %w", err)
+	}
+	return nil
+}
+
+// readLegacyString reads a string prefixed with its length in UTF-16 code units, encoded in UTF-16BE
+func readLegacyString(r io.Reader) (string, error) {
+	var length Short
+	if _, err := length.ReadFrom(r); err != nil {
+		return "", err
+	}
+	if length < 0 {
+		return "", &LimitError{Limit: "MaxStringLength", Value: int64(length)}
+	}
+
+	b := make([]byte, 2*int(length))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// appendLegacyString appends s prefixed with its length in UTF-16 code units, encoded in UTF-16BE
+func appendLegacyString(b []byte, s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b = append(b, byte(len(units)>>8), byte(len(units)))
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+// IsLegacyPing reports whether the connection starts with a legacy ping.
+// It should only be called on a fresh connection, before the Handshake.
+func (c *Conn) IsLegacyPing() (bool, error) {
+	return IsLegacyPing(c.reader)
+}
+
+// ReadLegacyPing reads a legacy ping from the connection.
+func (c *Conn) ReadLegacyPing() (*LegacyPing, error) {
+	return ReadLegacyPing(c.reader)
+}
+
+// WriteLegacyStatus answers a legacy ping of the given variant with the status.
+// The connection should be closed afterwards.
+func (c *Conn) WriteLegacyStatus(status *LegacyStatus, variant LegacyPingVariant) error {
+	return status.Pack(c.writer, variant)
+}
+
+// RequestLegacyStatus sends the legacy ping and reads the status answered by the server.
+func (c *Conn) RequestLegacyStatus(ping *LegacyPing) (*LegacyStatus, error) {
+	if _, err := ping.WriteTo(c.writer); err != nil {
+		return nil, err
+	}
+
+	status := new(LegacyStatus)
+	if err := status.Unpack(c.reader); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
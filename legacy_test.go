@@ -0,0 +1,175 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+)
+
+// utf16BE returns s encoded in UTF-16BE, without length
+func utf16BE(s string) []byte {
+	return appendLegacyString(nil, s)[2:]
+}
+
+func TestLegacyPing(t *testing.T) {
+	ping16 := append([]byte{0xFE, 0x01, 0xFA, 0x00, 0x0B}, utf16BE("MC|PingHost")...)
+	ping16 = append(ping16, 0x00, 0x19, 74, 0x00, 0x09)
+	ping16 = append(ping16, utf16BE("localhost")...)
+	ping16 = append(ping16, 0x00, 0x00, 0x63, 0xDD)
+
+	tests := []struct {
+		ping LegacyPing
+		data []byte
+	}{
+		{LegacyPing{Variant: LegacyPingBeta}, []byte{0xFE}},
+		{LegacyPing{Variant: LegacyPing14}, []byte{0xFE, 0x01}},
+		{LegacyPing{Variant: LegacyPing16, ProtocolVersion: 74, Hostname: "localhost", Port: 25565}, ping16},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if _, err := test.ping.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), test.data) {
+			t.Errorf("variant %d: got % X, want % X", test.ping.Variant, buf.Bytes(), test.data)
+		}
+
+		r := bufio.NewReader(bytes.NewReader(test.data))
+		if ok, err := IsLegacyPing(r); !ok || err != nil {
+			t.Errorf("variant %d: IsLegacyPing: got %v %v", test.ping.Variant, ok, err)
+		}
+		got, err := ReadLegacyPing(r)
+		if err != nil {
+			t.Errorf("variant %d: %v", test.ping.Variant, err)
+		} else if *got != test.ping {
+			t.Errorf("variant %d: got %+v, want %+v", test.ping.Variant, *got, test.ping)
+		}
+	}
+
+	// A hostname outside of the BMP is counted in UTF-16 code units
+	ping := LegacyPing{Variant: LegacyPing16, ProtocolVersion: 78, Hostname: "\U0001F600.example", Port: 25566}
+	var buf bytes.Buffer
+	ping.WriteTo(&buf)
+	if got, err := ReadLegacyPing(bufio.NewReader(&buf)); err != nil || *got != ping {
+		t.Errorf("got %+v %v, want %+v", got, err, ping)
+	}
+
+	r := bufio.NewReader(bytes.NewReader([]byte{0x10, 0x00}))
+	if ok, err := IsLegacyPing(r); ok || err != nil {
+		t.Errorf("Handshake: IsLegacyPing: got %v %v", ok, err)
+	}
+	if _, err := ReadLegacyPing(r); !errors.Is(err, ErrNotLegacyPing) {
+		t.Errorf("Handshake: got %v, want ErrNotLegacyPing", err)
+	}
+}
+
+func TestLegacyPingMalformed(t *testing.T) {
+	ping := LegacyPing{Variant: LegacyPing16, ProtocolVersion: 74, Hostname: "localhost", Port: 25565}
+	var buf bytes.Buffer
+	ping.WriteTo(&buf)
+	valid := buf.Bytes()
+
+	modify := func(i int, b byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[i] = b
+		return data
+	}
+	tests := map[string][]byte{
+		"payload":   {0xFE, 0x02},
+		"packet":    {0xFE, 0x01, 0xFB},
+		"channel":   modify(6, 'X'),
+		"length":    modify(28, 0x18),
+		"truncated": valid[:len(valid)-1],
+	}
+	for name, data := range tests {
+		if _, err := ReadLegacyPing(bufio.NewReader(bytes.NewReader(data))); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestLegacyStatus(t *testing.T) {
+	status := LegacyStatus{ProtocolVersion: 78, Version: "1.6.4", MOTD: "A §aMinecraft§r Server", Online: 3, Max: 20}
+	tests := []struct {
+		variant LegacyPingVariant
+		reason  string
+		want    LegacyStatus
+	}{
+		// Beta clients only get the MOTD and the players, separated by §
+		{LegacyPingBeta, "A §aMinecraft§r Server§3§20", LegacyStatus{MOTD: status.MOTD, Online: 3, Max: 20}},
+		{LegacyPing14, "§1\x0078\x001.6.4\x00A §aMinecraft§r Server\x003\x0020", status},
+		{LegacyPing16, "§1\x0078\x001.6.4\x00A §aMinecraft§r Server\x003\x0020", status},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := status.Pack(&buf, test.variant); err != nil {
+			t.Fatal(err)
+		}
+		if want := appendLegacyString([]byte{0xFF}, test.reason); !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("variant %d: got % X, want % X", test.variant, buf.Bytes(), want)
+		}
+
+		got := LegacyStatus{ProtocolVersion: 1, Version: "stale"}
+		if err := got.Unpack(&buf); err != nil {
+			t.Errorf("variant %d: %v", test.variant, err)
+		} else if got != test.want {
+			t.Errorf("variant %d: got %+v, want %+v", test.variant, got, test.want)
+		}
+	}
+
+	for _, reason := range []string{
+		"motd§3",
+		"motd§three§20",
+		"motd§3§",
+		"§1\x0078\x001.6.4\x00motd\x003",
+		"§1\x00proto\x001.6.4\x00motd\x003\x0020",
+		"§1\x0078\x001.6.4\x00motd\x003\x00max",
+	} {
+		var got LegacyStatus
+		if err := got.Unpack(bytes.NewReader(appendLegacyString([]byte{0xFF}, reason))); err == nil {
+			t.Errorf("%q: no error", reason)
+		}
+	}
+	var got LegacyStatus
+	if err := got.Unpack(bytes.NewReader(appendLegacyString([]byte{0xFE}, "motd§3§20"))); err == nil {
+		t.Error("ping packet: no error")
+	}
+	if err := got.Unpack(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF})); err == nil {
+		t.Error("negative length: no error")
+	}
+}
+
+func TestLegacyExchange(t *testing.T) {
+	status := &LegacyStatus{ProtocolVersion: 78, Version: "1.6.4", MOTD: "motd", Online: 1, Max: 10}
+	for _, variant := range []LegacyPingVariant{LegacyPingBeta, LegacyPing14, LegacyPing16} {
+		client, server := net.Pipe()
+		go func(variant LegacyPingVariant) {
+			defer server.Close()
+			conn := NewConn(server)
+			if ok, err := conn.IsLegacyPing(); !ok || err != nil {
+				t.Errorf("variant %d: IsLegacyPing: got %v %v", variant, ok, err)
+				return
+			}
+			ping, err := conn.ReadLegacyPing()
+			if err != nil {
+				t.Errorf("variant %d: %v", variant, err)
+				return
+			}
+			if ping.Variant != variant {
+				t.Errorf("got variant %d, want %d", ping.Variant, variant)
+			}
+			conn.WriteLegacyStatus(status, ping.Variant)
+		}(variant)
+
+		got, err := NewConn(client).RequestLegacyStatus(&LegacyPing{Variant: variant, ProtocolVersion: 78, Hostname: "localhost", Port: 25565})
+		client.Close()
+		if err != nil {
+			t.Fatalf("variant %d: %v", variant, err)
+		}
+		if got.MOTD != "motd" || got.Online != 1 || got.Max != 10 || (variant != LegacyPingBeta && got.Version != "1.6.4") {
+			t.Errorf("variant %d: got %+v", variant, got)
+		}
+	}
+}
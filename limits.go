@@ -0,0 +1,93 @@
+package proto
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// Limits restricts the sizes accepted while decoding packets, so that
+// malformed or malicious packets cannot crash the decoder or make it
+// allocate huge amounts of memory.
+// Exceeding a limit results in a *LimitError.
+type Limits struct {
+	// MaxFrameLength is the maximum Packet Length of a frame.
+	MaxFrameLength int
+	// MaxDataLength is the maximum uncompressed length of a compressed packet.
+	MaxDataLength int
+	// MaxStringLength is the maximum length of a String, in UTF-16 code units.
+	MaxStringLength int
+	// MaxByteArrayLength is the maximum length of a ByteArray.
+	MaxByteArrayLength int
+	// MaxArrayLength is the maximum number of elements of a length-prefixed array.
+	MaxArrayLength int
+	// MaxNBTDepth is the maximum nesting depth of NBT compounds and lists.
+	MaxNBTDepth int
+	// MaxNBTSize is the maximum size of an NBT tag, in bytes.
+	MaxNBTSize int
+}
+
+// DefaultLimits are the limits used when none are given.
+// They match the limits enforced by the vanilla implementation.
+var DefaultLimits = Limits{
+	MaxFrameLength:     2097151,
+	MaxDataLength:      8388608,
+	MaxStringLength:    32767,
+	MaxByteArrayLength: 2097151,
+	MaxArrayLength:     1 << 20,
+	MaxNBTDepth:        512,
+	MaxNBTSize:         2097152,
+}
+
+// limitsOf returns the Limits carried by the reader, or DefaultLimits.
+func limitsOf(r io.Reader) *Limits {
+	if l, ok := r.(interface{ Limits() *Limits }); ok {
+		return l.Limits()
+	}
+	return &DefaultLimits
+}
+
+// checkLength checks a decoded length against the given maximum.
+// If the reader knows how many bytes are left (like bytes.Reader does),
+// lengths that cannot be satisfied are rejected before anything is allocated.
+func checkLength(r io.Reader, limit string, length, max, elemSize int) error {
+	if length < 0 || length > max {
+		return &LimitError{Limit: limit, Value: int64(length), Max: int64(max)}
+	}
+	if l, ok := r.(interface{ Len() int }); ok && length*elemSize > l.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// checkArrayLength checks the length of a length-prefixed array whose
+// elements are encoded in at least elemSize bytes.
+func checkArrayLength(r io.Reader, length VarInt, elemSize int) error {
+	return checkLength(r, "MaxArrayLength", int(length), limitsOf(r).MaxArrayLength, elemSize)
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s []byte) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRune(s)
+		s = s[size:]
+		if r > 0xFFFF {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// limitedReader is a bytes.Reader that carries the Limits to apply to the types read from it.
+type limitedReader struct {
+	*bytes.Reader
+	limits *Limits
+}
+
+// Limits returns the Limits to apply.
+func (r limitedReader) Limits() *Limits {
+	return r.limits
+}
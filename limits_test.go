@@ -0,0 +1,139 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// frame returns the given VarInts followed by data
+func frame(data []byte, varints ...VarInt) []byte {
+	var buf bytes.Buffer
+	for _, v := range varints {
+		v.WriteTo(&buf)
+	}
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// checkLimitError checks that err is a *LimitError on the given limit
+func checkLimitError(t *testing.T, name string, err error, limit string) {
+	t.Helper()
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != limit {
+		t.Errorf("%s: got %v, want %s exceeded", name, err, limit)
+	}
+}
+
+func TestFrameLimits(t *testing.T) {
+	max := VarInt(DefaultLimits.MaxFrameLength)
+	tests := []struct {
+		name      string
+		data      []byte
+		threshold int
+		limit     string
+	}{
+		{"negative length", frame(nil, -1), -1, "MaxFrameLength"},
+		{"huge length", frame(nil, max+1), -1, "MaxFrameLength"},
+		{"negative compressed length", frame(nil, -1), 256, "MaxFrameLength"},
+		{"huge compressed length", frame(nil, max+1), 256, "MaxFrameLength"},
+		{"huge data length", frame([]byte{0x78, 0x9C}, 6, VarInt(DefaultLimits.MaxDataLength+1)), 256, "MaxDataLength"},
+	}
+	for _, test := range tests {
+		var p RawPacket
+		checkLimitError(t, test.name, p.Unpack(bytes.NewReader(test.data), test.threshold), test.limit)
+
+		var r PacketReader
+		checkLimitError(t, test.name+" (stream)", r.Unpack(bytes.NewReader(test.data), test.threshold), test.limit)
+	}
+
+	// The frame length may be lowered
+	limits := DefaultLimits
+	limits.MaxFrameLength = 4
+	p := RawPacket{Limits: &limits}
+	if err := p.Unpack(bytes.NewReader(frame([]byte{1, 2, 3}, 4, 0)), -1); err != nil {
+		t.Errorf("frame of 4 bytes: %v", err)
+	}
+	checkLimitError(t, "frame of 5 bytes", p.Unpack(bytes.NewReader(frame([]byte{1, 2, 3, 4}, 5, 0)), -1), "MaxFrameLength")
+}
+
+func TestTypeLimits(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxStringLength = 2
+	limits.MaxByteArrayLength = 4
+	limits.MaxArrayLength = 1
+
+	tests := []struct {
+		name  string
+		typ   Type
+		data  []byte
+		limit string
+	}{
+		{"string of 3 characters", new(String), frame([]byte("abc"), 3), "MaxStringLength"},
+		{"string of 3 UTF-16 units", new(String), frame([]byte("a😀"), 5), "MaxStringLength"},
+		{"string of 7 bytes", new(String), frame([]byte("ééé"), 7), "MaxStringLength"},
+		{"string of negative length", new(String), frame(nil, -1), "MaxStringLength"},
+		{"byte array of 5 bytes", new(ByteArray), frame(make([]byte, 5), 5), "MaxByteArrayLength"},
+		{"byte array of negative length", new(ByteArray), frame(nil, -1), "MaxByteArrayLength"},
+		{"array of 2 identifiers", new(Identifiers), frame([]byte{0, 0}, 2), "MaxArrayLength"},
+		{"array of 2 properties", new(ProfileProperties), frame(make([]byte, 6), 2), "MaxArrayLength"},
+	}
+	for _, test := range tests {
+		p := RawPacket{Data: test.data, Limits: &limits}
+		checkLimitError(t, test.name, p.Unmarshal(test.typ), test.limit)
+
+		var r PacketReader
+		r.Limits = &limits
+		if err := r.Unpack(bytes.NewReader(frame(test.data, VarInt(len(test.data)+1), 0)), -1); err != nil {
+			t.Fatal(err)
+		}
+		checkLimitError(t, test.name+" (stream)", r.Decode(test.typ), test.limit)
+	}
+
+	// The values at the limits are accepted
+	for _, test := range []struct {
+		name string
+		typ  Type
+		data []byte
+	}{
+		{"string of 2 UTF-16 units", new(String), frame([]byte("😀"), 4)},
+		{"string of 2 characters", new(String), frame([]byte("éé"), 4)},
+		{"byte array of 4 bytes", new(ByteArray), frame(make([]byte, 4), 4)},
+		{"array of 1 identifier", new(Identifiers), frame([]byte{0}, 1)},
+	} {
+		p := RawPacket{Data: test.data, Limits: &limits}
+		if err := p.Unmarshal(test.typ); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestLengthBeyondData(t *testing.T) {
+	// Lengths within the limits but beyond the data are truncated, without
+	// allocating what they declare
+	for _, test := range []struct {
+		name string
+		typ  Type
+		data []byte
+	}{
+		{"string", new(String), frame([]byte("a"), 30000)},
+		{"byte array", new(ByteArray), frame([]byte{1}, 2000000)},
+		{"array", new(Identifiers), frame(nil, 1000000)},
+	} {
+		p := RawPacket{Data: test.data}
+		if err := p.Unmarshal(test.typ); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%s: got %v, want ErrTruncated", test.name, err)
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < 10; i++ {
+			p.Unmarshal(test.typ)
+		}
+		runtime.ReadMemStats(&after)
+		if allocated := (after.TotalAlloc - before.TotalAlloc) / 10; allocated > 64<<10 {
+			t.Errorf("%s: %d bytes allocated", test.name, allocated)
+		}
+	}
+}
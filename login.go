@@ -1,6 +1,9 @@
 package proto
 
-import "fmt"
+import (
+	"io"
+	"strings"
+)
 
 // --- LoginDisconnect ---
 
@@ -23,22 +26,25 @@ func (pi *LoginDisconnect) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the LoginDisconnect Packet from the given RawPacket.
 func (pi *LoginDisconnect) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LoginDisconnect_ID {
-		return fmt.Errorf("invalid packet ID for LoginDisconnect: %d", p.ID)
+		return &PacketIDError{Packet: "LoginDisconnect", Expect: LoginDisconnect_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.Reason)
+	return p.unmarshalPacket(pi, &pi.Reason)
 }
 
 // --- EncryptionRequest ---
 
 // EncryptionRequest is a packet that initiate encryption process.
+// Its layout depends on RawPacket.Protocol: ShouldAuthenticate is only sent from 1.20.5.
 // Clientbound (S -> C)
 // Implements proto.Packet interface.
 type EncryptionRequest struct {
-	ServerID          String
-	PublicKeyLength   VarInt
-	PublicKey         ByteArray
-	VerifyTokenLength VarInt
-	VerifyToken       ByteArray
+	ServerID String
+	// PublicKey is the DER-encoded public key of the server.
+	PublicKey   ByteArray
+	VerifyToken ByteArray
+	// ShouldAuthenticate tells the client to join the session server.
+	// Before 1.20.5, clients always do.
+	ShouldAuthenticate Boolean
 }
 
 // EncrpytionRequest_ID is the EncryptionRequest packet ID.
@@ -47,25 +53,41 @@ const EncryptionRequest_ID = 0x01
 // ToRaw marshals the EncryptionRequest Packet to the given RawPacket.
 func (pi *EncryptionRequest) ToRaw(p *RawPacket) (err error) {
 	p.ID = EncryptionRequest_ID
-	return p.Marshal(&pi.ServerID, &pi.PublicKeyLength, &pi.PublicKey, &pi.VerifyTokenLength, &pi.VerifyToken)
+	return p.Marshal(pi.types(p.Protocol)...)
 }
 
 // FromRaw unmarshals the EncryptionRequest Packet from the given RawPacket.
 func (pi *EncryptionRequest) FromRaw(p *RawPacket) (err error) {
 	if p.ID != EncryptionRequest_ID {
-		return fmt.Errorf("invalid packet ID for EncryptionRequest: %d", p.ID)
+		return &PacketIDError{Packet: "EncryptionRequest", Expect: EncryptionRequest_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.ServerID, &pi.PublicKeyLength, &pi.PublicKey, &pi.VerifyTokenLength, &pi.VerifyToken)
+	pi.ShouldAuthenticate = false
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *EncryptionRequest) types(protocol int32) []Type {
+	types := []Type{&pi.ServerID, &pi.PublicKey, &pi.VerifyToken}
+	if protocol >= Protocol1_20_5 {
+		types = append(types, &pi.ShouldAuthenticate)
+	}
+	return types
 }
 
 // --- LoginSuccess ---
 
 // LoginSuccess is a packet that tells the client they have successfully logged in.
+// Its layout depends on RawPacket.Protocol: the UUID is sent as a string
+// before 1.16, Properties from 1.19, and StrictErrorHandling from 1.20.5 to 1.21.1.
+// If the protocol is zero, the layout of 1.16 to 1.18.2 is used.
 // Clientbound (S -> C)
 // Implements proto.Packet interface.
 type LoginSuccess struct {
-	UUID     UUID
-	Username String
+	UUID       UUID
+	Username   String
+	Properties ProfileProperties
+	// StrictErrorHandling makes the client disconnect on packets it fails to decode.
+	StrictErrorHandling Boolean
 }
 
 // LoginSuccess_ID is the LoginSuccess packet ID.
@@ -74,16 +96,34 @@ const LoginSuccess_ID = 0x02
 // ToRaw marshals the LoginSuccess Packet to the given RawPacket.
 func (pi *LoginSuccess) ToRaw(p *RawPacket) (err error) {
 	p.ID = LoginSuccess_ID
-	return p.Marshal(&pi.UUID, &pi.Username)
+	return p.Marshal(pi.types(p.Protocol)...)
 }
 
 // FromRaw unmarshals the LoginSuccess Packet from the given RawPacket.
 func (pi *LoginSuccess) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LoginSuccess_ID {
-		return fmt.Errorf("invalid packet ID for LoginSuccess: %d", p.ID)
+		return &PacketIDError{Packet: "LoginSuccess", Expect: LoginSuccess_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.UUID, &pi.Username)
+	pi.Properties, pi.StrictErrorHandling = nil, false
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
 
+// types returns the fields of the packet in the given protocol version
+func (pi *LoginSuccess) types(protocol int32) []Type {
+	var types []Type
+	switch {
+	case protocol == 0 || protocol >= Protocol1_16:
+		types = []Type{&pi.UUID, &pi.Username}
+	default:
+		types = []Type{stringUUID{&pi.UUID, protocol >= Protocol1_7_6}, &pi.Username}
+	}
+	if protocol >= Protocol1_19 {
+		types = append(types, &pi.Properties)
+	}
+	if protocol >= Protocol1_20_5 && protocol < Protocol1_21_2 {
+		types = append(types, &pi.StrictErrorHandling)
+	}
+	return types
 }
 
 // --- SetCompression ---
@@ -107,9 +147,9 @@ func (pi *SetCompression) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the SetCompression Packet from the given RawPacket.
 func (pi *SetCompression) FromRaw(p *RawPacket) (err error) {
 	if p.ID != SetCompression_ID {
-		return fmt.Errorf("invalid packet ID for SetCompression: %d", p.ID)
+		return &PacketIDError{Packet: "SetCompression", Expect: SetCompression_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.Threshold)
+	return p.unmarshalPacket(pi, &pi.Threshold)
 }
 
 // --- LoginPluginRequest ---
@@ -120,7 +160,7 @@ func (pi *SetCompression) FromRaw(p *RawPacket) (err error) {
 type LoginPluginRequest struct {
 	MessageID VarInt
 	Channel   Identifier
-	Data      ByteArray
+	Data      RemainingBytes
 }
 
 // LoginPluginResponse_ID is the LoginPluginResponse packet ID.
@@ -135,18 +175,25 @@ func (pi *LoginPluginRequest) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the LoginPluginRequest Packet from the given RawPacket.
 func (pi *LoginPluginRequest) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LoginPluginRequest_ID {
-		return fmt.Errorf("invalid packet ID for LoginPluginRequest: %d", p.ID)
+		return &PacketIDError{Packet: "LoginPluginRequest", Expect: LoginPluginRequest_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.MessageID, &pi.Channel, &pi.Data)
+	return p.unmarshalPacket(pi, &pi.MessageID, &pi.Channel, &pi.Data)
 }
 
 // --- LoginStart ---
 
 // LoginStart is a packet sent by client to initiate the login process.
+// Its layout depends on RawPacket.Protocol: Key is only sent from 1.19 to 1.19.2,
+// PlayerUUID is optional from 1.19.1 and mandatory from 1.20.2.
+// If the protocol is zero, only Name is sent.
 // Serverbound (C -> S)
 // Implements proto.Packet interface.
 type LoginStart struct {
 	Name String
+	// Key is the chat signing key of the player, if any.
+	Key *PlayerKey
+	// PlayerUUID is the UUID of the player, if sent.
+	PlayerUUID *UUID
 }
 
 // LoginStart_ID is the LoginStart packet ID.
@@ -155,27 +202,46 @@ const LoginStart_ID = 0x00
 // ToRaw marshals the LoginStart Packet to the given RawPacket.
 func (pi *LoginStart) ToRaw(p *RawPacket) (err error) {
 	p.ID = LoginStart_ID
-	return p.Marshal(&pi.Name)
+	return p.Marshal(pi.types(p.Protocol)...)
 }
 
 // FromRaw unmarshals the LoginStart Packet from the given RawPacket.
 func (pi *LoginStart) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LoginStart_ID {
-		return fmt.Errorf("invalid packet ID for LoginStart: %d", p.ID)
+		return &PacketIDError{Packet: "LoginStart", Expect: LoginStart_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.Name)
+	pi.Key, pi.PlayerUUID = nil, nil
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *LoginStart) types(protocol int32) []Type {
+	types := []Type{&pi.Name}
+	if protocol >= Protocol1_19 && protocol < Protocol1_19_3 {
+		types = append(types, optionalPlayerKey{&pi.Key})
+	}
+	if protocol >= Protocol1_19_1 {
+		types = append(types, optionalUUID{&pi.PlayerUUID, protocol < Protocol1_20_2})
+	}
+	return types
 }
 
 // --- EncryptionResponse ---
 
 // EncryptionResponse is a packet sent by server to confirm the encryption process.
+// Its layout depends on RawPacket.Protocol: from 1.19 to 1.19.2, the clients
+// with a player key may send Salt and Signature instead of VerifyToken.
 // Serverbound (C -> S)
 // Implements proto.Packet interface.
 type EncryptionResponse struct {
-	SharedSecretLength VarInt
-	SharedSecret       ByteArray
-	VerifyTokenLength  VarInt
-	VerifyToken        ByteArray
+	// SharedSecret and VerifyToken are encrypted with the public key of the server.
+	SharedSecret ByteArray
+	VerifyToken  ByteArray
+	// Salt and Signature are sent instead of VerifyToken if Signature is not nil.
+	// Signature is the signature of the verify token followed by the salt,
+	// by the private key of the player.
+	Salt      Long
+	Signature ByteArray
 }
 
 // EncrpytionResponse_ID is the EncryptionResponse packet ID.
@@ -184,15 +250,24 @@ const EncryptionResponse_ID = 0x01
 // ToRaw marshals the EncryptionResponse Packet to the given RawPacket.
 func (pi *EncryptionResponse) ToRaw(p *RawPacket) (err error) {
 	p.ID = EncryptionResponse_ID
-	return p.Marshal(&pi.SharedSecretLength, &pi.SharedSecret, &pi.VerifyTokenLength, &pi.VerifyToken)
+	return p.Marshal(pi.types(p.Protocol)...)
 }
 
 // FromRaw unmarshals the EncryptionResponse Packet from the given RawPacket.
 func (pi *EncryptionResponse) FromRaw(p *RawPacket) (err error) {
 	if p.ID != EncryptionResponse_ID {
-		return fmt.Errorf("invalid packet ID for EncryptionResponse: %d", p.ID)
+		return &PacketIDError{Packet: "EncryptionResponse", Expect: EncryptionResponse_ID, Get: p.ID}
+	}
+	pi.VerifyToken, pi.Salt, pi.Signature = nil, 0, nil
+	return p.unmarshalPacket(pi, pi.types(p.Protocol)...)
+}
+
+// types returns the fields of the packet in the given protocol version
+func (pi *EncryptionResponse) types(protocol int32) []Type {
+	if protocol >= Protocol1_19 && protocol < Protocol1_19_3 {
+		return []Type{&pi.SharedSecret, signedVerifyToken{pi}}
 	}
-	return p.Unmarshal(&pi.SharedSecretLength, &pi.SharedSecret, &pi.VerifyTokenLength, &pi.VerifyToken)
+	return []Type{&pi.SharedSecret, &pi.VerifyToken}
 }
 
 // --- LoginPluginResponse ---
@@ -203,7 +278,8 @@ func (pi *EncryptionResponse) FromRaw(p *RawPacket) (err error) {
 type LoginPluginResponse struct {
 	MessageID  VarInt
 	Successful Boolean
-	Data       ByteArray
+	// Data is only sent if Successful is true.
+	Data RemainingBytes
 }
 
 // LoginPluginResponse_ID is the LoginPluginResponse packet ID.
@@ -218,7 +294,193 @@ func (pi *LoginPluginResponse) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the LoginPluginResponse Packet from the given RawPacket.
 func (pi *LoginPluginResponse) FromRaw(p *RawPacket) (err error) {
 	if p.ID != LoginPluginResponse_ID {
-		return fmt.Errorf("invalid packet ID for LoginPluginResponse: %d", p.ID)
+		return &PacketIDError{Packet: "LoginPluginResponse", Expect: LoginPluginResponse_ID, Get: p.ID}
+	}
+	return p.unmarshalPacket(pi, &pi.MessageID, &pi.Successful, &pi.Data)
+}
+
+// --- LoginAcknowledged ---
+
+// LoginAcknowledged is a packet sent by client to acknowledge LoginSuccess and
+// switch to the configuration state (1.20.2+).
+// Serverbound (C -> S)
+// Implements proto.Packet interface.
+type LoginAcknowledged struct{}
+
+// LoginAcknowledged_ID is the LoginAcknowledged packet ID.
+const LoginAcknowledged_ID = 0x03
+
+// ToRaw marshals the LoginAcknowledged Packet to the given RawPacket.
+func (pi *LoginAcknowledged) ToRaw(p *RawPacket) (err error) {
+	p.ID = LoginAcknowledged_ID
+	return p.Marshal()
+}
+
+// FromRaw unmarshals the LoginAcknowledged Packet from the given RawPacket.
+func (pi *LoginAcknowledged) FromRaw(p *RawPacket) (err error) {
+	if p.ID != LoginAcknowledged_ID {
+		return &PacketIDError{Packet: "LoginAcknowledged", Expect: LoginAcknowledged_ID, Get: p.ID}
+	}
+	return p.unmarshalPacket(pi)
+}
+
+// --- Versioned fields ---
+
+// optionalUUID is an UUID field that may be absent.
+// If prefixed, it is preceded by a Boolean telling whether it is present,
+// otherwise it is always sent, as a zero UUID if absent.
+// Implements proto.Type interface (Minecraft protocol data type).
+type optionalUUID struct {
+	v        **UUID
+	prefixed bool
+}
+
+// ReadFrom reads optionalUUID data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (o optionalUUID) ReadFrom(r io.Reader) (n int64, err error) {
+	if o.prefixed {
+		var present Boolean
+		n, err = present.ReadFrom(r)
+		if err != nil || !present {
+			*o.v = nil
+			return n, err
+		}
+	}
+	*o.v = new(UUID)
+	nn, err := (*o.v).ReadFrom(r)
+	return n + nn, err
+}
+
+// WriteTo writes optionalUUID data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (o optionalUUID) WriteTo(w io.Writer) (n int64, err error) {
+	if o.prefixed {
+		n, err = Boolean(*o.v != nil).WriteTo(w)
+		if err != nil || *o.v == nil {
+			return n, err
+		}
+	}
+	var id UUID
+	if *o.v != nil {
+		id = **o.v
 	}
-	return p.Unmarshal(&pi.MessageID, &pi.Successful, &pi.Data)
+	nn, err := id.WriteTo(w)
+	return n + nn, err
+}
+
+func (o optionalUUID) field() interface{} {
+	return o.v
+}
+
+// optionalPlayerKey is a PlayerKey field preceded by a Boolean telling whether it is present.
+// Implements proto.Type interface (Minecraft protocol data type).
+type optionalPlayerKey struct {
+	v **PlayerKey
+}
+
+// ReadFrom reads optionalPlayerKey data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (o optionalPlayerKey) ReadFrom(r io.Reader) (n int64, err error) {
+	var present Boolean
+	n, err = present.ReadFrom(r)
+	if err != nil || !present {
+		*o.v = nil
+		return n, err
+	}
+	*o.v = new(PlayerKey)
+	nn, err := (*o.v).ReadFrom(r)
+	return n + nn, err
+}
+
+// WriteTo writes optionalPlayerKey data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (o optionalPlayerKey) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = Boolean(*o.v != nil).WriteTo(w)
+	if err != nil || *o.v == nil {
+		return n, err
+	}
+	nn, err := (*o.v).WriteTo(w)
+	return n + nn, err
+}
+
+func (o optionalPlayerKey) field() interface{} {
+	return o.v
+}
+
+// signedVerifyToken is the VerifyToken field of EncryptionResponse from 1.19
+// to 1.19.2: a Boolean telling whether the verify token is sent, followed by
+// the verify token, or by the salt and signature of the player.
+// Implements proto.Type interface (Minecraft protocol data type).
+type signedVerifyToken struct {
+	pi *EncryptionResponse
+}
+
+// ReadFrom reads signedVerifyToken data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (s signedVerifyToken) ReadFrom(r io.Reader) (n int64, err error) {
+	var hasVerifyToken Boolean
+	n, err = hasVerifyToken.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	var nn int64
+	if hasVerifyToken {
+		nn, err = s.pi.VerifyToken.ReadFrom(r)
+	} else {
+		nn, err = readTypes(r, &s.pi.Salt, &s.pi.Signature)
+	}
+	return n + nn, err
+}
+
+// WriteTo writes signedVerifyToken data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (s signedVerifyToken) WriteTo(w io.Writer) (n int64, err error) {
+	if s.pi.Signature == nil {
+		return writeTypes(w, Boolean(true), s.pi.VerifyToken)
+	}
+	return writeTypes(w, Boolean(false), s.pi.Salt, s.pi.Signature)
+}
+
+func (s signedVerifyToken) field() interface{} {
+	return &s.pi.VerifyToken
+}
+
+// stringUUID is an UUID field sent as a String, dashed or not.
+// Implements proto.Type interface (Minecraft protocol data type).
+type stringUUID struct {
+	v      *UUID
+	dashed bool
+}
+
+// ReadFrom reads stringUUID data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (s stringUUID) ReadFrom(r io.Reader) (n int64, err error) {
+	var str String
+	n, err = str.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	return n, s.v.UnmarshalText([]byte(str))
+}
+
+// WriteTo writes stringUUID data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (s stringUUID) WriteTo(w io.Writer) (n int64, err error) {
+	str := s.v.String()
+	if !s.dashed {
+		str = strings.Replace(str, "-", "", -1)
+	}
+	return String(str).WriteTo(w)
+}
+
+func (s stringUUID) field() interface{} {
+	return s.v
 }
@@ -0,0 +1,132 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// roundTrip encodes the packet in the given protocol version and decodes it
+// in strict mode, returning the raw packet.
+func roundTrip(t *testing.T, protocol int32, in, out Packet) *RawPacket {
+	t.Helper()
+	raw := &RawPacket{Protocol: protocol, Strict: true}
+	if err := in.ToRaw(raw); err != nil {
+		t.Fatalf("%T in protocol %d: ToRaw: %v", in, protocol, err)
+	}
+	if err := out.FromRaw(raw); err != nil {
+		t.Fatalf("%T in protocol %d: FromRaw: %v", in, protocol, err)
+	}
+	return raw
+}
+
+func TestLoginStart(t *testing.T) {
+	id := UUID{0x12, 0x34}
+	key := &PlayerKey{ExpiresAt: 1700000000000, PublicKey: ByteArray{1, 2}, Signature: ByteArray{3}}
+
+	tests := []struct {
+		protocol int32
+		in       LoginStart
+		// size is the encoded size of the packet
+		size int
+	}{
+		{Protocol1_16, LoginStart{Name: "Steve"}, 6},
+		{Protocol1_19, LoginStart{Name: "Steve", Key: key}, 6 + 1 + 8 + 3 + 2},
+		{Protocol1_19, LoginStart{Name: "Steve"}, 6 + 1},
+		{Protocol1_19_1, LoginStart{Name: "Steve", Key: key, PlayerUUID: &id}, 6 + 1 + 8 + 3 + 2 + 1 + 16},
+		{Protocol1_19_1, LoginStart{Name: "Steve"}, 6 + 1 + 1},
+		{Protocol1_19_3, LoginStart{Name: "Steve", PlayerUUID: &id}, 6 + 1 + 16},
+		{Protocol1_20_2, LoginStart{Name: "Steve", PlayerUUID: &id}, 6 + 16},
+		{0, LoginStart{Name: "Steve"}, 6},
+	}
+	for _, test := range tests {
+		out := LoginStart{Key: &PlayerKey{}, PlayerUUID: &UUID{9}}
+		raw := roundTrip(t, test.protocol, &test.in, &out)
+		if len(raw.Data) != test.size {
+			t.Errorf("protocol %d: got %d bytes, want %d", test.protocol, len(raw.Data), test.size)
+		}
+		if !reflect.DeepEqual(out, test.in) {
+			t.Errorf("protocol %d: got %#v, want %#v", test.protocol, out, test.in)
+		}
+	}
+
+	// From 1.20.2, the UUID is always sent
+	var out LoginStart
+	roundTrip(t, Protocol1_20_2, &LoginStart{Name: "Steve"}, &out)
+	if out.PlayerUUID == nil || *out.PlayerUUID != (UUID{}) {
+		t.Errorf("got %v, want the zero UUID", out.PlayerUUID)
+	}
+}
+
+func TestLoginSuccess(t *testing.T) {
+	id := UUID{0x12, 0x34, 0x56, 0x78, 0x12, 0x34, 0x56, 0x78, 0x12, 0x34, 0x56, 0x78, 0x12, 0x34, 0x56, 0x78}
+	properties := ProfileProperties{{Name: "textures", Value: "e30=", Signature: "c2ln"}}
+
+	tests := []struct {
+		protocol int32
+		in       LoginSuccess
+		// prefix is the beginning of the encoded packet
+		prefix []byte
+	}{
+		{4, LoginSuccess{UUID: id, Username: "Steve"}, append([]byte{32}, "12345678123456781234567812345678"...)},
+		{Protocol1_7_6, LoginSuccess{UUID: id, Username: "Steve"}, append([]byte{36}, "12345678-1234-5678-1234-567812345678"...)},
+		{Protocol1_16, LoginSuccess{UUID: id, Username: "Steve"}, id[:]},
+		{Protocol1_19, LoginSuccess{UUID: id, Username: "Steve", Properties: properties}, id[:]},
+		{Protocol1_20_5, LoginSuccess{UUID: id, Username: "Steve", Properties: ProfileProperties{}, StrictErrorHandling: true}, id[:]},
+		{Protocol1_21_2, LoginSuccess{UUID: id, Username: "Steve", Properties: ProfileProperties{}}, id[:]},
+	}
+	for _, test := range tests {
+		out := LoginSuccess{Properties: ProfileProperties{{}}, StrictErrorHandling: true}
+		raw := roundTrip(t, test.protocol, &test.in, &out)
+		if !bytes.HasPrefix(raw.Data, test.prefix) {
+			t.Errorf("protocol %d: got % X, want prefix % X", test.protocol, raw.Data, test.prefix)
+		}
+		if !reflect.DeepEqual(out, test.in) {
+			t.Errorf("protocol %d: got %#v, want %#v", test.protocol, out, test.in)
+		}
+	}
+
+	// The strict error handling flag is only sent from 1.20.5 to 1.21.1
+	raw := &RawPacket{Protocol: Protocol1_21_2}
+	in := LoginSuccess{UUID: id, Username: "Steve", StrictErrorHandling: true}
+	if err := in.ToRaw(raw); err != nil {
+		t.Fatal(err)
+	}
+	raw.Protocol, raw.Strict = Protocol1_20_5, true
+	if err := (&LoginSuccess{}).FromRaw(raw); !errors.Is(err, ErrTruncated) {
+		t.Errorf("1.21.2 packet read as 1.20.5: got %v, want ErrTruncated", err)
+	}
+}
+
+func TestEncryptionResponse(t *testing.T) {
+	tests := []struct {
+		protocol int32
+		in       EncryptionResponse
+	}{
+		{Protocol1_18_2, EncryptionResponse{SharedSecret: ByteArray{1}, VerifyToken: ByteArray{2}}},
+		{Protocol1_19, EncryptionResponse{SharedSecret: ByteArray{1}, VerifyToken: ByteArray{2}}},
+		{Protocol1_19, EncryptionResponse{SharedSecret: ByteArray{1}, Salt: -5, Signature: ByteArray{3, 4}}},
+		{Protocol1_19_3, EncryptionResponse{SharedSecret: ByteArray{1}, VerifyToken: ByteArray{2}}},
+	}
+	for _, test := range tests {
+		out := EncryptionResponse{VerifyToken: ByteArray{9}, Salt: 9, Signature: ByteArray{9}}
+		roundTrip(t, test.protocol, &test.in, &out)
+		if !reflect.DeepEqual(out, test.in) {
+			t.Errorf("protocol %d: got %#v, want %#v", test.protocol, out, test.in)
+		}
+	}
+}
+
+func TestLoginStartMalformed(t *testing.T) {
+	// A player key whose public key is truncated
+	raw := &RawPacket{ID: LoginStart_ID, Protocol: Protocol1_19, Data: []byte{1, 'a', 1, 0, 0, 0, 0, 0, 0, 0, 0, 5, 1}}
+	err := (&LoginStart{}).FromRaw(raw)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !errors.Is(err, ErrTruncated) {
+		t.Fatalf("got %v, want a truncated *FieldError", err)
+	}
+	if fieldErr.Field != "Key" || fieldErr.Offset != 2 {
+		t.Errorf("got field %s at offset %d, want Key at offset 2", fieldErr.Field, fieldErr.Offset)
+	}
+}
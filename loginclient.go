@@ -0,0 +1,208 @@
+package proto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// --- LoginClient ---
+
+// LoginClient logs players in to servers, on the client side.
+type LoginClient struct {
+	// Address is the server address sent in the Handshake, host and port.
+	// If empty, the remote address of the connection is used.
+	Address string
+	// ProtocolVersion is the protocol version sent in the Handshake.
+	ProtocolVersion int32
+	// Plugins answers the login plugin requests.
+	// If nil, all the requests are answered as not understood.
+	Plugins *LoginPluginDispatcher
+}
+
+// Login sends the Handshake and logs the player in on the connection.
+// The server authenticates the player through auth, if it is in online mode.
+// auth may be nil to join offline-mode servers only.
+// Encryption and compression are enabled on the Conn as asked by the server.
+// It returns the profile sent by the server in LoginSuccess, once the connection
+// reaches the play state, or the configuration state from 1.20.2.
+// If the server disconnects the player, a *DisconnectError is returned.
+func (c *LoginClient) Login(ctx context.Context, conn *Conn, profile *GameProfile, auth SessionAuthenticator) (*GameProfile, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	result, err := c.login(ctx, conn, profile, auth)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return result, nil
+}
+
+func (c *LoginClient) login(ctx context.Context, conn *Conn, profile *GameProfile, auth SessionAuthenticator) (*GameProfile, error) {
+	handshake, err := c.handshake(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Send(handshake); err != nil {
+		return nil, err
+	}
+	conn.Protocol = c.ProtocolVersion
+
+	id := profile.ID
+	if id == (UUID{}) {
+		id = OfflineUUID(profile.Name)
+	}
+	if err := conn.Send(&LoginStart{Name: String(profile.Name), PlayerUUID: &id}); err != nil {
+		return nil, err
+	}
+
+	var p RawPacket
+	for {
+		if err := conn.ReadPacket(&p); err != nil {
+			return nil, err
+		}
+
+		switch p.ID {
+		case LoginDisconnect_ID:
+			var disconnect LoginDisconnect
+			if err := disconnect.FromRaw(&p); err != nil {
+				return nil, err
+			}
+			var reason TextComponent
+			if err := json.Unmarshal([]byte(disconnect.Reason), &reason); err != nil {
+				reason = TextComponent{Text: string(disconnect.Reason)}
+			}
+			return nil, &DisconnectError{Reason: reason}
+
+		case EncryptionRequest_ID:
+			var request EncryptionRequest
+			if err := request.FromRaw(&p); err != nil {
+				return nil, err
+			}
+			if err := c.encrypt(ctx, conn, &request, profile, auth); err != nil {
+				return nil, err
+			}
+
+		case SetCompression_ID:
+			var compression SetCompression
+			if err := compression.FromRaw(&p); err != nil {
+				return nil, err
+			}
+			conn.Threshold = int(compression.Threshold)
+
+		case LoginPluginRequest_ID:
+			var request LoginPluginRequest
+			if err := request.FromRaw(&p); err != nil {
+				return nil, err
+			}
+			response, err := c.Plugins.Answer(&request)
+			if err != nil {
+				return nil, err
+			}
+			if err := conn.Send(response); err != nil {
+				return nil, err
+			}
+
+		case LoginSuccess_ID:
+			var success LoginSuccess
+			if err := success.FromRaw(&p); err != nil {
+				return nil, err
+			}
+			if handshake.ProtocolVersion >= Protocol1_20_2 {
+				if err := conn.Send(&LoginAcknowledged{}); err != nil {
+					return nil, err
+				}
+			}
+			return &GameProfile{
+				ID:         success.UUID,
+				Name:       string(success.Username),
+				Properties: []ProfileProperty(success.Properties),
+			}, nil
+
+		default:
+			return nil, fmt.Errorf("%w: packet 0x%02X in login state", ErrUnexpectedPacket, p.ID)
+		}
+	}
+}
+
+// handshake returns the Handshake sent to the server
+func (c *LoginClient) handshake(conn *Conn) (*Handshake, error) {
+	address := c.Address
+	if address == "" {
+		address = conn.RemoteAddr().String()
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 0xFFFF {
+		return nil, fmt.Errorf("invalid port in address %q", address)
+	}
+
+	return &Handshake{
+		ProtocolVersion: VarInt(c.ProtocolVersion),
+		ServerAddress:   String(host),
+		ServerPort:      UnsignedShort(port),
+		NextState:       StateLogin,
+	}, nil
+}
+
+// encrypt answers the EncryptionRequest and enables encryption
+func (c *LoginClient) encrypt(ctx context.Context, conn *Conn, request *EncryptionRequest, profile *GameProfile, auth SessionAuthenticator) error {
+	key, err := x509.ParsePKIXPublicKey(request.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid server public key: %w", err)
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("invalid server public key: not a RSA key")
+	}
+
+	secret := make([]byte, SharedSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	// From 1.20.5, the server may not authenticate the player
+	authenticate := conn.Protocol < Protocol1_20_5 || bool(request.ShouldAuthenticate)
+	if auth != nil && authenticate {
+		err := auth.Join(ctx, profile, AuthDigest(string(request.ServerID), secret, request.PublicKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, secret)
+	if err != nil {
+		return err
+	}
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, request.VerifyToken)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Send(&EncryptionResponse{SharedSecret: encryptedSecret, VerifyToken: encryptedToken})
+	if err != nil {
+		return err
+	}
+	return conn.EnableEncryption(secret)
+}
@@ -0,0 +1,258 @@
+package proto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultLoginTimeout is the timeout of the login of a LoginHandler, like the vanilla server.
+const defaultLoginTimeout = 30 * time.Second
+
+// maxUsernameLength is the maximum length of a player name.
+const maxUsernameLength = 16
+
+// verifyTokenLength is the length of the verify token of the EncryptionRequest.
+const verifyTokenLength = 4
+
+// serverKeyBits is the size of the RSA key of the server, like the vanilla server.
+const serverKeyBits = 1024
+
+// --- LoginHandler ---
+
+// LoginHandler drives the login state on the server side:
+// LoginStart, then EncryptionRequest and EncryptionResponse in online mode,
+// SetCompression if enabled, login plugin messages, LoginSuccess and,
+// from 1.20.2, LoginAcknowledged.
+// Encryption and compression are enabled on the Conn at the points the client expects.
+type LoginHandler struct {
+	// OnlineMode authenticates the players through the session server.
+	// In offline mode, players get the offline UUID of their name.
+	OnlineMode bool
+	// CompressionThreshold is the compression threshold sent to the clients:
+	// packets of this size or larger are compressed, so zero compresses every
+	// packet, like the network-compression-threshold of the vanilla server.
+	// If negative, compression is not enabled.
+	CompressionThreshold int
+	// Timeout is the maximum duration of the login. If zero, 30 seconds is used.
+	Timeout time.Duration
+
+	// Key is the RSA key used to exchange the shared secret.
+	// If nil, a key is generated once when it is first needed.
+	Key *rsa.PrivateKey
+	// Verifier authenticates the players in online mode.
+	// If nil, the Mojang session server is used.
+	Verifier SessionVerifier
+
+	// PluginRequests, if set, exchanges login plugin messages with the client,
+	// once encryption and compression are enabled, before the profile is resolved.
+	// See LoginPluginDispatcher.
+	PluginRequests func(conn *Conn, profile *GameProfile) error
+	// ResolveProfile, if set, returns the profile the player logs in with.
+	// It may change the authenticated profile, or deny the login by returning
+	// an error, a *DisconnectError to send a custom reason.
+	ResolveProfile func(conn *Conn, profile *GameProfile) (*GameProfile, error)
+
+	keyOnce sync.Once
+	keyErr  error
+	// publicKey is the DER-encoded public key of Key
+	publicKey []byte
+}
+
+// HandleLogin logs in the client whose Handshake to the login state was already read.
+// It returns the profile of the player, once the connection reaches the play
+// state, or the configuration state from 1.20.2.
+// If the login fails, the client is sent a LoginDisconnect with the reason,
+// but the connection is not closed.
+func (h *LoginHandler) HandleLogin(ctx context.Context, conn *Conn, handshake *Handshake) (*GameProfile, error) {
+	deadline := time.Now().Add(h.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	profile, err := h.login(ctx, conn, handshake, deadline)
+	if err != nil {
+		h.disconnect(conn, err)
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return profile, nil
+}
+
+func (h *LoginHandler) login(ctx context.Context, conn *Conn, handshake *Handshake, deadline time.Time) (*GameProfile, error) {
+	conn.Protocol = int32(handshake.ProtocolVersion)
+	var start LoginStart
+	if err := conn.Receive(&start); err != nil {
+		return nil, err
+	}
+	name := string(start.Name)
+	if !validUsername(name) {
+		return nil, &DisconnectError{Reason: TextComponent{Translate: "multiplayer.disconnect.invalid_player_data"}}
+	}
+
+	var profile *GameProfile
+	if h.OnlineMode {
+		var err error
+		if profile, err = h.authenticate(ctx, conn, &start); err != nil {
+			return nil, err
+		}
+	} else {
+		profile = &GameProfile{ID: OfflineUUID(name), Name: name}
+	}
+
+	if h.CompressionThreshold >= 0 {
+		if err := conn.Send(&SetCompression{Threshold: VarInt(h.CompressionThreshold)}); err != nil {
+			return nil, err
+		}
+		conn.Threshold = h.CompressionThreshold
+	}
+
+	if h.PluginRequests != nil {
+		if err := h.PluginRequests(conn, profile); err != nil {
+			return nil, err
+		}
+		// The hook may change the deadline, a LoginPluginDispatcher does
+		conn.SetDeadline(deadline)
+	}
+	if h.ResolveProfile != nil {
+		var err error
+		if profile, err = h.ResolveProfile(conn, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	err := conn.Send(&LoginSuccess{
+		UUID:       profile.ID,
+		Username:   String(profile.Name),
+		Properties: ProfileProperties(profile.Properties),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if handshake.ProtocolVersion >= Protocol1_20_2 {
+		var ack LoginAcknowledged
+		if err := conn.Receive(&ack); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// authenticate enables encryption and verifies the session of the player
+func (h *LoginHandler) authenticate(ctx context.Context, conn *Conn, start *LoginStart) (*GameProfile, error) {
+	key, publicKey, err := h.key()
+	if err != nil {
+		return nil, err
+	}
+
+	verifyToken := make([]byte, verifyTokenLength)
+	if _, err := rand.Read(verifyToken); err != nil {
+		return nil, err
+	}
+	err = conn.Send(&EncryptionRequest{PublicKey: publicKey, VerifyToken: verifyToken, ShouldAuthenticate: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var response EncryptionResponse
+	if err := conn.Receive(&response); err != nil {
+		return nil, err
+	}
+	if response.Signature != nil {
+		// From 1.19 to 1.19.2, the players with a key sign the verify token instead
+		if start.Key == nil {
+			return nil, errors.New("verify token signed without player key")
+		}
+		data := appendInt64(append([]byte(nil), verifyToken...), int64(response.Salt))
+		if err := start.Key.Verify(data, response.Signature); err != nil {
+			return nil, fmt.Errorf("invalid verify token signature: %w", err)
+		}
+	} else {
+		token, err := rsa.DecryptPKCS1v15(rand.Reader, key, response.VerifyToken)
+		if err != nil || subtle.ConstantTimeCompare(token, verifyToken) != 1 {
+			return nil, errors.New("invalid verify token")
+		}
+	}
+	secret, err := rsa.DecryptPKCS1v15(rand.Reader, key, response.SharedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shared secret: %w", err)
+	}
+	if err := conn.EnableEncryption(secret); err != nil {
+		return nil, err
+	}
+
+	var verifier SessionVerifier = &SessionServer{}
+	if h.Verifier != nil {
+		verifier = h.Verifier
+	}
+	profile, err := verifier.HasJoined(ctx, string(start.Name), AuthDigest("", secret, publicKey))
+	if errors.Is(err, ErrUnverifiedUsername) {
+		return nil, &DisconnectError{Reason: TextComponent{Translate: "multiplayer.disconnect.unverified_username"}}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session server: %w", err)
+	}
+	return profile, nil
+}
+
+// key returns the RSA key of the server and its DER-encoded public key
+func (h *LoginHandler) key() (*rsa.PrivateKey, []byte, error) {
+	h.keyOnce.Do(func() {
+		if h.Key == nil {
+			h.Key, h.keyErr = rsa.GenerateKey(rand.Reader, serverKeyBits)
+			if h.keyErr != nil {
+				return
+			}
+		}
+		h.publicKey, h.keyErr = x509.MarshalPKIXPublicKey(&h.Key.PublicKey)
+	})
+	return h.Key, h.publicKey, h.keyErr
+}
+
+func (h *LoginHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultLoginTimeout
+}
+
+// validUsername reports whether the name is a valid player name:
+// 1 to 16 ASCII letters, digits and underscores.
+func validUsername(name string) bool {
+	if name == "" || len(name) > maxUsernameLength {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// disconnect sends the reason of the failed login to the client
+func (h *LoginHandler) disconnect(conn *Conn, err error) {
+	reason := TextComponent{Translate: "multiplayer.disconnect.generic"}
+	var disconnectErr *DisconnectError
+	if errors.As(err, &disconnectErr) {
+		reason = disconnectErr.Reason
+	}
+
+	b, err := json.Marshal(reason)
+	if err != nil {
+		return
+	}
+	// The connection may be broken already, or the login timed out
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	conn.Send(&LoginDisconnect{Reason: Chat(b)})
+}
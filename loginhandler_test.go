@@ -0,0 +1,268 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuthDigest(t *testing.T) {
+	tests := []struct {
+		serverID, digest string
+	}{
+		{"Notch", "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48"},
+		{"jeb_", "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1"},
+		{"simon", "88e16a1019277b15d58faf0541e11910eb756f6"},
+	}
+	for _, test := range tests {
+		if got := AuthDigest(test.serverID, nil, nil); got != test.digest {
+			t.Errorf("AuthDigest(%q) = %s, want %s", test.serverID, got, test.digest)
+		}
+	}
+	// The server ID, secret and public key are hashed in this order
+	if got := AuthDigest("", []byte("jeb"), []byte("_")); got != tests[1].digest {
+		t.Errorf("got %s, want %s", got, tests[1].digest)
+	}
+}
+
+func TestCFB8(t *testing.T) {
+	// NIST SP 800-38A, F.3.7 CFB8-AES128.Encrypt
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	ciphertext, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range []int{1, 5, len(plaintext)} {
+		encrypter := newCFB8(block, iv, false)
+		decrypter := newCFB8(block, iv, true)
+		encrypted := make([]byte, len(plaintext))
+		decrypted := make([]byte, len(plaintext))
+		for i := 0; i < len(plaintext); i += chunk {
+			end := i + chunk
+			if end > len(plaintext) {
+				end = len(plaintext)
+			}
+			encrypter.XORKeyStream(encrypted[i:end], plaintext[i:end])
+			decrypter.XORKeyStream(decrypted[i:end], ciphertext[i:end])
+		}
+		if !bytes.Equal(encrypted, ciphertext) {
+			t.Errorf("chunks of %d: encrypted % X, want % X", chunk, encrypted, ciphertext)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("chunks of %d: decrypted % X, want % X", chunk, decrypted, plaintext)
+		}
+	}
+
+	// In place, as cipher.StreamReader does
+	buf := append([]byte(nil), ciphertext...)
+	newCFB8(block, iv, true).XORKeyStream(buf, buf)
+	if !bytes.Equal(buf, plaintext) {
+		t.Errorf("in place: decrypted % X, want % X", buf, plaintext)
+	}
+	var _ cipher.Stream = newCFB8(block, iv, true)
+}
+
+// testSession is a session server shared by a LoginClient and a LoginHandler
+type testSession struct {
+	mu     sync.Mutex
+	joined map[string]*GameProfile
+}
+
+func (s *testSession) Join(ctx context.Context, profile *GameProfile, serverHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.joined == nil {
+		s.joined = make(map[string]*GameProfile)
+	}
+	s.joined[profile.Name+" "+serverHash] = profile
+	return nil
+}
+
+func (s *testSession) HasJoined(ctx context.Context, username, serverHash string) (*GameProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.joined[username+" "+serverHash]
+	if !ok {
+		return nil, ErrUnverifiedUsername
+	}
+	return profile, nil
+}
+
+// testLogin logs a player in between a LoginClient and a LoginHandler over
+// net.Pipe, then sends a packet from the server to check that both sides
+// switched encryption and compression at the same point.
+func testLogin(t *testing.T, client *LoginClient, handler *LoginHandler, profile *GameProfile, auth SessionAuthenticator) (clientProfile, serverProfile *GameProfile, clientErr, serverErr error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientSide, serverSide := net.Pipe()
+	defer clientSide.Close()
+	defer serverSide.Close()
+	clientConn, serverConn := NewConn(clientSide), NewConn(serverSide)
+
+	packet := &RawPacket{ID: 0x10, Data: bytes.Repeat([]byte("data"), 100)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var handshake Handshake
+		if serverErr = serverConn.Receive(&handshake); serverErr != nil {
+			return
+		}
+		if serverProfile, serverErr = handler.HandleLogin(ctx, serverConn, &handshake); serverErr != nil {
+			serverSide.Close()
+			return
+		}
+		serverErr = serverConn.WritePacket(packet)
+	}()
+
+	clientProfile, clientErr = client.Login(ctx, clientConn, profile, auth)
+	if clientErr == nil {
+		var p RawPacket
+		if err := clientConn.ReadPacket(&p); err != nil {
+			t.Errorf("reading the packet after login: %v", err)
+		} else if p.ID != packet.ID || !bytes.Equal(p.Data, packet.Data) {
+			t.Errorf("got packet 0x%02X with %d bytes after login, want 0x%02X with %d bytes", p.ID, len(p.Data), packet.ID, len(packet.Data))
+		}
+	}
+	<-done
+	return
+}
+
+func TestLoginOffline(t *testing.T) {
+	for _, protocol := range []int32{Protocol1_19_3, Protocol1_20_2, Protocol1_20_5} {
+		for _, threshold := range []int{-1, 0, 256} {
+			client := &LoginClient{Address: "example.com:25565", ProtocolVersion: protocol}
+			handler := &LoginHandler{CompressionThreshold: threshold}
+			got, server, clientErr, serverErr := testLogin(t, client, handler, &GameProfile{Name: "Steve"}, nil)
+			if clientErr != nil || serverErr != nil {
+				t.Fatalf("protocol %d, threshold %d: client: %v, server: %v", protocol, threshold, clientErr, serverErr)
+			}
+			want := GameProfile{ID: OfflineUUID("Steve"), Name: "Steve"}
+			if got.ID != want.ID || got.Name != want.Name || server.ID != want.ID || server.Name != want.Name {
+				t.Errorf("protocol %d, threshold %d: client got %+v, server got %+v, want %+v", protocol, threshold, got, server, want)
+			}
+		}
+	}
+}
+
+func TestLoginOnline(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &testSession{}
+	profile := &GameProfile{
+		ID:         UUID{1, 2, 3},
+		Name:       "Steve",
+		Properties: []ProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}},
+	}
+
+	for _, protocol := range []int32{Protocol1_19_3, Protocol1_20_5} {
+		client := &LoginClient{Address: "example.com:25565", ProtocolVersion: protocol}
+		handler := &LoginHandler{OnlineMode: true, CompressionThreshold: 64, Key: key, Verifier: session}
+		got, server, clientErr, serverErr := testLogin(t, client, handler, profile, session)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("protocol %d: client: %v, server: %v", protocol, clientErr, serverErr)
+		}
+		if got.ID != profile.ID || len(got.Properties) != 1 || got.Properties[0] != profile.Properties[0] {
+			t.Errorf("protocol %d: client got %+v, want %+v", protocol, got, profile)
+		}
+		if server != profile {
+			t.Errorf("protocol %d: server got %+v, want %+v", protocol, server, profile)
+		}
+	}
+
+	// The player did not join the session server
+	client := &LoginClient{Address: "example.com:25565", ProtocolVersion: Protocol1_20_5}
+	handler := &LoginHandler{OnlineMode: true, Key: key, Verifier: session}
+	_, _, clientErr, serverErr := testLogin(t, client, handler, &GameProfile{Name: "Alex"}, nil)
+	var disconnectErr *DisconnectError
+	if !errors.As(clientErr, &disconnectErr) || disconnectErr.Reason.Translate != "multiplayer.disconnect.unverified_username" {
+		t.Errorf("client: got %v, want unverified username", clientErr)
+	}
+	if serverErr == nil {
+		t.Error("server: no error")
+	}
+}
+
+func TestLoginClientDisconnect(t *testing.T) {
+	tests := []struct {
+		reason Chat
+		want   TextComponent
+	}{
+		{`{"text":"Banned","color":"red"}`, TextComponent{Text: "Banned", Color: "red"}},
+		{`"Server closed"`, TextComponent{Text: "Server closed"}},
+		{`{"translate":"multiplayer.disconnect.outdated_client","with":["1.21"]}`, TextComponent{Translate: "multiplayer.disconnect.outdated_client", With: []TextComponent{{Text: "1.21"}}}},
+		// Reasons that are not JSON are used as text
+		{"Not JSON", TextComponent{Text: "Not JSON"}},
+	}
+	for _, test := range tests {
+		clientSide, serverSide := net.Pipe()
+		go func(reason Chat) {
+			defer serverSide.Close()
+			conn := NewConn(serverSide)
+			var handshake Handshake
+			var start LoginStart
+			if conn.Receive(&handshake) != nil {
+				return
+			}
+			conn.Protocol = int32(handshake.ProtocolVersion)
+			if conn.Receive(&start) != nil {
+				return
+			}
+			// The disconnection may follow the compression
+			if conn.Send(&SetCompression{Threshold: 16}) != nil {
+				return
+			}
+			conn.Threshold = 16
+			conn.Send(&LoginDisconnect{Reason: reason})
+		}(test.reason)
+
+		client := &LoginClient{Address: "example.com:25565", ProtocolVersion: Protocol1_20_5}
+		_, err := client.Login(context.Background(), NewConn(clientSide), &GameProfile{Name: "Steve"}, nil)
+		clientSide.Close()
+		var disconnectErr *DisconnectError
+		if !errors.As(err, &disconnectErr) || !errors.Is(err, ErrDisconnected) {
+			t.Errorf("%s: got %v, want a *DisconnectError", test.reason, err)
+			continue
+		}
+		if !reflect.DeepEqual(disconnectErr.Reason, test.want) {
+			t.Errorf("%s: got reason %+v, want %+v", test.reason, disconnectErr.Reason, test.want)
+		}
+	}
+}
+
+func TestLoginInvalidUsername(t *testing.T) {
+	for _, name := range []string{"", "Steve Jobs", "§cSteve", "Stéve", "Steve\x00", "ABCDEFGHIJKLMNOPQ"} {
+		client := &LoginClient{Address: "example.com:25565", ProtocolVersion: Protocol1_20_5}
+		_, _, clientErr, serverErr := testLogin(t, client, &LoginHandler{}, &GameProfile{ID: UUID{1}, Name: name}, nil)
+		var disconnectErr *DisconnectError
+		if !errors.As(clientErr, &disconnectErr) || disconnectErr.Reason.Translate != "multiplayer.disconnect.invalid_player_data" {
+			t.Errorf("%q: client got %v, want invalid player data", name, clientErr)
+		}
+		if !errors.As(serverErr, &disconnectErr) {
+			t.Errorf("%q: server got %v, want a *DisconnectError", name, serverErr)
+		}
+	}
+
+	for _, name := range []string{"a", "Steve_123", "ABCDEFGHIJKLMNOP"} {
+		if !validUsername(name) {
+			t.Errorf("%q: invalid", name)
+		}
+	}
+}
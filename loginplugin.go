@@ -0,0 +1,238 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultLoginPluginTimeout is the time a LoginPluginDispatcher waits for a response.
+const defaultLoginPluginTimeout = 10 * time.Second
+
+// ErrPluginNotUnderstood is returned by a LoginPluginHandler to answer that
+// it does not understand the request.
+var ErrPluginNotUnderstood = errors.New("login plugin request not understood")
+
+// LoginPluginHandler answers the data of a login plugin request, on the client side.
+// Returning ErrPluginNotUnderstood answers an unsuccessful response,
+// other errors abort the login.
+type LoginPluginHandler func(data []byte) ([]byte, error)
+
+// LoginPluginCallback receives the response to a login plugin request, on the server side.
+// understood is false if the client did not understand the request, or did
+// not answer it in time. Returning an error aborts the login.
+type LoginPluginCallback func(data []byte, understood bool) error
+
+// --- LoginPluginDispatcher ---
+
+// LoginPluginDispatcher routes login plugin messages.
+//
+// On the client side, the handlers registered with Handle answer the requests
+// of the server by channel. Requests on other channels are answered as not understood.
+//
+// On the server side, requests sent with Request are tracked by message ID until
+// Wait receives their response, or until they time out and are answered as not
+// understood. The requests are tracked per connection: a dispatcher sending
+// requests must not be shared by connections.
+type LoginPluginDispatcher struct {
+	// Timeout is the time the client has to answer a request. If zero, 10 seconds is used.
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	handlers map[string]LoginPluginHandler
+
+	nextID  int32
+	pending map[int32]*loginPluginCall
+}
+
+// loginPluginCall is a request waiting for its response
+type loginPluginCall struct {
+	id       int32
+	channel  string
+	deadline time.Time
+	callback LoginPluginCallback
+}
+
+// Handle registers the handler answering the requests on the channel.
+func (d *LoginPluginDispatcher) Handle(channel string, handler LoginPluginHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.handlers == nil {
+		d.handlers = make(map[string]LoginPluginHandler)
+	}
+	d.handlers[channel] = handler
+}
+
+// Answer returns the response to the request, from the handler registered on its channel.
+// A nil dispatcher answers all the requests as not understood.
+func (d *LoginPluginDispatcher) Answer(request *LoginPluginRequest) (*LoginPluginResponse, error) {
+	response := &LoginPluginResponse{MessageID: request.MessageID}
+	if d == nil {
+		return response, nil
+	}
+
+	d.mu.Lock()
+	handler, ok := d.handlers[string(request.Channel)]
+	d.mu.Unlock()
+	if !ok {
+		return response, nil
+	}
+
+	data, err := handler(request.Data)
+	if errors.Is(err, ErrPluginNotUnderstood) {
+		return response, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("login plugin %s: %w", request.Channel, err)
+	}
+	response.Successful = true
+	response.Data = data
+	return response, nil
+}
+
+// Request sends a request on the channel to the client. The callback receives
+// its response when Wait reads it.
+func (d *LoginPluginDispatcher) Request(conn *Conn, channel string, data []byte, callback LoginPluginCallback) error {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = defaultLoginPluginTimeout
+	}
+
+	d.mu.Lock()
+	id := d.nextID
+	d.nextID++
+	if d.pending == nil {
+		d.pending = make(map[int32]*loginPluginCall)
+	}
+	d.pending[id] = &loginPluginCall{id: id, channel: channel, deadline: time.Now().Add(timeout), callback: callback}
+	d.mu.Unlock()
+
+	err := conn.Send(&LoginPluginRequest{MessageID: VarInt(id), Channel: Identifier(channel), Data: data})
+	if err != nil {
+		d.mu.Lock()
+		delete(d.pending, id)
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// Call sends a request on the channel to the client, and waits for the
+// responses to all the pending requests.
+// It returns the response to this request.
+func (d *LoginPluginDispatcher) Call(conn *Conn, channel string, data []byte) (response []byte, understood bool, err error) {
+	err = d.Request(conn, channel, data, func(data []byte, ok bool) error {
+		response, understood = data, ok
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := d.Wait(conn); err != nil {
+		return nil, false, err
+	}
+	return response, understood, nil
+}
+
+// Pending returns the number of requests waiting for their response.
+func (d *LoginPluginDispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Wait reads the responses of the client until all the pending requests are
+// answered, and passes them to their callbacks.
+// It changes the read deadline of the connection to the deadline of the
+// oldest pending request, and clears it on return.
+// The requests that time out are answered as not understood, and their late
+// responses ignored.
+func (d *LoginPluginDispatcher) Wait(conn *Conn) error {
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		d.mu.Lock()
+		var next *loginPluginCall
+		for _, call := range d.pending {
+			if next == nil || call.deadline.Before(next.deadline) {
+				next = call
+			}
+		}
+		d.mu.Unlock()
+		if next == nil {
+			return nil
+		}
+
+		conn.SetReadDeadline(next.deadline)
+		var response LoginPluginResponse
+		if err := conn.Receive(&response); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if err := d.expire(next); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		id := int32(response.MessageID)
+		d.mu.Lock()
+		call, ok := d.pending[id]
+		delete(d.pending, id)
+		// The IDs are sequential: a lower ID is the one of a forgotten request
+		late := id >= 0 && id < d.nextID
+		d.mu.Unlock()
+		if !ok {
+			if late {
+				continue
+			}
+			return fmt.Errorf("%w: login plugin response to unknown message %d", ErrUnexpectedPacket, id)
+		}
+
+		var data []byte
+		if response.Successful {
+			data = response.Data
+		}
+		if err := call.answer(data, bool(response.Successful)); err != nil {
+			return err
+		}
+	}
+}
+
+// expire forgets the request that timed out, and the others whose deadline
+// passed, and answers them as not understood in the order they were sent
+func (d *LoginPluginDispatcher) expire(call *loginPluginCall) error {
+	d.mu.Lock()
+	delete(d.pending, call.id)
+	expired := []*loginPluginCall{call}
+	now := time.Now()
+	for id, c := range d.pending {
+		if !c.deadline.After(now) {
+			delete(d.pending, id)
+			expired = append(expired, c)
+		}
+	}
+	d.mu.Unlock()
+
+	sort.Slice(expired, func(i, j int) bool { return expired[i].id < expired[j].id })
+	for _, c := range expired {
+		if err := c.answer(nil, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// answer passes the response to the callback
+func (c *loginPluginCall) answer(data []byte, understood bool) error {
+	if c.callback == nil {
+		return nil
+	}
+	if err := c.callback(data, understood); err != nil {
+		return fmt.Errorf("login plugin %s: %w", c.channel, err)
+	}
+	return nil
+}
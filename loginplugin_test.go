@@ -0,0 +1,204 @@
+package proto
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoginPluginAnswer(t *testing.T) {
+	d := &LoginPluginDispatcher{}
+	d.Handle("test:echo", func(data []byte) ([]byte, error) { return data, nil })
+	d.Handle("test:unknown", func(data []byte) ([]byte, error) { return nil, ErrPluginNotUnderstood })
+	d.Handle("test:fail", func(data []byte) ([]byte, error) { return nil, errors.New("failed") })
+
+	tests := []struct {
+		dispatcher *LoginPluginDispatcher
+		channel    string
+		want       LoginPluginResponse
+	}{
+		{d, "test:echo", LoginPluginResponse{MessageID: 3, Successful: true, Data: RemainingBytes{1, 2}}},
+		{d, "test:unknown", LoginPluginResponse{MessageID: 3}},
+		{d, "test:other", LoginPluginResponse{MessageID: 3}},
+		{nil, "test:echo", LoginPluginResponse{MessageID: 3}},
+	}
+	for _, test := range tests {
+		response, err := test.dispatcher.Answer(&LoginPluginRequest{MessageID: 3, Channel: Identifier(test.channel), Data: RemainingBytes{1, 2}})
+		if err != nil {
+			t.Errorf("%s: %v", test.channel, err)
+		} else if !reflect.DeepEqual(*response, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.channel, *response, test.want)
+		}
+	}
+
+	if _, err := d.Answer(&LoginPluginRequest{Channel: "test:fail"}); err == nil {
+		t.Error("test:fail: no error")
+	}
+}
+
+// loginPluginResponse is the answer of a client to a login plugin request
+type loginPluginResponse struct {
+	data       []byte
+	understood bool
+}
+
+// recordResponse returns a callback recording the responses
+func recordResponse(responses map[string][]loginPluginResponse, channel string) LoginPluginCallback {
+	return func(data []byte, understood bool) error {
+		responses[channel] = append(responses[channel], loginPluginResponse{data, understood})
+		return nil
+	}
+}
+
+// loginPluginClient reads n requests on the connection, then writes the responses
+func loginPluginClient(conn *Conn, n int, responses ...LoginPluginResponse) chan error {
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			var request LoginPluginRequest
+			if err := conn.Receive(&request); err != nil {
+				done <- err
+				return
+			}
+		}
+		for i := range responses {
+			if err := conn.Send(&responses[i]); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	return done
+}
+
+func TestLoginPluginWait(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn := NewConn(server)
+	d := &LoginPluginDispatcher{}
+
+	// The responses are matched by ID, in any order
+	done := loginPluginClient(NewConn(client), 3,
+		LoginPluginResponse{MessageID: 2, Successful: true, Data: RemainingBytes("c")},
+		LoginPluginResponse{MessageID: 0},
+		LoginPluginResponse{MessageID: 1, Successful: true, Data: RemainingBytes("b")},
+	)
+	responses := make(map[string][]loginPluginResponse)
+	for _, channel := range []string{"test:a", "test:b", "test:c"} {
+		if err := d.Request(conn, channel, nil, recordResponse(responses, channel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d.Pending() != 3 {
+		t.Errorf("got %d pending requests, want 3", d.Pending())
+	}
+	if err := d.Wait(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]loginPluginResponse{
+		"test:a": {{nil, false}},
+		"test:b": {{[]byte("b"), true}},
+		"test:c": {{[]byte("c"), true}},
+	}
+	if !reflect.DeepEqual(responses, want) {
+		t.Errorf("got %v, want %v", responses, want)
+	}
+	if d.Pending() != 0 {
+		t.Errorf("got %d pending requests, want 0", d.Pending())
+	}
+
+	// A response to a message never sent is unexpected
+	done = loginPluginClient(NewConn(client), 1, LoginPluginResponse{MessageID: 7})
+	if _, _, err := d.Call(conn, "test:d", nil); !errors.Is(err, ErrUnexpectedPacket) {
+		t.Errorf("got %v, want ErrUnexpectedPacket", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginPluginTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn := NewConn(server)
+	d := &LoginPluginDispatcher{Timeout: 50 * time.Millisecond}
+
+	// All the requests that time out are answered as not understood, and the
+	// others are still waited for
+	done := loginPluginClient(NewConn(client), 3)
+	responses := make(map[string][]loginPluginResponse)
+	for _, channel := range []string{"test:a", "test:b"} {
+		if err := d.Request(conn, channel, nil, recordResponse(responses, channel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Timeout = time.Second
+	if err := d.Request(conn, "test:c", nil, recordResponse(responses, "test:c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		done <- NewConn(client).Send(&LoginPluginResponse{MessageID: 2, Successful: true, Data: RemainingBytes("c")})
+	}()
+	if err := d.Wait(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]loginPluginResponse{
+		"test:a": {{nil, false}},
+		"test:b": {{nil, false}},
+		"test:c": {{[]byte("c"), true}},
+	}
+	if !reflect.DeepEqual(responses, want) {
+		t.Errorf("got %v, want %v", responses, want)
+	}
+
+	// The late responses are ignored
+	done = loginPluginClient(NewConn(client), 1,
+		LoginPluginResponse{MessageID: 0, Successful: true, Data: RemainingBytes("a")},
+		LoginPluginResponse{MessageID: 3, Successful: true, Data: RemainingBytes("d")},
+	)
+	data, understood, err := d.Call(conn, "test:d", nil)
+	if err != nil || !understood || string(data) != "d" {
+		t.Errorf("got %q %v %v", data, understood, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(responses["test:a"]) != 1 {
+		t.Errorf("late response passed: %v", responses["test:a"])
+	}
+
+	// A callback may abort the login on timeout
+	d.Timeout = 50 * time.Millisecond
+	done = loginPluginClient(NewConn(client), 1)
+	failed := errors.New("not answered")
+	err = d.Request(conn, "test:e", nil, func(data []byte, understood bool) error {
+		if !understood {
+			return failed
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Wait(conn); !errors.Is(err, failed) {
+		t.Errorf("got %v, want the error of the callback", err)
+	}
+}
@@ -0,0 +1,701 @@
+package proto
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf16"
+)
+
+// NBT tag types.
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// ErrInvalidNBT is returned when NBT data is malformed, or a value cannot be encoded as NBT.
+var ErrInvalidNBT = errors.New("invalid NBT")
+
+// NBTCompound is the value of a compound tag: named tags of any type.
+type NBTCompound map[string]interface{}
+
+// NBTList is the value of a list tag: unnamed tags of the same type.
+type NBTList []interface{}
+
+// The values of NBT tags are the following Go types:
+//
+//	TagByte      int8 (bool is accepted when encoding)
+//	TagShort     int16
+//	TagInt       int32
+//	TagLong      int64
+//	TagFloat     float32
+//	TagDouble    float64
+//	TagByteArray []byte
+//	TagString    string
+//	TagList      NBTList
+//	TagCompound  NBTCompound
+//	TagIntArray  []int32
+//	TagLongArray []int64
+
+// --- NBTTag ---
+
+// NBTTag is a tag of Named Binary Tag data, as sent by the network protocol
+// from 1.20.2: the type of the root tag followed by its payload, without name.
+// A nil Value is encoded as TagEnd, which means no tag.
+// Decoding is restricted by the MaxNBTDepth and MaxNBTSize Limits.
+// Implements proto.Type interface (Minecraft protocol data type).
+type NBTTag struct {
+	Value interface{}
+}
+
+// ReadFrom reads NBTTag data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (t *NBTTag) ReadFrom(r io.Reader) (n int64, err error) {
+	d := nbtDecoder{r: r, limits: limitsOf(r)}
+	tagType, err := d.readByte()
+	if err != nil {
+		return d.size, err
+	}
+	t.Value = nil
+	if tagType != TagEnd {
+		t.Value, err = d.readPayload(tagType, 0)
+	}
+	return d.size, err
+}
+
+// WriteTo writes NBTTag data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (t NBTTag) WriteTo(w io.Writer) (n int64, err error) {
+	if t.Value == nil {
+		nn, err := w.Write([]byte{TagEnd})
+		return int64(nn), err
+	}
+	tagType, err := nbtTypeOf(t.Value)
+	if err != nil {
+		return 0, err
+	}
+	b, err := appendNBTPayload([]byte{tagType}, t.Value)
+	if err != nil {
+		return 0, err
+	}
+	nn, err := w.Write(b)
+	return int64(nn), err
+}
+
+// --- NamedNBTTag ---
+
+// NamedNBTTag is a tag of Named Binary Tag data, as stored in files and sent
+// by the network protocol before 1.20.2: the type of the root tag, its name
+// and its payload.
+// A nil Value is encoded as TagEnd, without name.
+// Decoding is restricted by the MaxNBTDepth and MaxNBTSize Limits.
+// Implements proto.Type interface (Minecraft protocol data type).
+type NamedNBTTag struct {
+	Name  string
+	Value interface{}
+}
+
+// ReadFrom reads NamedNBTTag data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (t *NamedNBTTag) ReadFrom(r io.Reader) (n int64, err error) {
+	d := nbtDecoder{r: r, limits: limitsOf(r)}
+	tagType, err := d.readByte()
+	if err != nil {
+		return d.size, err
+	}
+	t.Name, t.Value = "", nil
+	if tagType == TagEnd {
+		return d.size, nil
+	}
+	if t.Name, err = d.readString(); err != nil {
+		return d.size, err
+	}
+	t.Value, err = d.readPayload(tagType, 0)
+	return d.size, err
+}
+
+// WriteTo writes NamedNBTTag data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (t NamedNBTTag) WriteTo(w io.Writer) (n int64, err error) {
+	if t.Value == nil {
+		nn, err := w.Write([]byte{TagEnd})
+		return int64(nn), err
+	}
+	tagType, err := nbtTypeOf(t.Value)
+	if err != nil {
+		return 0, err
+	}
+	b, err := appendNBTString([]byte{tagType}, t.Name)
+	if err != nil {
+		return 0, err
+	}
+	if b, err = appendNBTPayload(b, t.Value); err != nil {
+		return 0, err
+	}
+	nn, err := w.Write(b)
+	return int64(nn), err
+}
+
+// --- Decoding ---
+
+// nbtMinSize is the minimum encoded size of the payload of each tag type
+var nbtMinSize = [...]int{
+	TagEnd:       0,
+	TagByte:      1,
+	TagShort:     2,
+	TagInt:       4,
+	TagLong:      8,
+	TagFloat:     4,
+	TagDouble:    8,
+	TagByteArray: 4,
+	TagString:    2,
+	TagList:      5,
+	TagCompound:  1,
+	TagIntArray:  4,
+	TagLongArray: 4,
+}
+
+// nbtDecoder reads NBT data while enforcing the Limits
+type nbtDecoder struct {
+	r      io.Reader
+	limits *Limits
+	// size is the number of bytes read
+	size int64
+	buf  [8]byte
+}
+
+// reserve checks that length elements of elemSize bytes can be read
+// without exceeding MaxNBTSize, before they are allocated
+func (d *nbtDecoder) reserve(length, elemSize int) error {
+	if length < 0 {
+		return &LimitError{Limit: "MaxNBTSize", Value: int64(length), Max: int64(d.limits.MaxNBTSize)}
+	}
+	if size := d.size + int64(length)*int64(elemSize); size > int64(d.limits.MaxNBTSize) {
+		return &LimitError{Limit: "MaxNBTSize", Value: size, Max: int64(d.limits.MaxNBTSize)}
+	}
+	if l, ok := d.r.(interface{ Len() int }); ok && int64(length)*int64(elemSize) > int64(l.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// read reads the next n bytes, n being at most 8
+func (d *nbtDecoder) read(n int) ([]byte, error) {
+	if err := d.reserve(n, 1); err != nil {
+		return nil, err
+	}
+	nn, err := io.ReadFull(d.r, d.buf[:n])
+	d.size += int64(nn)
+	return d.buf[:n], err
+}
+
+func (d *nbtDecoder) readByte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *nbtDecoder) readUint16() (uint16, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (d *nbtDecoder) readUint32() (uint32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (d *nbtDecoder) readUint64() (uint64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// readBytes reads a byte slice of the given length
+func (d *nbtDecoder) readBytes(length int) ([]byte, error) {
+	if err := d.reserve(length, 1); err != nil {
+		return nil, err
+	}
+	b := make([]byte, length)
+	nn, err := io.ReadFull(d.r, b)
+	d.size += int64(nn)
+	return b, err
+}
+
+// readLength reads the Int length of an array or list of elements of elemSize bytes
+func (d *nbtDecoder) readLength(elemSize int) (int, error) {
+	u, err := d.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	length := int(int32(u))
+	return length, d.reserve(length, elemSize)
+}
+
+// readString reads a string in modified UTF-8
+func (d *nbtDecoder) readString() (string, error) {
+	length, err := d.readUint16()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.readBytes(int(length))
+	if err != nil {
+		return "", err
+	}
+	return decodeMUTF8(b)
+}
+
+// readPayload reads the payload of a tag of the given type, nested at the given depth
+func (d *nbtDecoder) readPayload(tagType byte, depth int) (interface{}, error) {
+	switch tagType {
+	case TagByte:
+		b, err := d.readByte()
+		return int8(b), err
+	case TagShort:
+		u, err := d.readUint16()
+		return int16(u), err
+	case TagInt:
+		u, err := d.readUint32()
+		return int32(u), err
+	case TagLong:
+		u, err := d.readUint64()
+		return int64(u), err
+	case TagFloat:
+		u, err := d.readUint32()
+		return math.Float32frombits(u), err
+	case TagDouble:
+		u, err := d.readUint64()
+		return math.Float64frombits(u), err
+	case TagByteArray:
+		length, err := d.readLength(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.readBytes(length)
+	case TagString:
+		return d.readString()
+	case TagIntArray:
+		length, err := d.readLength(4)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readBytes(length * 4)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int32, length)
+		for i := range values {
+			values[i] = int32(binary.BigEndian.Uint32(b[i*4:]))
+		}
+		return values, nil
+	case TagLongArray:
+		length, err := d.readLength(8)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readBytes(length * 8)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int64, length)
+		for i := range values {
+			values[i] = int64(binary.BigEndian.Uint64(b[i*8:]))
+		}
+		return values, nil
+	case TagList:
+		return d.readList(depth + 1)
+	case TagCompound:
+		return d.readCompound(depth + 1)
+	default:
+		return nil, fmt.Errorf("%w: tag type %d", ErrInvalidNBT, tagType)
+	}
+}
+
+// checkDepth checks the nesting depth of a list or compound
+func (d *nbtDecoder) checkDepth(depth int) error {
+	if depth > d.limits.MaxNBTDepth {
+		return &LimitError{Limit: "MaxNBTDepth", Value: int64(depth), Max: int64(d.limits.MaxNBTDepth)}
+	}
+	return nil
+}
+
+func (d *nbtDecoder) readList(depth int) (NBTList, error) {
+	if err := d.checkDepth(depth); err != nil {
+		return nil, err
+	}
+	elemType, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if int(elemType) >= len(nbtMinSize) {
+		return nil, fmt.Errorf("%w: tag type %d", ErrInvalidNBT, elemType)
+	}
+	length, err := d.readLength(nbtMinSize[elemType])
+	if err != nil {
+		return nil, err
+	}
+	if elemType == TagEnd && length > 0 {
+		return nil, fmt.Errorf("%w: list of %d end tags", ErrInvalidNBT, length)
+	}
+
+	list := make(NBTList, length)
+	for i := range list {
+		if list[i], err = d.readPayload(elemType, depth); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (d *nbtDecoder) readCompound(depth int) (NBTCompound, error) {
+	if err := d.checkDepth(depth); err != nil {
+		return nil, err
+	}
+	compound := make(NBTCompound)
+	for {
+		tagType, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if tagType == TagEnd {
+			return compound, nil
+		}
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if compound[name], err = d.readPayload(tagType, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// --- Encoding ---
+
+// nbtTypeOf returns the tag type of the value
+func nbtTypeOf(v interface{}) (byte, error) {
+	switch v.(type) {
+	case int8, bool:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case NBTList:
+		return TagList, nil
+	case NBTCompound:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	default:
+		return 0, fmt.Errorf("%w: unsupported value of type %T", ErrInvalidNBT, v)
+	}
+}
+
+// appendNBTPayload appends the payload of the value
+func appendNBTPayload(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case int8:
+		return append(b, byte(v)), nil
+	case bool:
+		if v {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case int16:
+		return append(b, byte(v>>8), byte(v)), nil
+	case int32:
+		return appendInt32(b, v), nil
+	case int64:
+		return appendInt64(b, v), nil
+	case float32:
+		return appendInt32(b, int32(math.Float32bits(v))), nil
+	case float64:
+		return appendInt64(b, int64(math.Float64bits(v))), nil
+	case []byte:
+		b = appendInt32(b, int32(len(v)))
+		return append(b, v...), nil
+	case string:
+		return appendNBTString(b, v)
+	case []int32:
+		b = appendInt32(b, int32(len(v)))
+		for _, i := range v {
+			b = appendInt32(b, i)
+		}
+		return b, nil
+	case []int64:
+		b = appendInt32(b, int32(len(v)))
+		for _, l := range v {
+			b = appendInt64(b, l)
+		}
+		return b, nil
+	case NBTList:
+		return appendNBTList(b, v)
+	case NBTCompound:
+		return appendNBTCompound(b, v)
+	default:
+		return nil, fmt.Errorf("%w: unsupported value of type %T", ErrInvalidNBT, v)
+	}
+}
+
+func appendNBTList(b []byte, list NBTList) ([]byte, error) {
+	elemType := TagEnd
+	for i, v := range list {
+		t, err := nbtTypeOf(v)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && t != elemType {
+			return nil, fmt.Errorf("%w: list of tags of types %d and %d", ErrInvalidNBT, elemType, t)
+		}
+		elemType = t
+	}
+
+	b = append(b, elemType)
+	b = appendInt32(b, int32(len(list)))
+	var err error
+	for _, v := range list {
+		if b, err = appendNBTPayload(b, v); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendNBTCompound(b []byte, compound NBTCompound) ([]byte, error) {
+	// Sort the names, so that the encoding is deterministic
+	names := make([]string, 0, len(compound))
+	for name := range compound {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := compound[name]
+		t, err := nbtTypeOf(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		b = append(b, t)
+		if b, err = appendNBTString(b, name); err != nil {
+			return nil, err
+		}
+		if b, err = appendNBTPayload(b, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return append(b, TagEnd), nil
+}
+
+// appendNBTString appends a string in modified UTF-8, prefixed with its length
+func appendNBTString(b []byte, s string) ([]byte, error) {
+	start := len(b)
+	b = append(b, 0, 0)
+	b = appendMUTF8(b, s)
+	length := len(b) - start - 2
+	if length > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: string of %d bytes", ErrInvalidNBT, length)
+	}
+	b[start], b[start+1] = byte(length>>8), byte(length)
+	return b, nil
+}
+
+func appendInt32(b []byte, v int32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// --- Modified UTF-8 ---
+
+// appendMUTF8 appends s in the modified UTF-8 of Java: NUL is encoded in
+// two bytes, and supplementary characters as surrogate pairs.
+func appendMUTF8(b []byte, s string) []byte {
+	for _, r := range s {
+		switch {
+		case r != 0 && r < 0x80:
+			b = append(b, byte(r))
+		case r < 0x800:
+			b = append(b, 0xC0|byte(r>>6), 0x80|byte(r)&0x3F)
+		case r < 0x10000:
+			b = append(b, 0xE0|byte(r>>12), 0x80|byte(r>>6)&0x3F, 0x80|byte(r)&0x3F)
+		default:
+			r1, r2 := utf16.EncodeRune(r)
+			b = append(b, 0xE0|byte(r1>>12), 0x80|byte(r1>>6)&0x3F, 0x80|byte(r1)&0x3F)
+			b = append(b, 0xE0|byte(r2>>12), 0x80|byte(r2>>6)&0x3F, 0x80|byte(r2)&0x3F)
+		}
+	}
+	return b
+}
+
+// decodeMUTF8 decodes a string in the modified UTF-8 of Java
+func decodeMUTF8(b []byte) (string, error) {
+	ascii := true
+	for _, c := range b {
+		if c == 0 || c >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(b), nil
+	}
+
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80 && c != 0:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(b) && b[i+1]&0xC0 == 0x80:
+			units = append(units, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(b) && b[i+1]&0xC0 == 0x80 && b[i+2]&0xC0 == 0x80:
+			units = append(units, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		default:
+			return "", fmt.Errorf("%w: malformed modified UTF-8 at byte %d", ErrInvalidNBT, i)
+		}
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+// --- JSON ---
+
+// nbtFromJSON converts JSON data to an NBT value, the way vanilla converts
+// text components: booleans become bytes, integers ints or longs, other
+// numbers doubles. Arrays must be homogeneous, null values are omitted.
+func nbtFromJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return nbtFromJSONValue(v)
+}
+
+func nbtFromJSONValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool, string:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return int32(v), nil
+		}
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), nil
+		}
+		return v, nil
+	case []interface{}:
+		list := make(NBTList, 0, len(v))
+		for _, e := range v {
+			if e == nil {
+				continue
+			}
+			value, err := nbtFromJSONValue(e)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case map[string]interface{}:
+		compound := make(NBTCompound, len(v))
+		for name, e := range v {
+			if e == nil {
+				continue
+			}
+			value, err := nbtFromJSONValue(e)
+			if err != nil {
+				return nil, err
+			}
+			compound[name] = value
+		}
+		return compound, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported JSON value %v", ErrInvalidNBT, v)
+	}
+}
+
+// nbtToJSON returns the JSON of the NBT value of a text component, as sent
+// before 1.20.3. The bytes are booleans, and the compounds with an empty name
+// only, wrapping the elements of lists mixing types, are unwrapped.
+func nbtToJSON(v interface{}) ([]byte, error) {
+	if v == nil {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(nbtToJSONValue(v))
+}
+
+func nbtToJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int8:
+		if v == 0 || v == 1 {
+			return v == 1
+		}
+		return v
+	case []byte:
+		values := make([]int8, len(v))
+		for i, b := range v {
+			values[i] = int8(b)
+		}
+		return values
+	case NBTList:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = nbtToJSONValue(e)
+		}
+		return values
+	case NBTCompound:
+		if e, ok := v[""]; ok && len(v) == 1 {
+			return nbtToJSONValue(e)
+		}
+		values := make(map[string]interface{}, len(v))
+		for name, e := range v {
+			values[name] = nbtToJSONValue(e)
+		}
+		return values
+	default:
+		return v
+	}
+}
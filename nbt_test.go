@@ -0,0 +1,268 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestNBTTagRoundTrip(t *testing.T) {
+	value := NBTCompound{
+		"byte":      int8(-1),
+		"short":     int16(-300),
+		"int":       int32(70000),
+		"long":      int64(-1 << 40),
+		"float":     float32(1.5),
+		"double":    float64(-2.25),
+		"byteArray": []byte{1, 2, 3},
+		"string":    "a\x00é€😀",
+		"list":      NBTList{"a", "b"},
+		"empty":     NBTList{},
+		"nested":    NBTCompound{"list": NBTList{NBTCompound{"x": int32(1)}}},
+		"intArray":  []int32{-1, 2},
+		"longArray": []int64{-1, 2},
+		"\x00é😀key": int8(0),
+	}
+
+	for _, named := range []bool{false, true} {
+		var buf bytes.Buffer
+		var err error
+		if named {
+			_, err = NamedNBTTag{Name: "root", Value: value}.WriteTo(&buf)
+		} else {
+			_, err = NBTTag{Value: value}.WriteTo(&buf)
+		}
+		if err != nil {
+			t.Fatalf("named %v: write: %v", named, err)
+		}
+		size := int64(buf.Len())
+
+		var n int64
+		var got interface{}
+		if named {
+			var tag NamedNBTTag
+			n, err = tag.ReadFrom(&buf)
+			if tag.Name != "root" {
+				t.Errorf("named %v: got name %q", named, tag.Name)
+			}
+			got = tag.Value
+		} else {
+			var tag NBTTag
+			n, err = tag.ReadFrom(&buf)
+			got = tag.Value
+		}
+		if err != nil {
+			t.Fatalf("named %v: read: %v", named, err)
+		}
+		if n != size {
+			t.Errorf("named %v: read %d bytes, written %d", named, n, size)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("named %v: got %#v, want %#v", named, got, value)
+		}
+	}
+}
+
+func TestNBTTagEnd(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := (NBTTag{}).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{TagEnd}) {
+		t.Fatalf("got % X, want 00", buf.Bytes())
+	}
+
+	tag := NBTTag{Value: int8(1)}
+	if _, err := tag.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if tag.Value != nil {
+		t.Errorf("got %#v, want nil", tag.Value)
+	}
+}
+
+func TestNBTBool(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := (NBTTag{Value: true}).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{TagByte, 1}) {
+		t.Errorf("got % X, want 01 01", buf.Bytes())
+	}
+}
+
+func TestMUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		mutf []byte
+	}{
+		{"abc", []byte("abc")},
+		{"\x00", []byte{0xC0, 0x80}},
+		{"é", []byte{0xC3, 0xA9}},
+		{"€", []byte{0xE2, 0x82, 0xAC}},
+		// U+1F600 as the surrogate pair D83D DE00
+		{"😀", []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}},
+	}
+	for _, test := range tests {
+		if got := appendMUTF8(nil, test.s); !bytes.Equal(got, test.mutf) {
+			t.Errorf("appendMUTF8(%q) = % X, want % X", test.s, got, test.mutf)
+		}
+		got, err := decodeMUTF8(test.mutf)
+		if err != nil {
+			t.Errorf("decodeMUTF8(% X): %v", test.mutf, err)
+		} else if got != test.s {
+			t.Errorf("decodeMUTF8(% X) = %q, want %q", test.mutf, got, test.s)
+		}
+	}
+}
+
+func TestMUTF8Malformed(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x00},
+		{0x80},
+		{0xC3},
+		{0xE2, 0x82},
+		{0xC3, 0x41},
+		{0xF0, 0x9F, 0x98, 0x80},
+	} {
+		if _, err := decodeMUTF8(b); !errors.Is(err, ErrInvalidNBT) {
+			t.Errorf("decodeMUTF8(% X): got %v, want ErrInvalidNBT", b, err)
+		}
+	}
+}
+
+func TestNBTTagMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"unknown tag type", []byte{13}, ErrInvalidNBT},
+		{"unknown list type", []byte{TagList, 13, 0, 0, 0, 0}, ErrInvalidNBT},
+		{"list of end tags", []byte{TagList, TagEnd, 0, 0, 0, 1}, ErrInvalidNBT},
+		{"negative list length", []byte{TagList, TagByte, 0xFF, 0xFF, 0xFF, 0xFF}, ErrLimitExceeded},
+		{"negative array length", []byte{TagIntArray, 0x80, 0, 0, 0}, ErrLimitExceeded},
+		{"truncated int", []byte{TagInt, 0, 0}, io.ErrUnexpectedEOF},
+		{"truncated compound", []byte{TagCompound, TagByte, 0, 1, 'a', 1}, io.ErrUnexpectedEOF},
+		{"truncated array", []byte{TagByteArray, 0, 0, 0, 4, 1}, io.ErrUnexpectedEOF},
+		{"malformed string", []byte{TagString, 0, 1, 0x80}, ErrInvalidNBT},
+	}
+	for _, test := range tests {
+		var tag NBTTag
+		_, err := tag.ReadFrom(limitedReader{bytes.NewReader(test.data), &DefaultLimits})
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestNBTTagDepthLimit(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxNBTDepth = 3
+
+	// Nested lists of lists, the last one empty
+	nested := func(depth int) []byte {
+		var b []byte
+		b = append(b, TagList)
+		for i := 1; i < depth; i++ {
+			b = append(b, TagList, 0, 0, 0, 1)
+		}
+		return append(b, TagEnd, 0, 0, 0, 0)
+	}
+
+	var tag NBTTag
+	if _, err := tag.ReadFrom(limitedReader{bytes.NewReader(nested(3)), &limits}); err != nil {
+		t.Errorf("depth 3: %v", err)
+	}
+	_, err := tag.ReadFrom(limitedReader{bytes.NewReader(nested(4)), &limits})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxNBTDepth" {
+		t.Errorf("depth 4: got %v, want MaxNBTDepth exceeded", err)
+	}
+}
+
+func TestNBTTagSizeLimit(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxNBTSize = 16
+
+	var tag NBTTag
+	small := []byte{TagByteArray, 0, 0, 0, 11}
+	small = append(small, make([]byte, 11)...)
+	if _, err := tag.ReadFrom(limitedReader{bytes.NewReader(small), &limits}); err != nil {
+		t.Errorf("16 bytes: %v", err)
+	}
+
+	// The length is checked before the array is allocated
+	large := []byte{TagLongArray, 0x10, 0, 0, 0}
+	_, err := tag.ReadFrom(limitedReader{bytes.NewReader(large), &limits})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxNBTSize" {
+		t.Errorf("large array: got %v, want MaxNBTSize exceeded", err)
+	}
+
+	// A stream of small tags is limited too
+	var buf bytes.Buffer
+	list := make(NBTList, 20)
+	for i := range list {
+		list[i] = int8(i)
+	}
+	if _, err := (NBTTag{Value: list}).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tag.ReadFrom(limitedReader{bytes.NewReader(buf.Bytes()), &limits})
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxNBTSize" {
+		t.Errorf("list: got %v, want MaxNBTSize exceeded", err)
+	}
+}
+
+func TestNBTEncodeInvalid(t *testing.T) {
+	for _, value := range []interface{}{
+		uint8(1),
+		NBTList{int8(1), "a"},
+		NBTCompound{"a": struct{}{}},
+		string(make([]byte, 1<<16)),
+	} {
+		_, err := NBTTag{Value: value}.WriteTo(io.Discard)
+		if !errors.Is(err, ErrInvalidNBT) {
+			t.Errorf("%T: got %v, want ErrInvalidNBT", value, err)
+		}
+	}
+}
+
+func TestNBTJSON(t *testing.T) {
+	value, err := nbtFromJSON([]byte(`{"text":"a","bold":true,"extra":[{"text":"b"}],"n":1,"big":3000000000,"f":0.5,"null":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NBTCompound{
+		"text":  "a",
+		"bold":  true,
+		"extra": NBTList{NBTCompound{"text": "b"}},
+		"n":     int32(1),
+		"big":   int64(3000000000),
+		"f":     float64(0.5),
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Fatalf("got %#v, want %#v", value, want)
+	}
+
+	// Heterogeneous arrays cannot be encoded
+	value, err = nbtFromJSON([]byte(`[1,"a"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (NBTTag{Value: value}).WriteTo(io.Discard); !errors.Is(err, ErrInvalidNBT) {
+		t.Errorf("heterogeneous array: got %v, want ErrInvalidNBT", err)
+	}
+
+	// Bytes 0 and 1 are booleans, and compounds wrapping an unnamed value are unwrapped
+	text, err := nbtToJSON(NBTCompound{"bold": int8(1), "extra": NBTList{NBTCompound{"": "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"bold":true,"extra":["b"]}`; string(text) != want {
+		t.Errorf("got %s, want %s", text, want)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"compress/zlib"
 	"fmt"
 	"io"
+	"net"
+	"reflect"
 	"sync"
 )
 
@@ -20,6 +22,21 @@ type Packet interface {
 type RawPacket struct {
 	ID   int32
 	Data []byte
+
+	// Limits restricts the sizes accepted by Unpack and Unmarshal.
+	// If nil, DefaultLimits are used.
+	Limits *Limits
+	// Strict makes Unmarshal fail with a *TrailingBytesError if data remain
+	// after the last type. Otherwise the remaining data are returned by Tail.
+	Strict bool
+	// Protocol is the protocol version the packet is encoded with, used by the
+	// packets whose layout changed across versions. If zero, these packets use
+	// the layout documented on their type.
+	Protocol int32
+
+	// read is the number of bytes of Data consumed by the last Unmarshal,
+	// reset when Data is replaced by Marshal or Unpack
+	read int
 }
 
 // NewRawPacket creates a new RawPacket
@@ -27,6 +44,14 @@ func NewRawPacket() *RawPacket {
 	return &RawPacket{}
 }
 
+// limits returns the limits that apply to the raw packet
+func (p *RawPacket) limits() *Limits {
+	if p.Limits != nil {
+		return p.Limits
+	}
+	return &DefaultLimits
+}
+
 var bufPool = sync.Pool{
 	New: func() interface{} {
 		return new(bytes.Buffer)
@@ -45,141 +70,193 @@ func (p *RawPacket) Marshal(types ...Type) error {
 	}
 
 	p.Data = buffer.Bytes()
+	p.read = 0
 
 	return nil
 }
 
-// Unmarshal parses the raw packet and store the result in given types
+// Unmarshal parses the raw packet and store the result in given types.
+// A type that cannot be decoded is reported as a *FieldError.
 func (p *RawPacket) Unmarshal(types ...Type) error {
-	reader := bytes.NewReader(p.Data)
-	for _, t := range types {
+	reader := limitedReader{bytes.NewReader(p.Data), p.limits()}
+	for i, t := range types {
+		// The offset of the field, reported if it cannot be decoded
+		offset := len(p.Data) - reader.Len()
 		_, err := t.ReadFrom(reader)
 		if err != nil {
-			return err
+			p.read = offset
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrTruncated
+			}
+			return &FieldError{Index: i, Offset: int64(offset), Err: err}
 		}
 	}
 
+	p.read = len(p.Data) - reader.Len()
+	if p.Strict && reader.Len() > 0 {
+		return &TrailingBytesError{Offset: int64(p.read), Remaining: reader.Len()}
+	}
+
 	return nil
 }
 
-// Pack packs the raw packet to the writer
-func (p *RawPacket) Pack(writer io.Writer, threshold int) error {
-	if threshold >= 0 {
-		return p.packWithCompression(writer, threshold)
+// Tail returns the data left unread by the last Unmarshal, from the field that
+// could not be decoded if it failed. The data are unread before any Unmarshal.
+// In lenient (non-strict) mode, it gives access to fields appended by newer
+// protocol versions.
+func (p *RawPacket) Tail() RemainingBytes {
+	if p.read > len(p.Data) {
+		return nil
 	}
-	return p.packWithoutCompression(writer)
+	return RemainingBytes(p.Data[p.read:])
 }
 
-func (p *RawPacket) packWithoutCompression(writer io.Writer) error {
-
-	buffer := bufPool.Get().(*bytes.Buffer)
-	defer bufPool.Put(buffer)
-	buffer.Reset()
-
-	_, err := VarInt(p.ID).WriteTo(buffer)
+// checkID checks that the raw packet has the ID of the packet in its protocol
+// version, given the IDs of the packet by protocol version, newest first.
+func (p *RawPacket) checkID(packet string, ids []versionedID) error {
+	id, err := packetID(packet, p.Protocol, ids)
 	if err != nil {
 		return err
 	}
+	if p.ID != id {
+		return &PacketIDError{Packet: packet, Expect: id, Get: p.ID}
+	}
+	return nil
+}
 
-	totalLength := int(buffer.Len()) + len(p.Data)
+// unmarshalPacket is Unmarshal for the fields of the given packet.
+// Errors are reported with the names of the packet and of the failing field.
+func (p *RawPacket) unmarshalPacket(packet Packet, types ...Type) error {
+	err := p.Unmarshal(types...)
+	switch err := err.(type) {
+	case *FieldError:
+		err.Packet, err.Field = fieldName(packet, types[err.Index])
+	case *TrailingBytesError:
+		err.Packet, _ = fieldName(packet, nil)
+	}
+	return err
+}
 
-	_, err = VarInt(totalLength).WriteTo(writer)
-	if err != nil {
-		return err
+// fieldWrapper is implemented by the types wrapping a packet field to encode it
+// differently, so that errors are reported with the name of the field.
+type fieldWrapper interface {
+	// field returns the pointer to the wrapped field
+	field() interface{}
+}
+
+// fieldName returns the names of the packet struct and of its field pointed by t.
+func fieldName(packet Packet, t Type) (packetName, field string) {
+	var target interface{} = t
+	if w, ok := t.(fieldWrapper); ok {
+		target = w.field()
 	}
 
-	_, err = writer.Write(buffer.Bytes())
-	if err != nil {
-		return err
+	v := reflect.ValueOf(packet).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.CanAddr() && f.Addr().Interface() == target {
+			return v.Type().Name(), v.Type().Field(i).Name
+		}
 	}
+	return v.Type().Name(), ""
+}
+
+// Pack packs the raw packet to the writer
+func (p *RawPacket) Pack(writer io.Writer, threshold int) error {
+	buffer := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buffer)
+	buffer.Reset()
 
-	_, err = writer.Write(p.Data)
+	buffers, err := p.buffers(threshold, buffer)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = buffers.WriteTo(writer)
+	return err
 }
 
-func (p *RawPacket) packWithCompression(writer io.Writer, threshold int) error {
-	buffer := bufPool.Get().(*bytes.Buffer)
-	defer bufPool.Put(buffer)
-	buffer.Reset()
+// Buffers returns the packed raw packet as a sequence of buffers that can be
+// written with a single vectored write (writev) by net.Buffers.WriteTo.
+// The returned buffers share memory with p.Data, which must not be modified
+// until they have been written.
+func (p *RawPacket) Buffers(threshold int) (net.Buffers, error) {
+	return p.buffers(threshold, new(bytes.Buffer))
+}
 
-	if len(p.Data) < threshold {
+// buffers builds the packed raw packet.
+// The compressed body, if any, is written to scratch.
+func (p *RawPacket) buffers(threshold int, scratch *bytes.Buffer) (net.Buffers, error) {
+	if threshold >= 0 {
+		return p.buffersWithCompression(threshold, scratch)
+	}
+	return p.buffersWithoutCompression(), nil
+}
 
-		_, err := VarInt(0).WriteTo(buffer)
-		if err != nil {
-			return err
-		}
-		_, err = VarInt(p.ID).WriteTo(buffer)
-		if err != nil {
-			return err
-		}
-		_, err = buffer.Write(p.Data)
-		if err != nil {
-			return err
-		}
-		// Packet Length
-		_, err = VarInt(buffer.Len()).WriteTo(writer)
-		if err != nil {
-			return err
-		}
-		// Data Length + Packet ID + Data
-		_, err = buffer.WriteTo(writer)
-		if err != nil {
-			return err
-		}
+func (p *RawPacket) buffersWithoutCompression() net.Buffers {
+	var id [MaxVarIntLen]byte
+	idBytes := appendVarInt(id[:0], p.ID)
 
-	} else {
-		zlibWriter := zlib.NewWriter(buffer)
+	// Packet Length + Packet ID
+	header := make([]byte, 0, 2*MaxVarIntLen)
+	header = appendVarInt(header, int32(len(idBytes)+len(p.Data)))
+	header = append(header, idBytes...)
 
-		idLength, err := VarInt(p.ID).WriteTo(zlibWriter)
-		if err != nil {
-			return err
-		}
+	if len(p.Data) == 0 {
+		// Some writers, like net.Pipe, do not skip empty writes
+		return net.Buffers{header}
+	}
+	return net.Buffers{header, p.Data}
+}
 
-		dataLength, err := zlibWriter.Write(p.Data)
-		if err != nil {
-			return err
-		}
+func (p *RawPacket) buffersWithCompression(threshold int, scratch *bytes.Buffer) (net.Buffers, error) {
+	var id [MaxVarIntLen]byte
+	idBytes := appendVarInt(id[:0], p.ID)
 
-		err = zlibWriter.Close()
-		if err != nil {
-			return err
+	if len(p.Data) < threshold {
+		// Packet Length + Data Length (0) + Packet ID
+		header := make([]byte, 0, 3*MaxVarIntLen)
+		header = appendVarInt(header, int32(1+len(idBytes)+len(p.Data)))
+		header = appendVarInt(header, 0)
+		header = append(header, idBytes...)
+
+		if len(p.Data) == 0 {
+			return net.Buffers{header}, nil
 		}
+		return net.Buffers{header, p.Data}, nil
+	}
 
-		totalDataLength := bufPool.Get().(*bytes.Buffer)
-		defer bufPool.Put(&dataLength)
-		totalDataLength.Reset()
+	zlibWriter := zlib.NewWriter(scratch)
 
-		totalDataLengthLength, err := VarInt(int(idLength) + dataLength).WriteTo(totalDataLength)
-		if err != nil {
-			return err
-		}
+	_, err := zlibWriter.Write(idBytes)
+	if err != nil {
+		return nil, err
+	}
 
-		// Packet Length
-		_, err = VarInt(int(totalDataLengthLength) + buffer.Len()).WriteTo(writer)
-		if err != nil {
-			return err
-		}
-		// Data Length
-		_, err = totalDataLength.WriteTo(writer)
-		if err != nil {
-			return err
-		}
-		// PacketID + Data
-		_, err = buffer.WriteTo(writer)
-		if err != nil {
-			return err
-		}
+	_, err = zlibWriter.Write(p.Data)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	err = zlibWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var dataLength [MaxVarIntLen]byte
+	dataLengthBytes := appendVarInt(dataLength[:0], int32(len(idBytes)+len(p.Data)))
+
+	// Packet Length + Data Length
+	header := make([]byte, 0, 2*MaxVarIntLen)
+	header = appendVarInt(header, int32(len(dataLengthBytes)+scratch.Len()))
+	header = append(header, dataLengthBytes...)
+
+	// Packet ID + Data (compressed)
+	return net.Buffers{header, scratch.Bytes()}, nil
 }
 
 // Unpack unpacks the raw packet from the reader
 func (p *RawPacket) Unpack(reader io.Reader, threshold int) error {
+	p.read = 0
 	if threshold >= 0 {
 		return p.unpackWithCompression(reader, threshold)
 	}
@@ -193,6 +270,11 @@ func (p *RawPacket) unpackWithoutCompression(reader io.Reader) error {
 		return err
 	}
 
+	err = checkFrameLength(length, p.limits())
+	if err != nil {
+		return err
+	}
+
 	var id VarInt
 	idLength, err := id.ReadFrom(reader)
 	if err != nil {
@@ -201,6 +283,9 @@ func (p *RawPacket) unpackWithoutCompression(reader io.Reader) error {
 	p.ID = int32(id)
 
 	dataLength := int(length) - int(idLength)
+	if dataLength < 0 {
+		return io.ErrUnexpectedEOF
+	}
 
 	if cap(p.Data) < dataLength {
 		p.Data = make([]byte, dataLength)
@@ -223,6 +308,11 @@ func (p *RawPacket) unpackWithCompression(reader io.Reader, threshold int) error
 		return err
 	}
 
+	err = checkFrameLength(length, p.limits())
+	if err != nil {
+		return err
+	}
+
 	buffer := bufPool.Get().(*bytes.Buffer)
 	defer bufPool.Put(buffer)
 	buffer.Reset()
@@ -231,7 +321,33 @@ func (p *RawPacket) unpackWithCompression(reader io.Reader, threshold int) error
 	if err != nil {
 		return err
 	}
-	reader = bytes.NewReader(buffer.Bytes())
+
+	return p.decodeCompressed(buffer.Bytes(), threshold)
+}
+
+// decodeUncompressed decodes the body of an uncompressed frame,
+// that is the frame without its leading Packet Length.
+func (p *RawPacket) decodeUncompressed(frame []byte) error {
+	p.read = 0
+	reader := bytes.NewReader(frame)
+
+	var id VarInt
+	_, err := id.ReadFrom(reader)
+	if err != nil {
+		return err
+	}
+	p.ID = int32(id)
+
+	p.Data = append(p.Data[:0], frame[len(frame)-reader.Len():]...)
+
+	return nil
+}
+
+// decodeCompressed decodes the body of a compressed frame,
+// that is the frame without its leading Packet Length.
+func (p *RawPacket) decodeCompressed(frame []byte, threshold int) error {
+	p.read = 0
+	var reader io.Reader = bytes.NewReader(frame)
 
 	var dataLength VarInt
 	dataLengthLength, err := dataLength.ReadFrom(reader)
@@ -240,19 +356,19 @@ func (p *RawPacket) unpackWithCompression(reader io.Reader, threshold int) error
 	}
 
 	var id VarInt
-	if dataLength != 0 {
+	compressed := dataLength != 0
+	if compressed {
 		if int(dataLength) < threshold {
-			return fmt.Errorf("compressed packet error: size of %d is below threshold of %d", dataLength, threshold)
+			return &CompressionError{fmt.Errorf("size of %d is below threshold of %d", dataLength, threshold)}
 		}
 
-		const maxDataLength = 2097152
-		if dataLength > maxDataLength {
-			return fmt.Errorf("compressed packet error: size of %d is larger than protocol maximum of %d", dataLength, maxDataLength)
+		if maxDataLength := p.limits().MaxDataLength; int(dataLength) > maxDataLength {
+			return &LimitError{Limit: "MaxDataLength", Value: int64(dataLength), Max: int64(maxDataLength)}
 		}
 
 		zlibReader, err := zlib.NewReader(reader)
 		if err != nil {
-			return err
+			return &CompressionError{err}
 		}
 		defer zlibReader.Close()
 
@@ -260,17 +376,20 @@ func (p *RawPacket) unpackWithCompression(reader io.Reader, threshold int) error
 
 		idLength, err := id.ReadFrom(reader)
 		if err != nil {
-			return err
+			return &CompressionError{err}
 		}
 
 		dataLength -= VarInt(idLength)
+		if dataLength < 0 {
+			return &CompressionError{fmt.Errorf("size of %d is too small for packet ID", dataLength+VarInt(idLength))}
+		}
 	} else {
 		idLength, err := id.ReadFrom(reader)
 		if err != nil {
 			return err
 		}
 
-		dataLength = VarInt(int64(length) - dataLengthLength - idLength)
+		dataLength = VarInt(int64(len(frame)) - dataLengthLength - idLength)
 	}
 
 	if cap(p.Data) < int(dataLength) {
@@ -283,8 +402,19 @@ func (p *RawPacket) unpackWithCompression(reader io.Reader, threshold int) error
 
 	_, err = io.ReadFull(reader, p.Data)
 	if err != nil {
+		if compressed {
+			err = &CompressionError{err}
+		}
 		return err
 	}
 
 	return nil
 }
+
+// checkFrameLength checks the Packet Length of a frame against the limits
+func checkFrameLength(length VarInt, limits *Limits) error {
+	if length < 0 || int(length) > limits.MaxFrameLength {
+		return &LimitError{Limit: "MaxFrameLength", Value: int64(length), Max: int64(limits.MaxFrameLength)}
+	}
+	return nil
+}
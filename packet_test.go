@@ -0,0 +1,99 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestUnmarshalTrailingBytes(t *testing.T) {
+	p := &RawPacket{Data: []byte{0x01, 0x02, 'a', 'b', 0x63, 0xDD}}
+	if tail := p.Tail(); !bytes.Equal(tail, p.Data) {
+		t.Errorf("before Unmarshal: got tail % X", tail)
+	}
+
+	// In lenient mode, the remaining data are exposed
+	var version VarInt
+	var address String
+	if err := p.Unmarshal(&version, &address); err != nil {
+		t.Fatal(err)
+	}
+	if tail := p.Tail(); !bytes.Equal(tail, []byte{0x63, 0xDD}) {
+		t.Errorf("lenient: got tail % X, want 63 DD", tail)
+	}
+
+	// In strict mode, they are an error
+	p.Strict = true
+	err := p.Unmarshal(&version, &address)
+	var trailingErr *TrailingBytesError
+	if !errors.As(err, &trailingErr) || trailingErr.Offset != 4 || trailingErr.Remaining != 2 {
+		t.Fatalf("strict: got %v, want 2 trailing bytes at offset 4", err)
+	}
+	if tail := p.Tail(); !bytes.Equal(tail, []byte{0x63, 0xDD}) {
+		t.Errorf("strict: got tail % X, want 63 DD", tail)
+	}
+	var port UnsignedShort
+	if err := p.Unmarshal(&version, &address, &port); err != nil || port != 0x63DD || len(p.Tail()) != 0 {
+		t.Errorf("all read: got %v, port %d, tail % X", err, port, p.Tail())
+	}
+
+	// A failed Unmarshal leaves the data from the failing field
+	var next VarInt
+	if err := p.Unmarshal(&version, &address, &port, &next); !errors.Is(err, ErrTruncated) {
+		t.Errorf("got %v, want ErrTruncated", err)
+	}
+	if err := p.Unmarshal(&address, &address); !errors.Is(err, ErrTruncated) || !bytes.Equal(p.Tail(), p.Data[2:]) {
+		t.Errorf("got %v, tail % X", err, p.Tail())
+	}
+
+	// Marshal replaces the data
+	one := VarInt(1)
+	if err := p.Marshal(&one); err != nil || !bytes.Equal(p.Tail(), []byte{0x01}) {
+		t.Errorf("after Marshal: got %v, tail % X", err, p.Tail())
+	}
+}
+
+func TestStrictPacket(t *testing.T) {
+	// A 1.19 LoginStart read with the layout of 1.16
+	raw := &RawPacket{Protocol: Protocol1_19}
+	if err := (&LoginStart{Name: "Steve"}).ToRaw(raw); err != nil {
+		t.Fatal(err)
+	}
+	raw.Protocol = Protocol1_16
+	var ls LoginStart
+	if err := ls.FromRaw(raw); err != nil || ls.Name != "Steve" {
+		t.Errorf("lenient: got %v, name %q", err, ls.Name)
+	}
+	if tail := raw.Tail(); !bytes.Equal(tail, []byte{0}) {
+		t.Errorf("lenient: got tail % X, want 00", tail)
+	}
+
+	raw.Strict = true
+	err := ls.FromRaw(raw)
+	var trailingErr *TrailingBytesError
+	if !errors.As(err, &trailingErr) || trailingErr.Packet != "LoginStart" || trailingErr.Offset != 6 || trailingErr.Remaining != 1 {
+		t.Errorf("strict: got %v, want 1 trailing byte of LoginStart at offset 6", err)
+	}
+}
+
+func TestConnStrict(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		c := NewConn(client)
+		for i := 0; i < 2; i++ {
+			c.WritePacket(&RawPacket{ID: Request_ID, Data: []byte{0x00}})
+		}
+	}()
+
+	c := NewConn(server)
+	if err := c.Receive(&Request{}); err != nil {
+		t.Errorf("lenient: %v", err)
+	}
+	c.Strict = true
+	if err := c.Receive(&Request{}); !errors.Is(err, ErrTrailingBytes) {
+		t.Errorf("strict: got %v, want ErrTrailingBytes", err)
+	}
+}
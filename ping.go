@@ -0,0 +1,286 @@
+package proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultPort is the default port of Minecraft servers.
+const DefaultPort = 25565
+
+// pingProtocolVersion is the protocol version sent by default when pinging,
+// -1 meaning that the client does not target a particular version.
+const pingProtocolVersion = -1
+
+// legacyPingProtocolVersion is the protocol version of 1.6.4, sent in legacy pings.
+const legacyPingProtocolVersion = 78
+
+// SRVResolver resolves SRV records. It is implemented by *net.Resolver.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// ContextDialer dials network connections. It is implemented by *net.Dialer.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ErrUnexpectedPong is returned when a server answers a Ping with another payload.
+var ErrUnexpectedPong = errors.New("unexpected pong payload")
+
+// --- Pinger ---
+
+// Pinger gets the status of servers through the Server List Ping.
+// The zero value is ready to use.
+type Pinger struct {
+	// Resolver resolves the _minecraft._tcp SRV records of addresses without port.
+	// If nil, net.DefaultResolver is used.
+	Resolver SRVResolver
+	// Dialer dials the connections. If nil, a zero net.Dialer is used.
+	Dialer ContextDialer
+	// ProtocolVersion is the protocol version sent in the Handshake.
+	// If zero, -1 is sent, which servers answer with their own version.
+	ProtocolVersion int32
+	// DisableLegacyFallback disables the legacy ping sent when a server
+	// closes the connection on a modern Handshake.
+	DisableLegacyFallback bool
+}
+
+// PingResult is the result of a ping.
+type PingResult struct {
+	Status *StatusResponse
+	// Latency is the round-trip time of the Ping/Pong exchange,
+	// or of the whole exchange for legacy pings.
+	Latency time.Duration
+	// Address is the address the ping was sent to, after SRV resolution.
+	Address string
+	// Legacy reports whether the status was obtained through a legacy ping.
+	Legacy bool
+}
+
+// PingServer gets the status of the server at the given address with a zero Pinger.
+func PingServer(ctx context.Context, address string) (*PingResult, error) {
+	var p Pinger
+	return p.Ping(ctx, address)
+}
+
+// Ping gets the status of the server at the given address.
+// The address is a host with an optional port. Without port, the
+// _minecraft._tcp SRV record of the host is used if any, or the default port.
+func (p *Pinger) Ping(ctx context.Context, address string) (*PingResult, error) {
+	host, port, err := p.Resolve(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like the vanilla client, the Handshake carries the host of the address
+	// rather than the SRV target, which virtual hosts and proxies route on
+	serverAddress := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		serverAddress = h
+	}
+
+	result, err := p.ping(ctx, host, port, serverAddress)
+	if err != nil && !p.DisableLegacyFallback && ctx.Err() == nil && isClosedByPeer(err) {
+		result, err = p.pingLegacy(ctx, host, port, serverAddress)
+	}
+	return result, err
+}
+
+// Resolve resolves the host and port to connect to for the given address.
+func (p *Pinger) Resolve(ctx context.Context, address string) (host string, port int, err error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err == nil {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 0 || port > 0xFFFF {
+			return "", 0, fmt.Errorf("invalid port in address %q", address)
+		}
+		return host, port, nil
+	}
+
+	host = address
+	if net.ParseIP(host) != nil {
+		return host, DefaultPort, nil
+	}
+
+	var resolver SRVResolver = net.DefaultResolver
+	if p.Resolver != nil {
+		resolver = p.Resolver
+	}
+	_, addrs, err := resolver.LookupSRV(ctx, "minecraft", "tcp", host)
+	if err != nil || len(addrs) == 0 {
+		// Like the vanilla client, fall back to the default port
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		return host, DefaultPort, nil
+	}
+
+	target := addrs[0].Target
+	if len(target) > 1 && target[len(target)-1] == '.' {
+		target = target[:len(target)-1]
+	}
+	return target, int(addrs[0].Port), nil
+}
+
+// dial connects to the server and applies the context to the connection
+func (p *Pinger) dial(ctx context.Context, host string, port int) (*Conn, func(), error) {
+	var dialer ContextDialer = &net.Dialer{}
+	if p.Dialer != nil {
+		dialer = p.Dialer
+	}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewConn(netConn), watchContext(ctx, netConn), nil
+}
+
+// watchContext applies the deadline of the context to the connection, and
+// closes it when the context is done.
+// The returned function stops watching the context and closes the connection.
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		conn.Close()
+	}
+}
+
+func (p *Pinger) ping(ctx context.Context, host string, port int, serverAddress string) (*PingResult, error) {
+	conn, stop, err := p.dial(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	protocol := p.ProtocolVersion
+	if protocol == 0 {
+		protocol = pingProtocolVersion
+	}
+
+	result, err := requestStatus(conn, &Handshake{
+		ProtocolVersion: VarInt(protocol),
+		ServerAddress:   String(serverAddress),
+		ServerPort:      UnsignedShort(port),
+		NextState:       StateStatus,
+	})
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	result.Address = net.JoinHostPort(host, strconv.Itoa(port))
+	return result, nil
+}
+
+// requestStatus runs the status exchange on the connection
+func requestStatus(conn *Conn, handshake *Handshake) (*PingResult, error) {
+	if err := conn.Send(handshake); err != nil {
+		return nil, err
+	}
+	if err := conn.Send(&Request{}); err != nil {
+		return nil, err
+	}
+
+	var response Response
+	if err := conn.Receive(&response); err != nil {
+		return nil, err
+	}
+	status := response.Status
+	if status == nil {
+		// FromRaw leaves the status nil if it is invalid: get the error
+		_, err := response.DecodeStatus()
+		return nil, err
+	}
+
+	payload := time.Now().UnixNano() / int64(time.Millisecond)
+	start := time.Now()
+	if err := conn.Send(&Ping{Payload: Long(payload)}); err != nil {
+		return nil, err
+	}
+
+	var pong Pong
+	if err := conn.Receive(&pong); err != nil {
+		return nil, err
+	}
+	latency := time.Since(start)
+
+	if int64(pong.Payload) != payload {
+		return nil, ErrUnexpectedPong
+	}
+
+	return &PingResult{Status: status, Latency: latency}, nil
+}
+
+func (p *Pinger) pingLegacy(ctx context.Context, host string, port int, serverAddress string) (*PingResult, error) {
+	conn, stop, err := p.dial(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	start := time.Now()
+	legacy, err := conn.RequestLegacyStatus(&LegacyPing{
+		Variant:         LegacyPing16,
+		ProtocolVersion: legacyPingProtocolVersion,
+		Hostname:        serverAddress,
+		Port:            port,
+	})
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return &PingResult{
+		Status:  legacy.StatusResponse(),
+		Latency: time.Since(start),
+		Address: net.JoinHostPort(host, strconv.Itoa(port)),
+		Legacy:  true,
+	}, nil
+}
+
+// StatusResponse converts the legacy status to a StatusResponse.
+func (s *LegacyStatus) StatusResponse() *StatusResponse {
+	return &StatusResponse{
+		Version:     StatusVersion{Name: s.Version, Protocol: s.ProtocolVersion},
+		Players:     &StatusPlayers{Max: s.Max, Online: s.Online},
+		Description: TextComponent{Text: s.MOTD},
+	}
+}
+
+// isClosedByPeer reports whether the error is caused by the peer closing the connection
+func isClosedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, ErrTruncated) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// contextError returns the error of the context if it is done, err otherwise
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	// The deadline of the connection may expire just before the context
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return err
+}
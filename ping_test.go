@@ -0,0 +1,248 @@
+package proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testResolver answers SRV lookups from a map, failing for unknown names
+type testResolver map[string]*net.SRV
+
+func (r testResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if srv, ok := r[name]; ok {
+		return "_" + service + "._" + proto + "." + name, []*net.SRV{srv}, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// testDialer serves each dialed connection with the next function, recording
+// the addresses dialed
+type testDialer struct {
+	mu        sync.Mutex
+	servers   []func(conn *Conn)
+	addresses []string
+}
+
+func (d *testDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addresses = append(d.addresses, address)
+	if len(d.servers) == 0 {
+		return nil, errors.New("connection refused")
+	}
+	serve := d.servers[0]
+	d.servers = d.servers[1:]
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		serve(NewConn(server))
+	}()
+	return client, nil
+}
+
+func TestPingerResolve(t *testing.T) {
+	p := Pinger{Resolver: testResolver{
+		"example.com": {Target: "mc.example.net.", Port: 25600},
+	}}
+	tests := []struct {
+		address string
+		host    string
+		port    int
+	}{
+		{"example.com", "mc.example.net", 25600},
+		{"example.com:25570", "example.com", 25570},
+		{"unknown.com", "unknown.com", DefaultPort},
+		{"127.0.0.1", "127.0.0.1", DefaultPort},
+		{"[::1]:25570", "::1", 25570},
+	}
+	for _, test := range tests {
+		host, port, err := p.Resolve(context.Background(), test.address)
+		if err != nil {
+			t.Errorf("%s: %v", test.address, err)
+		} else if host != test.host || port != test.port {
+			t.Errorf("%s: got %s %d, want %s %d", test.address, host, port, test.host, test.port)
+		}
+	}
+
+	if _, _, err := p.Resolve(context.Background(), "example.com:65536"); err == nil {
+		t.Error("invalid port: no error")
+	}
+}
+
+// serveStatus answers a status exchange, checking the Handshake
+func serveStatus(t *testing.T, status *StatusResponse, host string, port int) func(conn *Conn) {
+	return func(conn *Conn) {
+		var handshake Handshake
+		if err := conn.Receive(&handshake); err != nil {
+			t.Errorf("Handshake: %v", err)
+			return
+		}
+		if handshake.ServerAddress != String(host) || handshake.ServerPort != UnsignedShort(port) ||
+			handshake.ProtocolVersion != pingProtocolVersion || handshake.NextState != StateStatus {
+			t.Errorf("got %+v, want the address %s:%d", handshake, host, port)
+		}
+		if err := conn.Receive(&Request{}); err != nil {
+			t.Errorf("Request: %v", err)
+			return
+		}
+		if err := conn.Send(&Response{Status: status}); err != nil {
+			t.Errorf("Response: %v", err)
+			return
+		}
+		var ping Ping
+		if err := conn.Receive(&ping); err != nil {
+			t.Errorf("Ping: %v", err)
+			return
+		}
+		if err := conn.Send(&Pong{Payload: ping.Payload}); err != nil {
+			t.Errorf("Pong: %v", err)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	status := &StatusResponse{
+		Version:     StatusVersion{Name: "1.21", Protocol: int(Protocol1_21)},
+		Players:     &StatusPlayers{Max: 20, Online: 3},
+		Description: TextComponent{Text: "A Minecraft Server"},
+	}
+	dialer := &testDialer{servers: []func(*Conn){serveStatus(t, status, "example.com", 25600)}}
+	p := Pinger{
+		Resolver: testResolver{"example.com": {Target: "mc.example.net.", Port: 25600}},
+		Dialer:   dialer,
+	}
+
+	result, err := p.Ping(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Legacy || result.Address != "mc.example.net:25600" {
+		t.Errorf("got address %s, legacy %v, want mc.example.net:25600", result.Address, result.Legacy)
+	}
+	if result.Status.Version != status.Version || result.Status.Players.Online != 3 ||
+		result.Status.Description.Text != status.Description.Text {
+		t.Errorf("got %+v, want %+v", result.Status, status)
+	}
+	if len(dialer.addresses) != 1 || dialer.addresses[0] != "mc.example.net:25600" {
+		t.Errorf("dialed %v, want mc.example.net:25600", dialer.addresses)
+	}
+}
+
+func TestPingInvalidStatus(t *testing.T) {
+	dialer := &testDialer{servers: []func(*Conn){func(conn *Conn) {
+		var handshake Handshake
+		conn.Receive(&handshake)
+		conn.Receive(&Request{})
+		conn.Send(&Response{JSONResponse: "{"})
+	}}}
+	p := Pinger{Dialer: dialer}
+
+	_, err := p.Ping(context.Background(), "127.0.0.1:25565")
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "JSONResponse" {
+		t.Errorf("got %v, want a *FieldError on JSONResponse", err)
+	}
+}
+
+func TestPingUnexpectedPong(t *testing.T) {
+	dialer := &testDialer{servers: []func(*Conn){func(conn *Conn) {
+		var handshake Handshake
+		var ping Ping
+		conn.Receive(&handshake)
+		conn.Receive(&Request{})
+		conn.Send(&Response{Status: &StatusResponse{}})
+		conn.Receive(&ping)
+		conn.Send(&Pong{Payload: ping.Payload + 1})
+	}}}
+	p := Pinger{Dialer: dialer}
+
+	if _, err := p.Ping(context.Background(), "127.0.0.1:25565"); !errors.Is(err, ErrUnexpectedPong) {
+		t.Errorf("got %v, want ErrUnexpectedPong", err)
+	}
+}
+
+func TestPingLegacyFallback(t *testing.T) {
+	legacy := &LegacyStatus{ProtocolVersion: 78, Version: "1.6.4", MOTD: "A Minecraft Server", Online: 3, Max: 20}
+	// The Request is read too, as it would be buffered by the kernel over TCP
+	closeOnHandshake := func(conn *Conn) {
+		var handshake Handshake
+		conn.Receive(&handshake)
+		conn.Receive(&Request{})
+	}
+	serveLegacy := func(conn *Conn) {
+		ping, err := conn.ReadLegacyPing()
+		if err != nil {
+			t.Errorf("legacy ping: %v", err)
+			return
+		}
+		if ping.Variant != LegacyPing16 || ping.Hostname != "example.com" || ping.Port != 25600 {
+			t.Errorf("got %+v, want a 1.6 ping to example.com:25600", ping)
+		}
+		if err := conn.WriteLegacyStatus(legacy, ping.Variant); err != nil {
+			t.Errorf("legacy status: %v", err)
+		}
+	}
+
+	dialer := &testDialer{servers: []func(*Conn){closeOnHandshake, serveLegacy}}
+	p := Pinger{
+		Resolver: testResolver{"example.com": {Target: "mc.example.net.", Port: 25600}},
+		Dialer:   dialer,
+	}
+	result, err := p.Ping(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Legacy || result.Address != "mc.example.net:25600" {
+		t.Errorf("got address %s, legacy %v, want a legacy ping to mc.example.net:25600", result.Address, result.Legacy)
+	}
+	if result.Status.Version.Name != "1.6.4" || result.Status.Description.Text != legacy.MOTD ||
+		result.Status.Players.Online != 3 || result.Status.Players.Max != 20 {
+		t.Errorf("got %+v, want %+v", result.Status, legacy)
+	}
+
+	// The fallback may be disabled
+	dialer = &testDialer{servers: []func(*Conn){closeOnHandshake, serveLegacy}}
+	p = Pinger{Dialer: dialer, DisableLegacyFallback: true}
+	if _, err := p.Ping(context.Background(), "127.0.0.1:25565"); err == nil {
+		t.Error("fallback disabled: no error")
+	}
+	if len(dialer.addresses) != 1 {
+		t.Errorf("fallback disabled: dialed %d times", len(dialer.addresses))
+	}
+}
+
+func TestPingDeadline(t *testing.T) {
+	unresponsive := make(chan struct{})
+	defer close(unresponsive)
+	dialer := &testDialer{servers: []func(*Conn){
+		func(conn *Conn) { <-unresponsive },
+		func(conn *Conn) { <-unresponsive },
+	}}
+	p := Pinger{Dialer: dialer}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := p.Ping(ctx, "127.0.0.1:25565")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+	if len(dialer.addresses) != 1 {
+		t.Errorf("dialed %d times, want no legacy fallback", len(dialer.addresses))
+	}
+
+	// The connection is closed when the context is canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := p.Ping(ctx, "127.0.0.1:25565"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,181 @@
+package proto
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"io"
+)
+
+// --- ProfileProperty ---
+
+// ProfileProperty is a property of a player profile, such as its skin textures.
+type ProfileProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Signature is the Yggdrasil signature of the value, if any.
+	Signature string `json:"signature,omitempty"`
+}
+
+// --- ProfileProperties ---
+
+// ProfileProperties is a length-prefixed array of profile properties.
+// Each property is encoded as its name, its value and an optional signature.
+// Implements proto.Type interface (Minecraft protocol data type).
+type ProfileProperties []ProfileProperty
+
+// ReadFrom reads ProfileProperties data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (ps *ProfileProperties) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	// A property is at least 3 bytes long: two empty strings and a boolean
+	if err := checkArrayLength(r, count, 3); err != nil {
+		return n, err
+	}
+
+	properties := make(ProfileProperties, count)
+	for i := range properties {
+		var name, value, signature String
+		var signed Boolean
+
+		nn, err := readTypes(r, &name, &value, &signed)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		if signed {
+			nn, err := signature.ReadFrom(r)
+			n += nn
+			if err != nil {
+				return n, err
+			}
+		}
+
+		properties[i] = ProfileProperty{Name: string(name), Value: string(value), Signature: string(signature)}
+	}
+
+	*ps = properties
+	return n, nil
+}
+
+// WriteTo writes ProfileProperties data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (ps ProfileProperties) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(ps)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+
+	for _, p := range ps {
+		signed := Boolean(p.Signature != "")
+		nn, err := writeTypes(w, String(p.Name), String(p.Value), signed)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		if signed {
+			nn, err := String(p.Signature).WriteTo(w)
+			n += nn
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// --- PlayerKey ---
+
+// PlayerKey is the public key a player signs its chat messages with,
+// along with its expiry and the signature of Mojang.
+// Implements proto.Type interface (Minecraft protocol data type).
+type PlayerKey struct {
+	// ExpiresAt is the expiry time, in milliseconds since the Unix epoch.
+	ExpiresAt Long
+	// PublicKey is the DER-encoded RSA public key.
+	PublicKey ByteArray
+	// Signature is the signature of the key by Mojang.
+	Signature ByteArray
+}
+
+// ReadFrom reads PlayerKey data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (k *PlayerKey) ReadFrom(r io.Reader) (n int64, err error) {
+	return readTypes(r, &k.ExpiresAt, &k.PublicKey, &k.Signature)
+}
+
+// WriteTo writes PlayerKey data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (k PlayerKey) WriteTo(w io.Writer) (n int64, err error) {
+	return writeTypes(w, k.ExpiresAt, k.PublicKey, k.Signature)
+}
+
+// Verify checks the SHA256withRSA signature of the data by the private key of the player.
+func (k *PlayerKey) Verify(data, signature []byte) error {
+	key, err := x509.ParsePKIXPublicKey(k.PublicKey)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("player key: not a RSA key")
+	}
+	digest := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+}
+
+// PlayerKeyRevision is the revision of the player keys, which depends on the
+// protocol version of the client.
+type PlayerKeyRevision int
+
+// Revisions of the player keys.
+const (
+	// PlayerKeyV1 is the revision of the keys of the 1.19 clients.
+	PlayerKeyV1 PlayerKeyRevision = 1
+	// PlayerKeyV2 is the revision of the keys from 1.19.1, whose Mojang
+	// signature covers the UUID of the player.
+	PlayerKeyV2 PlayerKeyRevision = 2
+)
+
+// PlayerKeyRevisionOf returns the revision of the keys of the clients of the
+// protocol version, or zero before 1.19, when the clients had no key.
+func PlayerKeyRevisionOf(protocol int32) PlayerKeyRevision {
+	switch {
+	case protocol >= Protocol1_19_1:
+		return PlayerKeyV2
+	case protocol >= Protocol1_19:
+		return PlayerKeyV1
+	default:
+		return 0
+	}
+}
+
+// --- GameProfile ---
+
+// GameProfile is the profile of a player, as returned by the session server.
+type GameProfile struct {
+	ID         UUID              `json:"id"`
+	Name       string            `json:"name"`
+	Properties []ProfileProperty `json:"properties,omitempty"`
+}
+
+// OfflineUUID returns the UUID of the player with the given name on offline-mode
+// servers: the version 3 UUID of "OfflinePlayer:" followed by the name.
+func OfflineUUID(name string) UUID {
+	id := UUID(md5.Sum([]byte("OfflinePlayer:" + name)))
+	id[6] = id[6]&0x0F | 0x30
+	id[8] = id[8]&0x3F | 0x80
+	return id
+}
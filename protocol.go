@@ -0,0 +1,64 @@
+package proto
+
+import "fmt"
+
+// Protocol versions of the releases that changed the packets and registries of this package.
+const (
+	// Protocol1_7_6 sends dashed UUIDs in LoginSuccess.
+	Protocol1_7_6 = 5
+	// Protocol1_16 sends LoginSuccess with a binary UUID.
+	Protocol1_16 = 735
+	// Protocol1_16_2 sends the registry codec with dimension types and biomes.
+	Protocol1_16_2 = 751
+	// Protocol1_17 added the minimum height and the height of dimension types.
+	Protocol1_17 = 755
+	// Protocol1_18 removed the depth and scale of biomes.
+	Protocol1_18 = 757
+	// Protocol1_18_2 made the infiniburn blocks of dimension types a tag.
+	Protocol1_18_2 = 758
+	// Protocol1_19 added the player key to LoginStart and the properties to LoginSuccess,
+	// the chat types, and the monster spawn light levels of dimension types.
+	// It removed the category of biomes.
+	Protocol1_19 = 759
+	// Protocol1_19_1 added the player UUID to LoginStart, and changed the layout of chat types.
+	Protocol1_19_1 = 760
+	// Protocol1_19_3 removed the player key from LoginStart, and replaced the
+	// precipitation of biomes with a flag.
+	Protocol1_19_3 = 761
+	// Protocol1_19_4 added the damage types.
+	Protocol1_19_4 = 762
+	// Protocol1_20 added the armor trim patterns and materials.
+	Protocol1_20 = 763
+	// Protocol1_20_2 added the configuration state, entered with LoginAcknowledged,
+	// and made the player UUID of LoginStart mandatory.
+	Protocol1_20_2 = 764
+	// Protocol1_20_3 sent the text components as NBT, and added the UUID of resource packs.
+	Protocol1_20_3 = 765
+	// Protocol1_20_5 added the strict error handling flag to LoginSuccess, and
+	// replaced the registry codec with a RegistryData packet per registry.
+	// It added the banner patterns and wolf variants.
+	Protocol1_20_5 = 766
+	// Protocol1_21 added the painting variants, jukebox songs and enchantments.
+	Protocol1_21 = 767
+	// Protocol1_21_2 removed the strict error handling flag from LoginSuccess.
+	Protocol1_21_2 = 768
+)
+
+// versionedID is the ID of a packet from a protocol version.
+type versionedID struct {
+	protocol, id int32
+}
+
+// packetID returns the ID of the packet in the protocol version, given its IDs
+// by protocol version, newest first. If the protocol is zero, the newest ID is used.
+func packetID(packet string, protocol int32, ids []versionedID) (int32, error) {
+	if protocol == 0 {
+		return ids[0].id, nil
+	}
+	for _, v := range ids {
+		if protocol >= v.protocol {
+			return v.id, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s in protocol %d", ErrUnsupportedProtocol, packet, protocol)
+}
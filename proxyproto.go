@@ -0,0 +1,518 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The HAProxy PROXY protocol lets TCP load balancers and proxies prepend the
+// address of the real client to a connection, before the first Handshake.
+// See https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+
+var (
+	// ErrNoProxyHeader is returned when a PROXY protocol header is required but missing.
+	ErrNoProxyHeader = errors.New("proxy protocol: missing header")
+	// ErrInvalidProxyHeader is returned when a PROXY protocol header is malformed.
+	ErrInvalidProxyHeader = errors.New("proxy protocol: invalid header")
+)
+
+// defaultProxyHeaderTimeout is the time a ProxyConn waits for its header.
+const defaultProxyHeaderTimeout = 5 * time.Second
+
+const (
+	proxyV1Prefix    = "PROXY "
+	proxyV1MaxLength = 107
+	proxyV2Signature = "\r\n\r\n\x00\r\nQUIT\n"
+)
+
+// ProxyCommand is the command of a PROXY protocol header.
+type ProxyCommand byte
+
+const (
+	// ProxyLocal is sent for connections established by the proxy itself, such
+	// as health checks. The addresses of the connection must be used.
+	ProxyLocal ProxyCommand = 0x0
+	// ProxyProxy is sent for connections relayed on behalf of a client.
+	ProxyProxy ProxyCommand = 0x1
+)
+
+// ProxyTLV is a Type-Length-Value extension of a PROXY protocol v2 header.
+type ProxyTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// Some ProxyTLV types defined by the specification.
+const (
+	ProxyTLVALPN      = 0x01
+	ProxyTLVAuthority = 0x02
+	ProxyTLVCRC32C    = 0x03
+	ProxyTLVNoop      = 0x04
+	ProxyTLVUniqueID  = 0x05
+	ProxyTLVSSL       = 0x20
+	ProxyTLVNetNS     = 0x30
+)
+
+// --- ProxyHeader ---
+
+// ProxyHeader is a PROXY protocol header.
+type ProxyHeader struct {
+	// Version is the protocol version: 1 (text) or 2 (binary).
+	Version int
+	Command ProxyCommand
+	// Source and Destination are the addresses of the client and of the proxy.
+	// They are nil if the proxy did not know them (UNKNOWN or AF_UNSPEC).
+	Source      net.Addr
+	Destination net.Addr
+	// TLVs are the extensions of a version 2 header.
+	TLVs []ProxyTLV
+}
+
+// NewProxyHeader creates a version 2 PROXY header relaying a connection from source to destination.
+func NewProxyHeader(source, destination net.Addr) *ProxyHeader {
+	return &ProxyHeader{Version: 2, Command: ProxyProxy, Source: source, Destination: destination}
+}
+
+// ReadProxyHeader reads a PROXY protocol header of any version from the reader.
+// If the reader does not start with a header, nothing is consumed and
+// ErrNoProxyHeader is returned.
+func ReadProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	// The first byte is checked alone, as a legacy server list ping can be a single byte
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch first[0] {
+	case proxyV1Prefix[0]:
+		prefix, err := r.Peek(len(proxyV1Prefix))
+		if err != nil || string(prefix) != proxyV1Prefix {
+			return nil, ErrNoProxyHeader
+		}
+		return readProxyHeaderV1(r)
+	case proxyV2Signature[0]:
+		signature, err := r.Peek(len(proxyV2Signature))
+		if err != nil || string(signature) != proxyV2Signature {
+			return nil, ErrNoProxyHeader
+		}
+		return readProxyHeaderV2(r)
+	default:
+		return nil, ErrNoProxyHeader
+	}
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLength {
+			return nil, ErrInvalidProxyHeader
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	h := &ProxyHeader{Version: 1, Command: ProxyProxy}
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return h, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	source, err := parseProxyV1Addr(fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	destination, err := parseProxyV1Addr(fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+	if (fields[1] == "TCP4") != (source.IP.To4() != nil && destination.IP.To4() != nil) {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	h.Source, h.Destination = source, destination
+	return h, nil
+}
+
+func parseProxyV1Addr(ip, port string) (*net.TCPAddr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if addr.IP == nil || err != nil {
+		return nil, ErrInvalidProxyHeader
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readProxyHeaderV2(r *bufio.Reader) (*ProxyHeader, error) {
+	var fixed [16]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return nil, err
+	}
+
+	versionCommand, family := fixed[12], fixed[13]
+	if versionCommand>>4 != 2 {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	h := &ProxyHeader{Version: 2, Command: ProxyCommand(versionCommand & 0xF)}
+	if h.Command != ProxyLocal && h.Command != ProxyProxy {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	var addrLength int
+	switch family {
+	case 0x11, 0x12: // TCP/UDP over IPv4
+		addrLength = 12
+		if len(payload) < addrLength {
+			return nil, ErrInvalidProxyHeader
+		}
+		h.Source, h.Destination = proxyV2Addrs(family, payload[0:4], payload[4:8], payload[8:10], payload[10:12])
+	case 0x21, 0x22: // TCP/UDP over IPv6
+		addrLength = 36
+		if len(payload) < addrLength {
+			return nil, ErrInvalidProxyHeader
+		}
+		h.Source, h.Destination = proxyV2Addrs(family, payload[0:16], payload[16:32], payload[32:34], payload[34:36])
+	case 0x31, 0x32: // Unix stream/datagram sockets
+		addrLength = 216
+		if len(payload) < addrLength {
+			return nil, ErrInvalidProxyHeader
+		}
+		network := "unix"
+		if family == 0x32 {
+			network = "unixgram"
+		}
+		h.Source = &net.UnixAddr{Name: string(bytes.TrimRight(payload[:108], "\x00")), Net: network}
+		h.Destination = &net.UnixAddr{Name: string(bytes.TrimRight(payload[108:216], "\x00")), Net: network}
+	case 0x00: // AF_UNSPEC
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+
+	tlvs := payload[addrLength:]
+	for len(tlvs) > 0 {
+		if len(tlvs) < 3 {
+			return nil, ErrInvalidProxyHeader
+		}
+		length := int(binary.BigEndian.Uint16(tlvs[1:3]))
+		if len(tlvs) < 3+length {
+			return nil, ErrInvalidProxyHeader
+		}
+		h.TLVs = append(h.TLVs, ProxyTLV{Type: tlvs[0], Value: tlvs[3 : 3+length]})
+		tlvs = tlvs[3+length:]
+	}
+
+	return h, nil
+}
+
+func proxyV2Addrs(family byte, srcIP, dstIP, srcPort, dstPort []byte) (source, destination net.Addr) {
+	sp, dp := int(binary.BigEndian.Uint16(srcPort)), int(binary.BigEndian.Uint16(dstPort))
+	if family&0xF == 0x2 {
+		return &net.UDPAddr{IP: net.IP(srcIP), Port: sp}, &net.UDPAddr{IP: net.IP(dstIP), Port: dp}
+	}
+	return &net.TCPAddr{IP: net.IP(srcIP), Port: sp}, &net.TCPAddr{IP: net.IP(dstIP), Port: dp}
+}
+
+// TLV returns the value of the first TLV of the given type, if any.
+func (h *ProxyHeader) TLV(typ byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == typ {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// WriteTo writes the PROXY protocol header to w.
+func (h *ProxyHeader) WriteTo(w io.Writer) (int64, error) {
+	var b []byte
+	var err error
+	if h.Version == 1 {
+		b, err = h.appendV1(nil)
+	} else {
+		b, err = h.appendV2(nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+func (h *ProxyHeader) appendV1(b []byte) ([]byte, error) {
+	src, srcOK := h.Source.(*net.TCPAddr)
+	dst, dstOK := h.Destination.(*net.TCPAddr)
+	if h.Command == ProxyLocal || !srcOK || !dstOK {
+		return append(b, proxyV1Prefix+"UNKNOWN\r\n"...), nil
+	}
+
+	protocol := "TCP6"
+	if src.IP.To4() != nil && dst.IP.To4() != nil {
+		protocol = "TCP4"
+	}
+	return append(b, fmt.Sprintf("%s%s %s %s %d %d\r\n", proxyV1Prefix, protocol, src.IP, dst.IP, src.Port, dst.Port)...), nil
+}
+
+func (h *ProxyHeader) appendV2(b []byte) ([]byte, error) {
+	var family byte
+	var addrs []byte
+
+	srcIP, srcPort, srcUDP := proxyAddrIP(h.Source)
+	dstIP, dstPort, dstUDP := proxyAddrIP(h.Destination)
+	switch {
+	case h.Source == nil || h.Destination == nil:
+	case srcIP != nil && dstIP != nil && srcUDP == dstUDP:
+		if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+			family = 0x11
+			addrs = append(append(addrs, src4...), dst4...)
+		} else {
+			family = 0x21
+			addrs = append(append(addrs, srcIP.To16()...), dstIP.To16()...)
+		}
+		if srcUDP {
+			family++
+		}
+		addrs = append(addrs, byte(srcPort>>8), byte(srcPort), byte(dstPort>>8), byte(dstPort))
+	default:
+		src, srcOK := h.Source.(*net.UnixAddr)
+		dst, dstOK := h.Destination.(*net.UnixAddr)
+		if !srcOK || !dstOK || len(src.Name) > 108 || len(dst.Name) > 108 {
+			return nil, fmt.Errorf("proxy protocol: unsupported addresses %v and %v", h.Source, h.Destination)
+		}
+		family = 0x31
+		if src.Net == "unixgram" {
+			family = 0x32
+		}
+		addrs = make([]byte, 216)
+		copy(addrs, src.Name)
+		copy(addrs[108:], dst.Name)
+	}
+
+	length := len(addrs)
+	for _, tlv := range h.TLVs {
+		length += 3 + len(tlv.Value)
+	}
+	if length > 0xFFFF {
+		return nil, fmt.Errorf("proxy protocol: header too long")
+	}
+
+	b = append(b, proxyV2Signature...)
+	b = append(b, 0x20|byte(h.Command), family, byte(length>>8), byte(length))
+	b = append(b, addrs...)
+	for _, tlv := range h.TLVs {
+		b = append(b, tlv.Type, byte(len(tlv.Value)>>8), byte(len(tlv.Value)))
+		b = append(b, tlv.Value...)
+	}
+	return b, nil
+}
+
+// proxyAddrIP returns the IP and port of a TCP or UDP address
+func proxyAddrIP(addr net.Addr) (ip net.IP, port int, udp bool) {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP, addr.Port, false
+	case *net.UDPAddr:
+		return addr.IP, addr.Port, true
+	}
+	return nil, 0, false
+}
+
+// --- ProxyListener ---
+
+// ProxyListener is a net.Listener accepting connections that may start with a
+// PROXY protocol header, such as connections from TCP load balancers.
+// Headers are only accepted from trusted sources: connections from other
+// sources are returned as is.
+type ProxyListener struct {
+	net.Listener
+
+	// Trusted are the networks allowed to send PROXY protocol headers.
+	Trusted []*net.IPNet
+	// Required makes connections from trusted sources fail if they do not send a header.
+	Required bool
+	// Timeout is the maximum time to receive the header. If zero, 5 seconds is used.
+	Timeout time.Duration
+}
+
+// ParseCIDRs parses a list of networks in CIDR notation, like "10.0.0.0/8".
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks[i] = network
+	}
+	return networks, nil
+}
+
+// Accept waits for and returns the next connection.
+// Connections from trusted sources are returned as *ProxyConn, whose header
+// is read on first use.
+func (l *ProxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trusts(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return NewProxyConn(conn, l.Required, l.Timeout), nil
+}
+
+// trusts reports whether the address is in a trusted network
+func (l *ProxyListener) trusts(addr net.Addr) bool {
+	ip, _, _ := proxyAddrIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.Trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- ProxyConn ---
+
+// ProxyConn is a connection that may start with a PROXY protocol header.
+// The header is read on first use of Read, RemoteAddr, LocalAddr or Header,
+// and the addresses it carries are reported instead of the ones of the
+// underlying connection. These methods block until the header is read, or
+// until the header timeout or the read deadline of the connection expire.
+type ProxyConn struct {
+	net.Conn
+
+	reader   *bufio.Reader
+	required bool
+	timeout  time.Duration
+
+	once   sync.Once
+	header *ProxyHeader
+	err    error
+
+	mu sync.Mutex
+	// readDeadline is the read deadline set by the user of the connection
+	readDeadline time.Time
+	// headerDeadline is the deadline of the header while it is read
+	headerDeadline time.Time
+}
+
+// NewProxyConn creates a ProxyConn over the connection, which must come from
+// a trusted source.
+// If required is true, reading from a connection without header fails.
+// If timeout is zero, the header must be received within 5 seconds.
+func NewProxyConn(conn net.Conn, required bool, timeout time.Duration) *ProxyConn {
+	return &ProxyConn{
+		Conn:     conn,
+		reader:   bufio.NewReader(conn),
+		required: required,
+		timeout:  timeout,
+	}
+}
+
+// readHeader reads the header once, then restores the read deadline of the user
+func (c *ProxyConn) readHeader() {
+	c.once.Do(func() {
+		timeout := c.timeout
+		if timeout <= 0 {
+			timeout = defaultProxyHeaderTimeout
+		}
+		c.mu.Lock()
+		c.headerDeadline = time.Now().Add(timeout)
+		c.Conn.SetReadDeadline(c.effectiveReadDeadline())
+		c.mu.Unlock()
+
+		c.header, c.err = ReadProxyHeader(c.reader)
+		if c.err == ErrNoProxyHeader && !c.required {
+			c.err = nil
+		}
+
+		c.mu.Lock()
+		c.headerDeadline = time.Time{}
+		c.Conn.SetReadDeadline(c.readDeadline)
+		c.mu.Unlock()
+	})
+}
+
+// effectiveReadDeadline returns the earliest of the read deadline of the user
+// and of the deadline of the header. c.mu must be held.
+func (c *ProxyConn) effectiveReadDeadline() time.Time {
+	if c.headerDeadline.IsZero() || (!c.readDeadline.IsZero() && c.readDeadline.Before(c.headerDeadline)) {
+		return c.readDeadline
+	}
+	return c.headerDeadline
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (c *ProxyConn) SetDeadline(t time.Time) error {
+	if err := c.Conn.SetWriteDeadline(t); err != nil {
+		return err
+	}
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+// While the header is read, the earliest of t and the header timeout applies.
+func (c *ProxyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(c.effectiveReadDeadline())
+}
+
+// Header returns the PROXY protocol header of the connection, or nil if it did not send any.
+func (c *ProxyConn) Header() (*ProxyHeader, error) {
+	c.readHeader()
+	return c.header, c.err
+}
+
+// Read reads data following the header.
+func (c *ProxyConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the address of the client, once the header is read.
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && c.header.Command == ProxyProxy && c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, once the header is read.
+func (c *ProxyConn) LocalAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && c.header.Command == ProxyProxy && c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
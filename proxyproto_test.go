@@ -0,0 +1,299 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readProxyHeader(b []byte) (*ProxyHeader, error) {
+	return ReadProxyHeader(bufio.NewReader(bytes.NewReader(b)))
+}
+
+func TestProxyHeaderV1(t *testing.T) {
+	tests := []struct {
+		line        string
+		source      string
+		destination string
+	}{
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 25565\r\n", "192.168.0.1:56324", "192.168.0.11:25565"},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 25565\r\n", "[2001:db8::1]:56324", "[2001:db8::2]:25565"},
+		{"PROXY UNKNOWN\r\n", "", ""},
+		{"PROXY UNKNOWN ffff:f...f:ffff ffff:f...f:ffff 65535 65535\r\n", "", ""},
+	}
+	for _, test := range tests {
+		h, err := readProxyHeader([]byte(test.line + "data"))
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+		if h.Version != 1 || h.Command != ProxyProxy {
+			t.Errorf("%q: got version %d command %d", test.line, h.Version, h.Command)
+		}
+		if test.source == "" {
+			if h.Source != nil || h.Destination != nil {
+				t.Errorf("%q: got %v and %v, want no addresses", test.line, h.Source, h.Destination)
+			}
+			continue
+		}
+		if h.Source.String() != test.source || h.Destination.String() != test.destination {
+			t.Errorf("%q: got %v and %v, want %s and %s", test.line, h.Source, h.Destination, test.source, test.destination)
+		}
+
+		// The header is written back as is
+		var buf bytes.Buffer
+		if _, err := h.WriteTo(&buf); err != nil {
+			t.Errorf("%q: %v", test.line, err)
+		} else if buf.String() != test.line {
+			t.Errorf("got %q, want %q", buf.String(), test.line)
+		}
+	}
+}
+
+func TestProxyHeaderV1Invalid(t *testing.T) {
+	for _, line := range []string{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",
+		"PROXY TCP4 192.168.0.1 2001:db8::2 56324 25565\r\n",
+		"PROXY TCP6 192.168.0.1 192.168.0.11 56324 25565\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 65536\r\n",
+		"PROXY TCP4 192.168.0.256 192.168.0.11 56324 25565\r\n",
+		"PROXY UDP4 192.168.0.1 192.168.0.11 56324 25565\r\n",
+		"PROXY \r\n",
+	} {
+		if _, err := readProxyHeader([]byte(line)); !errors.Is(err, ErrInvalidProxyHeader) {
+			t.Errorf("%q: got %v, want ErrInvalidProxyHeader", line, err)
+		}
+	}
+
+	// The line is at most 107 bytes long, CRLF included
+	line := "PROXY UNKNOWN " + strings.Repeat("x", 91) + "\r\n"
+	if _, err := readProxyHeader([]byte(line)); err != nil {
+		t.Errorf("107 bytes: %v", err)
+	}
+	line = "PROXY UNKNOWN " + strings.Repeat("x", 92) + "\r\n"
+	if _, err := readProxyHeader([]byte(line)); !errors.Is(err, ErrInvalidProxyHeader) {
+		t.Errorf("108 bytes: got %v, want ErrInvalidProxyHeader", err)
+	}
+
+	if _, err := readProxyHeader([]byte("PROXY TCP4 192.168.0.1")); !errors.Is(err, io.EOF) {
+		t.Errorf("truncated: got %v, want io.EOF", err)
+	}
+}
+
+func TestProxyHeaderV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		header ProxyHeader
+		// size is the encoded size of the header
+		size int
+	}{
+		{"local", ProxyHeader{Version: 2, Command: ProxyLocal}, 16},
+		{"TCP over IPv4", *NewProxyHeader(
+			&net.TCPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 56324},
+			&net.TCPAddr{IP: net.IPv4(192, 168, 0, 11), Port: 25565},
+		), 16 + 12},
+		{"UDP over IPv6", *NewProxyHeader(
+			&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+			&net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 19132},
+		), 16 + 36},
+		{"IPv4 and IPv6", *NewProxyHeader(
+			&net.TCPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 56324},
+			&net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 25565},
+		), 16 + 36},
+		{"unix", *NewProxyHeader(
+			&net.UnixAddr{Name: "/run/client.sock", Net: "unix"},
+			&net.UnixAddr{Name: "/run/server.sock", Net: "unix"},
+		), 16 + 216},
+		{"TLVs", ProxyHeader{
+			Version:     2,
+			Command:     ProxyProxy,
+			Source:      &net.TCPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 56324},
+			Destination: &net.TCPAddr{IP: net.IPv4(192, 168, 0, 11), Port: 25565},
+			TLVs: []ProxyTLV{
+				{Type: ProxyTLVAuthority, Value: []byte("example.com")},
+				{Type: ProxyTLVNoop, Value: []byte{}},
+			},
+		}, 16 + 12 + 3 + 11 + 3},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if _, err := test.header.WriteTo(&buf); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if buf.Len() != test.size {
+			t.Errorf("%s: got %d bytes, want %d", test.name, buf.Len(), test.size)
+		}
+
+		h, err := readProxyHeader(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if h.Version != 2 || h.Command != test.header.Command || len(h.TLVs) != len(test.header.TLVs) {
+			t.Errorf("%s: got %+v, want %+v", test.name, h, test.header)
+		}
+		if test.header.Source != nil {
+			if h.Source.String() != test.header.Source.String() || h.Destination.String() != test.header.Destination.String() ||
+				h.Source.Network() != test.header.Source.Network() {
+				t.Errorf("%s: got %v and %v, want %v and %v", test.name, h.Source, h.Destination, test.header.Source, test.header.Destination)
+			}
+		} else if h.Source != nil || h.Destination != nil {
+			t.Errorf("%s: got %v and %v, want no addresses", test.name, h.Source, h.Destination)
+		}
+		for _, tlv := range test.header.TLVs {
+			if value, ok := h.TLV(tlv.Type); !ok || !bytes.Equal(value, tlv.Value) {
+				t.Errorf("%s: got TLV 0x%02X %q, want %q", test.name, tlv.Type, value, tlv.Value)
+			}
+		}
+	}
+
+	// The example of the specification: TCP over IPv4 from 127.0.0.1:1 to 127.0.0.2:2
+	h, err := readProxyHeader([]byte(proxyV2Signature + "\x21\x11\x00\x0C\x7F\x00\x00\x01\x7F\x00\x00\x02\x00\x01\x00\x02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Source.String() != "127.0.0.1:1" || h.Destination.String() != "127.0.0.2:2" {
+		t.Errorf("got %v and %v", h.Source, h.Destination)
+	}
+}
+
+func TestProxyHeaderV2Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"version 1", "\x11\x11\x00\x0C" + strings.Repeat("\x00", 12), ErrInvalidProxyHeader},
+		{"unknown command", "\x22\x11\x00\x0C" + strings.Repeat("\x00", 12), ErrInvalidProxyHeader},
+		{"unknown family", "\x21\x41\x00\x00", ErrInvalidProxyHeader},
+		{"IPv4 addresses too short", "\x21\x11\x00\x0B" + strings.Repeat("\x00", 11), ErrInvalidProxyHeader},
+		{"IPv6 addresses too short", "\x21\x21\x00\x0C" + strings.Repeat("\x00", 12), ErrInvalidProxyHeader},
+		{"unix addresses too short", "\x21\x31\x00\x0C" + strings.Repeat("\x00", 12), ErrInvalidProxyHeader},
+		{"truncated TLV header", "\x21\x00\x00\x02\x04\x00", ErrInvalidProxyHeader},
+		{"truncated TLV value", "\x21\x00\x00\x04\x04\x00\x02\x00", ErrInvalidProxyHeader},
+		{"truncated fixed part", "\x21\x11", io.ErrUnexpectedEOF},
+		{"truncated payload", "\x21\x11\x00\x0C\x7F\x00", io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		if _, err := readProxyHeader([]byte(proxyV2Signature + test.data)); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// The headers that cannot be encoded
+	for _, h := range []*ProxyHeader{
+		{Version: 2, Command: ProxyProxy, TLVs: []ProxyTLV{{Type: ProxyTLVNoop, Value: make([]byte, 0xFFFF)}}},
+		NewProxyHeader(&net.UnixAddr{Name: strings.Repeat("x", 109), Net: "unix"}, &net.UnixAddr{Name: "/run/server.sock", Net: "unix"}),
+		NewProxyHeader(&net.TCPAddr{IP: net.IPv4(192, 168, 0, 1)}, &net.UnixAddr{Name: "/run/server.sock", Net: "unix"}),
+	} {
+		if _, err := h.WriteTo(io.Discard); err == nil {
+			t.Errorf("%+v: no error", h)
+		}
+	}
+}
+
+func TestReadProxyHeaderNone(t *testing.T) {
+	for _, data := range []string{"\x10\x00", "\xFE", "PROXZ TCP4", "\r\n\r\n\x00\r\nQUIZ\n"} {
+		r := bufio.NewReader(strings.NewReader(data))
+		if _, err := ReadProxyHeader(r); err != ErrNoProxyHeader {
+			t.Errorf("%q: got %v, want ErrNoProxyHeader", data, err)
+		}
+		// Nothing is consumed
+		if rest, _ := io.ReadAll(r); string(rest) != data {
+			t.Errorf("%q: got %q left", data, rest)
+		}
+	}
+}
+
+// newTestProxyConn returns a ProxyConn receiving the data through a pipe
+func newTestProxyConn(data []byte, required bool) *ProxyConn {
+	client, server := net.Pipe()
+	go func() {
+		client.Write(data)
+		client.Close()
+	}()
+	return NewProxyConn(server, required, time.Second)
+}
+
+func TestProxyConn(t *testing.T) {
+	source := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+	destination := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 25565}
+	var proxied, local bytes.Buffer
+	NewProxyHeader(source, destination).WriteTo(&proxied)
+	(&ProxyHeader{Version: 1, Command: ProxyLocal}).WriteTo(&local)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		required bool
+		// proxied reports whether the addresses of the header are reported
+		proxied bool
+		err     error
+	}{
+		{"proxied", append(proxied.Bytes(), "data"...), true, true, nil},
+		{"local", append(local.Bytes(), "data"...), true, false, nil},
+		{"no header", []byte("data"), false, false, nil},
+		{"no header required", []byte("data"), true, false, ErrNoProxyHeader},
+		{"invalid header", []byte("PROXY TCP4\r\ndata"), false, false, ErrInvalidProxyHeader},
+	}
+	for _, test := range tests {
+		conn := newTestProxyConn(test.data, test.required)
+		if test.proxied {
+			if conn.RemoteAddr().String() != source.String() || conn.LocalAddr().String() != destination.String() {
+				t.Errorf("%s: got %v and %v, want %v and %v", test.name, conn.RemoteAddr(), conn.LocalAddr(), source, destination)
+			}
+		} else if conn.RemoteAddr() != conn.Conn.RemoteAddr() || conn.LocalAddr() != conn.Conn.LocalAddr() {
+			t.Errorf("%s: got %v and %v, want the addresses of the connection", test.name, conn.RemoteAddr(), conn.LocalAddr())
+		}
+
+		data, err := io.ReadAll(conn)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		} else if err == nil && string(data) != "data" {
+			t.Errorf("%s: got %q, want the data after the header", test.name, data)
+		}
+		conn.Close()
+	}
+}
+
+func TestProxyConnTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := NewProxyConn(server, false, 50*time.Millisecond)
+	defer conn.Close()
+
+	_, err := conn.Header()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("got %v, want a timeout", err)
+	}
+}
+
+func TestProxyListenerTrusts(t *testing.T) {
+	trusted, err := ParseCIDRs("10.0.0.0/8", "fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &ProxyListener{Trusted: trusted}
+	for _, test := range []struct {
+		addr    net.Addr
+		trusted bool
+	}{
+		{&net.TCPAddr{IP: net.IPv4(10, 1, 2, 3)}, true},
+		{&net.TCPAddr{IP: net.ParseIP("fd00::1")}, true},
+		{&net.TCPAddr{IP: net.IPv4(192, 168, 0, 1)}, false},
+		{&net.UnixAddr{Name: "/run/proxy.sock", Net: "unix"}, false},
+	} {
+		if got := l.trusts(test.addr); got != test.trusted {
+			t.Errorf("%v: got %v, want %v", test.addr, got, test.trusted)
+		}
+	}
+
+	if _, err := ParseCIDRs("10.0.0.0"); err == nil {
+		t.Error("invalid network: no error")
+	}
+}
@@ -0,0 +1,475 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The Query protocol is the UDP protocol, derived from GameSpy4, enabled by
+// enable-query in server.properties. It is not related to the status state:
+// a client asks for a challenge token with a handshake, then sends it back
+// in a basic or a full stat request.
+
+// Query packet types.
+const (
+	queryTypeStat      = 0x00
+	queryTypeHandshake = 0x09
+)
+
+// queryMagic starts every query request.
+var queryMagic = [2]byte{0xFE, 0xFD}
+
+// querySessionMask masks the session IDs, only the low 4 bits of each byte being used.
+const querySessionMask = 0x0F0F0F0F
+
+// queryFullStatPadding and queryPlayerPadding surround the key-value section and the
+// player list of a full stat response.
+var (
+	queryFullStatPadding = []byte("splitnum\x00\x80\x00")
+	queryPlayerPadding   = []byte("\x01player_\x00\x00")
+)
+
+// queryMaxPacketSize is the maximum size of a query packet.
+const queryMaxPacketSize = 1460
+
+// defaultQueryTimeout is the timeout of a query of a zero Querier.
+const defaultQueryTimeout = 5 * time.Second
+
+// defaultQueryTokenLifetime is the lifetime of the challenge tokens of a QueryServer.
+const defaultQueryTokenLifetime = 30 * time.Second
+
+// ErrInvalidQuery is returned when a query packet is malformed.
+var ErrInvalidQuery = errors.New("invalid query packet")
+
+// --- QueryStatus ---
+
+// QueryStatus is the status of a server sent through the Query protocol.
+// A basic stat only carries MOTD, GameType, Map, NumPlayers, MaxPlayers, HostPort and HostIP.
+type QueryStatus struct {
+	MOTD       string
+	GameType   string
+	GameID     string
+	Version    string
+	Plugins    string
+	Map        string
+	NumPlayers int
+	MaxPlayers int
+	HostPort   int
+	HostIP     string
+	Players    []string
+}
+
+// NewQueryStatus creates the query status of a server from its status.
+// The player names are those of the player sample.
+func NewQueryStatus(status *StatusResponse, host string, port int) *QueryStatus {
+	qs := &QueryStatus{
+		MOTD:     status.Description.PlainText(),
+		GameType: "SMP",
+		GameID:   "MINECRAFT",
+		Version:  status.Version.Name,
+		Map:      "world",
+		HostPort: port,
+		HostIP:   host,
+	}
+	if status.Players != nil {
+		qs.NumPlayers = status.Players.Online
+		qs.MaxPlayers = status.Players.Max
+		for _, player := range status.Players.Sample {
+			qs.Players = append(qs.Players, player.Name)
+		}
+	}
+	return qs
+}
+
+// appendBasic appends the payload of a basic stat response
+func (qs *QueryStatus) appendBasic(b []byte) []byte {
+	b = appendCString(b, qs.MOTD)
+	b = appendCString(b, qs.GameType)
+	b = appendCString(b, qs.Map)
+	b = appendCString(b, strconv.Itoa(qs.NumPlayers))
+	b = appendCString(b, strconv.Itoa(qs.MaxPlayers))
+	b = append(b, byte(qs.HostPort), byte(qs.HostPort>>8))
+	return appendCString(b, qs.HostIP)
+}
+
+// appendFull appends the payload of a full stat response.
+// The players that do not fit in a query packet are left out.
+func (qs *QueryStatus) appendFull(b []byte) []byte {
+	b = append(b, queryFullStatPadding...)
+	for _, kv := range [...][2]string{
+		{"hostname", qs.MOTD},
+		{"gametype", qs.GameType},
+		{"game_id", qs.GameID},
+		{"version", qs.Version},
+		{"plugins", qs.Plugins},
+		{"map", qs.Map},
+		{"numplayers", strconv.Itoa(qs.NumPlayers)},
+		{"maxplayers", strconv.Itoa(qs.MaxPlayers)},
+		{"hostport", strconv.Itoa(qs.HostPort)},
+		{"hostip", qs.HostIP},
+	} {
+		b = appendCString(b, kv[0])
+		b = appendCString(b, kv[1])
+	}
+	b = append(b, 0)
+
+	b = append(b, queryPlayerPadding...)
+	for _, player := range qs.Players {
+		// Keep room for the terminators of the player and of the list
+		if len(b)+len(player)+2 > queryMaxPacketSize {
+			break
+		}
+		b = appendCString(b, player)
+	}
+	return append(b, 0)
+}
+
+// parseBasic parses the payload of a basic stat response
+func (qs *QueryStatus) parseBasic(b []byte) (err error) {
+	r := queryReader{b: b}
+	qs.MOTD = r.cstring()
+	qs.GameType = r.cstring()
+	qs.Map = r.cstring()
+	qs.NumPlayers = r.int()
+	qs.MaxPlayers = r.int()
+	if len(r.b) < 2 {
+		return ErrInvalidQuery
+	}
+	qs.HostPort = int(binary.LittleEndian.Uint16(r.b))
+	r.b = r.b[2:]
+	qs.HostIP = r.cstring()
+	return r.err
+}
+
+// parseFull parses the payload of a full stat response
+func (qs *QueryStatus) parseFull(b []byte) error {
+	if !bytes.HasPrefix(b, queryFullStatPadding) {
+		return ErrInvalidQuery
+	}
+	r := queryReader{b: b[len(queryFullStatPadding):]}
+
+	for r.err == nil {
+		key := r.cstring()
+		if key == "" {
+			break
+		}
+		value := r.cstring()
+		switch key {
+		case "hostname":
+			qs.MOTD = value
+		case "gametype":
+			qs.GameType = value
+		case "game_id":
+			qs.GameID = value
+		case "version":
+			qs.Version = value
+		case "plugins":
+			qs.Plugins = value
+		case "map":
+			qs.Map = value
+		case "numplayers":
+			qs.NumPlayers, _ = strconv.Atoi(value)
+		case "maxplayers":
+			qs.MaxPlayers, _ = strconv.Atoi(value)
+		case "hostport":
+			qs.HostPort, _ = strconv.Atoi(value)
+		case "hostip":
+			qs.HostIP = value
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	if !bytes.HasPrefix(r.b, queryPlayerPadding) {
+		return ErrInvalidQuery
+	}
+	r.b = r.b[len(queryPlayerPadding):]
+	qs.Players = nil
+	for r.err == nil && len(r.b) > 0 {
+		player := r.cstring()
+		if player == "" {
+			break
+		}
+		qs.Players = append(qs.Players, player)
+	}
+	return r.err
+}
+
+func appendCString(b []byte, s string) []byte {
+	return append(append(b, s...), 0)
+}
+
+// queryReader reads the null-terminated strings of a query packet
+type queryReader struct {
+	b   []byte
+	err error
+}
+
+func (r *queryReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		r.err = ErrInvalidQuery
+		return ""
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
+
+func (r *queryReader) int() int {
+	s := r.cstring()
+	if r.err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		r.err = fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return n
+}
+
+// --- QueryServer ---
+
+// QueryServer answers the queries of clients.
+type QueryServer struct {
+	// Status returns the status sent to the client at the given address.
+	Status func(addr net.Addr) (*QueryStatus, error)
+	// TokenLifetime is the interval at which the challenge tokens change.
+	// A token stays valid until the second change. If zero, 30 seconds is used.
+	TokenLifetime time.Duration
+
+	mu       sync.Mutex
+	secrets  [2][]byte
+	rotateAt time.Time
+}
+
+// Serve answers the queries received on the connection until reading from it fails.
+// Malformed packets and requests with invalid challenge tokens are ignored.
+func (s *QueryServer) Serve(conn net.PacketConn) error {
+	buf := make([]byte, queryMaxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if response := s.handle(buf[:n], addr); response != nil {
+			// Like any UDP packet, the response may be lost
+			conn.WriteTo(response, addr)
+		}
+	}
+}
+
+// ListenAndServe listens on the UDP address and answers the queries.
+func (s *QueryServer) ListenAndServe(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
+
+// handle returns the response to the request, or nil if it must be ignored
+func (s *QueryServer) handle(request []byte, addr net.Addr) []byte {
+	if len(request) < 7 || request[0] != queryMagic[0] || request[1] != queryMagic[1] {
+		return nil
+	}
+	typ, session, payload := request[2], request[3:7], request[7:]
+
+	response := append([]byte{typ}, session...)
+	switch typ {
+	case queryTypeHandshake:
+		return appendCString(response, strconv.Itoa(int(s.token(addr, 0))))
+
+	case queryTypeStat:
+		if len(payload) < 4 {
+			return nil
+		}
+		token := int32(binary.BigEndian.Uint32(payload))
+		if token != s.token(addr, 0) && token != s.token(addr, 1) {
+			return nil
+		}
+		if s.Status == nil {
+			return nil
+		}
+		status, err := s.Status(addr)
+		if err != nil || status == nil {
+			return nil
+		}
+		if len(payload) >= 8 {
+			return status.appendFull(response)
+		}
+		return status.appendBasic(response)
+
+	default:
+		return nil
+	}
+}
+
+// token returns the challenge token of the address with the current (0) or previous (1) secret
+func (s *QueryServer) token(addr net.Addr, generation int) int32 {
+	s.mu.Lock()
+	s.rotate()
+	secret := s.secrets[generation]
+	s.mu.Unlock()
+
+	ip, _, _ := proxyAddrIP(addr)
+	if ip == nil {
+		ip = net.ParseIP(addr.String())
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(ip)
+	// Like the vanilla tokens, the tokens are positive
+	return int32(binary.BigEndian.Uint32(mac.Sum(nil)) & 0x7FFFFFFF)
+}
+
+// rotate renews the secrets when they expire
+func (s *QueryServer) rotate() {
+	now := time.Now()
+	if s.secrets[0] != nil && now.Before(s.rotateAt) {
+		return
+	}
+
+	lifetime := s.TokenLifetime
+	if lifetime <= 0 {
+		lifetime = defaultQueryTokenLifetime
+	}
+	if s.secrets[0] == nil || now.Sub(s.rotateAt) >= lifetime {
+		// The previous secret expired too
+		s.secrets[1] = randomSecret()
+	} else {
+		s.secrets[1] = s.secrets[0]
+	}
+	s.secrets[0] = randomSecret()
+	s.rotateAt = now.Add(lifetime)
+}
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// --- Querier ---
+
+// Querier queries the status of servers through the Query protocol.
+// The zero value is ready to use.
+type Querier struct {
+	// Dialer dials the UDP connections. If nil, a zero net.Dialer is used.
+	Dialer ContextDialer
+	// Timeout is the timeout of a query, if the context has no earlier deadline.
+	// If zero, 5 seconds is used.
+	Timeout time.Duration
+}
+
+// BasicStat queries the basic status of the server at the UDP address.
+func (q *Querier) BasicStat(ctx context.Context, address string) (*QueryStatus, error) {
+	return q.query(ctx, address, false)
+}
+
+// FullStat queries the full status of the server at the UDP address,
+// including the version, plugins and player names.
+func (q *Querier) FullStat(ctx context.Context, address string) (*QueryStatus, error) {
+	return q.query(ctx, address, true)
+}
+
+func (q *Querier) query(ctx context.Context, address string, full bool) (*QueryStatus, error) {
+	timeout := q.Timeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer ContextDialer = &net.Dialer{}
+	if q.Dialer != nil {
+		dialer = q.Dialer
+	}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	stop := watchContext(ctx, conn)
+	defer stop()
+
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	session := binary.BigEndian.Uint32(b[:]) & querySessionMask
+
+	// Handshake
+	response, err := queryExchange(conn, queryRequest(queryTypeHandshake, session), session)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	r := queryReader{b: response}
+	token, err := strconv.ParseInt(r.cstring(), 10, 64)
+	if r.err != nil || err != nil {
+		return nil, ErrInvalidQuery
+	}
+
+	// Stat
+	request := queryRequest(queryTypeStat, session)
+	request = append(request, byte(token>>24), byte(token>>16), byte(token>>8), byte(token))
+	if full {
+		request = append(request, 0, 0, 0, 0)
+	}
+	response, err = queryExchange(conn, request, session)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	qs := new(QueryStatus)
+	if full {
+		err = qs.parseFull(response)
+	} else {
+		err = qs.parseBasic(response)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+func queryRequest(typ byte, session uint32) []byte {
+	request := []byte{queryMagic[0], queryMagic[1], typ, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(request[3:], session)
+	return request
+}
+
+// queryExchange sends the request and returns the payload of the response
+// with the same type and session ID
+func queryExchange(conn net.Conn, request []byte, session uint32) ([]byte, error) {
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 5 && buf[0] == request[2] && binary.BigEndian.Uint32(buf[1:]) == session {
+			return buf[5:n], nil
+		}
+		// Ignore the responses to other requests
+	}
+}
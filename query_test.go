@@ -0,0 +1,233 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var queryAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 56324}
+
+func testQueryStatus() *QueryStatus {
+	return &QueryStatus{
+		MOTD:       "A Minecraft Server",
+		GameType:   "SMP",
+		GameID:     "MINECRAFT",
+		Version:    "1.21",
+		Map:        "world",
+		NumPlayers: 2,
+		MaxPlayers: 20,
+		HostPort:   25565,
+		HostIP:     "127.0.0.1",
+		Players:    []string{"Steve", "Alex"},
+	}
+}
+
+// queryHandshake returns the challenge token answered by the server
+func queryHandshake(t *testing.T, s *QueryServer, addr net.Addr) int32 {
+	t.Helper()
+	response := s.handle([]byte{0xFE, 0xFD, queryTypeHandshake, 0x01, 0x02, 0x03, 0x04}, addr)
+	if len(response) < 6 || !bytes.Equal(response[:5], []byte{queryTypeHandshake, 0x01, 0x02, 0x03, 0x04}) || response[len(response)-1] != 0 {
+		t.Fatalf("got handshake response % X", response)
+	}
+	token, err := strconv.ParseInt(string(response[5:len(response)-1]), 10, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int32(token)
+}
+
+func queryStatRequest(token int32, full bool) []byte {
+	request := []byte{0xFE, 0xFD, queryTypeStat, 0x01, 0x02, 0x03, 0x04, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(request[7:], uint32(token))
+	if full {
+		request = append(request, 0, 0, 0, 0)
+	}
+	return request
+}
+
+func TestQueryHandshake(t *testing.T) {
+	s := &QueryServer{}
+	token := queryHandshake(t, s, queryAddr)
+	if queryHandshake(t, s, &net.UDPAddr{IP: queryAddr.IP, Port: 1}) != token {
+		t.Error("the token depends on the port")
+	}
+
+	// The tokens are positive
+	for i := 0; i < 64; i++ {
+		if token := queryHandshake(t, s, &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(i))}); token < 0 {
+			t.Errorf("got token %d", token)
+		}
+	}
+
+	// The tokens of the previous secret stay valid until the next rotation
+	s.Status = func(net.Addr) (*QueryStatus, error) { return testQueryStatus(), nil }
+	s.mu.Lock()
+	s.rotateAt = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+	if s.handle(queryStatRequest(token, false), queryAddr) == nil {
+		t.Error("previous token rejected")
+	}
+	if queryHandshake(t, s, queryAddr) == token {
+		t.Error("token not renewed")
+	}
+	s.mu.Lock()
+	s.rotateAt = time.Now().Add(-time.Hour)
+	s.mu.Unlock()
+	if s.handle(queryStatRequest(token, false), queryAddr) != nil {
+		t.Error("expired token accepted")
+	}
+}
+
+func TestQueryBasicStat(t *testing.T) {
+	s := &QueryServer{Status: func(net.Addr) (*QueryStatus, error) { return testQueryStatus(), nil }}
+	token := queryHandshake(t, s, queryAddr)
+
+	response := s.handle(queryStatRequest(token, false), queryAddr)
+	want := []byte("\x00\x01\x02\x03\x04A Minecraft Server\x00SMP\x00world\x002\x0020\x00\xDD\x63127.0.0.1\x00")
+	if !bytes.Equal(response, want) {
+		t.Fatalf("got %q, want %q", response, want)
+	}
+
+	var qs QueryStatus
+	if err := qs.parseBasic(response[5:]); err != nil {
+		t.Fatal(err)
+	}
+	basic := QueryStatus{MOTD: "A Minecraft Server", GameType: "SMP", Map: "world", NumPlayers: 2, MaxPlayers: 20, HostPort: 25565, HostIP: "127.0.0.1"}
+	if !reflect.DeepEqual(qs, basic) {
+		t.Errorf("got %+v, want %+v", qs, basic)
+	}
+
+	for _, b := range []string{"motd\x00SMP\x00world\x00two\x0020\x00\xDD\x63127.0.0.1\x00", "motd\x00SMP\x00world\x002\x0020\x00\xDD", "motd"} {
+		if err := qs.parseBasic([]byte(b)); err == nil {
+			t.Errorf("%q: no error", b)
+		}
+	}
+}
+
+func TestQueryFullStat(t *testing.T) {
+	s := &QueryServer{Status: func(net.Addr) (*QueryStatus, error) { return testQueryStatus(), nil }}
+	token := queryHandshake(t, s, queryAddr)
+
+	response := s.handle(queryStatRequest(token, true), queryAddr)
+	want := "\x00\x01\x02\x03\x04splitnum\x00\x80\x00" +
+		"hostname\x00A Minecraft Server\x00gametype\x00SMP\x00game_id\x00MINECRAFT\x00version\x001.21\x00plugins\x00\x00" +
+		"map\x00world\x00numplayers\x002\x00maxplayers\x0020\x00hostport\x0025565\x00hostip\x00127.0.0.1\x00\x00" +
+		"\x01player_\x00\x00Steve\x00Alex\x00\x00"
+	if string(response) != want {
+		t.Fatalf("got %q, want %q", response, want)
+	}
+
+	var qs QueryStatus
+	if err := qs.parseFull(response[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&qs, testQueryStatus()) {
+		t.Errorf("got %+v, want %+v", qs, testQueryStatus())
+	}
+}
+
+func TestQueryFullStatSize(t *testing.T) {
+	status := testQueryStatus()
+	status.Players = make([]string, 200)
+	for i := range status.Players {
+		status.Players[i] = "Player" + strconv.Itoa(i) + strings.Repeat("_", 5)
+	}
+	s := &QueryServer{Status: func(net.Addr) (*QueryStatus, error) { return status, nil }}
+	token := queryHandshake(t, s, queryAddr)
+
+	response := s.handle(queryStatRequest(token, true), queryAddr)
+	if len(response) > queryMaxPacketSize {
+		t.Errorf("got a response of %d bytes, want at most %d", len(response), queryMaxPacketSize)
+	}
+	var qs QueryStatus
+	if err := qs.parseFull(response[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(qs.Players) == 0 || len(qs.Players) == len(status.Players) || !reflect.DeepEqual(qs.Players, status.Players[:len(qs.Players)]) {
+		t.Errorf("got %d players, want the first players that fit", len(qs.Players))
+	}
+	if qs.NumPlayers != status.NumPlayers {
+		t.Errorf("got %d players online, want %d", qs.NumPlayers, status.NumPlayers)
+	}
+}
+
+func TestQueryIgnored(t *testing.T) {
+	s := &QueryServer{Status: func(net.Addr) (*QueryStatus, error) { return testQueryStatus(), nil }}
+	token := queryHandshake(t, s, queryAddr)
+
+	for _, test := range []struct {
+		name    string
+		request []byte
+		addr    net.Addr
+	}{
+		{"wrong magic", append([]byte{0xFE, 0xFE}, queryStatRequest(token, false)[2:]...), queryAddr},
+		{"unknown type", append([]byte{0xFE, 0xFD, 0x01}, queryStatRequest(token, false)[3:]...), queryAddr},
+		{"short request", queryStatRequest(token, false)[:9], queryAddr},
+		{"wrong token", queryStatRequest(token+1, false), queryAddr},
+		{"other address", queryStatRequest(token, false), &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 56324}},
+	} {
+		if response := s.handle(test.request, test.addr); response != nil {
+			t.Errorf("%s: got %q", test.name, response)
+		}
+	}
+}
+
+// queryDialer connects the Querier to the QueryServer through a pipe, each
+// write being read as a datagram
+type queryDialer struct {
+	server *QueryServer
+}
+
+func (d queryDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, queryMaxPacketSize)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			if response := d.server.handle(buf[:n], queryAddr); response != nil {
+				if _, err := server.Write(response); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return client, nil
+}
+
+func TestQuerier(t *testing.T) {
+	s := &QueryServer{Status: func(net.Addr) (*QueryStatus, error) { return testQueryStatus(), nil }}
+	q := Querier{Dialer: queryDialer{s}}
+
+	basic, err := q.BasicStat(context.Background(), "127.0.0.1:25565")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if basic.MOTD != "A Minecraft Server" || basic.NumPlayers != 2 || basic.HostPort != 25565 || basic.Players != nil {
+		t.Errorf("basic stat: got %+v", basic)
+	}
+
+	full, err := q.FullStat(context.Background(), "127.0.0.1:25565")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(full, testQueryStatus()) {
+		t.Errorf("full stat: got %+v, want %+v", full, testQueryStatus())
+	}
+
+	// A server without status does not answer
+	q = Querier{Dialer: queryDialer{&QueryServer{}}, Timeout: 50 * time.Millisecond}
+	if _, err := q.BasicStat(context.Background(), "127.0.0.1:25565"); err == nil {
+		t.Error("no status: no error")
+	}
+}
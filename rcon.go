@@ -0,0 +1,329 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RCON packet types.
+const (
+	RCONTypeResponse     = 0
+	RCONTypeCommand      = 2
+	RCONTypeAuthResponse = 2
+	RCONTypeAuth         = 3
+)
+
+// RCONAuthFailedID is the request ID of the response to a failed authentication.
+const RCONAuthFailedID = -1
+
+const (
+	// rconHeaderLength is the length of the request ID, type and the two terminating null bytes.
+	rconHeaderLength = 10
+	// rconMaxResponsePayload is the maximum payload of a response packet, longer responses are split.
+	rconMaxResponsePayload = 4096
+	// rconMaxPacketLength is the maximum length of a packet, without its leading length.
+	rconMaxPacketLength = rconHeaderLength + rconMaxResponsePayload
+)
+
+// defaultRCONTimeout is the timeout of an exchange of a RCONClient.
+const defaultRCONTimeout = 10 * time.Second
+
+// ErrRCONAuth is returned when the RCON password is rejected.
+var ErrRCONAuth = errors.New("rcon: authentication failed")
+
+// --- RCONPacket ---
+
+// RCONPacket is a packet of the RCON protocol.
+// Unlike game packets, its fields are little-endian and its length is a fixed-size integer.
+type RCONPacket struct {
+	RequestID int32
+	Type      int32
+	Payload   string
+}
+
+// Pack writes the packet to the writer.
+func (p *RCONPacket) Pack(writer io.Writer) error {
+	b := make([]byte, 4+rconHeaderLength+len(p.Payload))
+	binary.LittleEndian.PutUint32(b, uint32(rconHeaderLength+len(p.Payload)))
+	binary.LittleEndian.PutUint32(b[4:], uint32(p.RequestID))
+	binary.LittleEndian.PutUint32(b[8:], uint32(p.Type))
+	copy(b[12:], p.Payload)
+	// The payload and an empty string are null-terminated, already zeroed
+	_, err := writer.Write(b)
+	return err
+}
+
+// Unpack reads the packet from the reader.
+func (p *RCONPacket) Unpack(reader io.Reader) error {
+	var length int32
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return err
+	}
+	if length < 0 || length > rconMaxPacketLength {
+		return &LimitError{Limit: "RCON packet length", Value: int64(length), Max: rconMaxPacketLength}
+	}
+	// At least the request ID, the type and the payload terminator
+	if length < rconHeaderLength-1 {
+		return fmt.Errorf("rcon: packet length %d is too short", length)
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(reader, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	p.RequestID = int32(binary.LittleEndian.Uint32(b))
+	p.Type = int32(binary.LittleEndian.Uint32(b[4:]))
+	// Some clients omit the padding, only the payload terminator is required
+	payload := b[8:]
+	if i := bytes.IndexByte(payload, 0); i >= 0 {
+		payload = payload[:i]
+	} else {
+		return fmt.Errorf("rcon: payload is not null-terminated")
+	}
+	p.Payload = string(payload)
+	return nil
+}
+
+// --- RCONServer ---
+
+// RCONHandler executes the RCON command and returns its output.
+type RCONHandler func(command string) string
+
+// RCONServer serves the RCON protocol.
+type RCONServer struct {
+	// Password is the password clients must authenticate with.
+	// Authentication always fails if it is empty.
+	Password string
+	// Handler executes the commands of authenticated clients.
+	Handler RCONHandler
+	// IdleTimeout closes the connections idle for this duration. If zero, they are never closed.
+	IdleTimeout time.Duration
+}
+
+// ListenAndServe listens on the TCP address and serves the RCON clients.
+func (s *RCONServer) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Serve serves the RCON clients accepted by the listener, until accepting fails.
+func (s *RCONServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves the RCON client on the connection, and closes it.
+func (s *RCONServer) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+
+	authenticated := false
+	var p RCONPacket
+	for {
+		if s.IdleTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		if err := p.Unpack(conn); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var err error
+		switch {
+		case p.Type == RCONTypeAuth:
+			authenticated = s.Password != "" &&
+				subtle.ConstantTimeCompare([]byte(p.Payload), []byte(s.Password)) == 1
+			id := p.RequestID
+			if !authenticated {
+				id = RCONAuthFailedID
+			}
+			err = (&RCONPacket{RequestID: id, Type: RCONTypeAuthResponse}).Pack(conn)
+
+		case !authenticated:
+			err = (&RCONPacket{RequestID: RCONAuthFailedID, Type: RCONTypeAuthResponse}).Pack(conn)
+
+		case p.Type == RCONTypeCommand:
+			var output string
+			if s.Handler != nil {
+				output = s.Handler(p.Payload)
+			}
+			err = writeRCONResponse(conn, p.RequestID, output)
+
+		default:
+			// Like the vanilla server. Clients rely on it to detect the end of multi-packet responses.
+			err = writeRCONResponse(conn, p.RequestID, fmt.Sprintf("Unknown request %x", p.Type))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeRCONResponse writes the response, split in several packets if needed
+func writeRCONResponse(w io.Writer, requestID int32, output string) error {
+	for {
+		n := len(output)
+		if n > rconMaxResponsePayload {
+			n = rconMaxResponsePayload
+		}
+		p := RCONPacket{RequestID: requestID, Type: RCONTypeResponse, Payload: output[:n]}
+		if err := p.Pack(w); err != nil {
+			return err
+		}
+		output = output[n:]
+		if output == "" {
+			return nil
+		}
+	}
+}
+
+// --- RCONClient ---
+
+// RCONClient is an authenticated RCON connection.
+// It is safe for concurrent use, commands being sent one at a time.
+type RCONClient struct {
+	// Timeout is the timeout of a command. If zero, 10 seconds is used.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	nextID int32
+}
+
+// DialRCON connects to the RCON server at the TCP address and authenticates with the password.
+func DialRCON(ctx context.Context, address, password string) (*RCONClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewRCONClient(conn)
+	if err := c.authenticate(ctx, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewRCONClient creates a RCONClient on the connection.
+// The client must then be authenticated by Authenticate.
+func NewRCONClient(conn net.Conn) *RCONClient {
+	return &RCONClient{conn: conn, nextID: 1}
+}
+
+// Authenticate authenticates the client with the password.
+func (c *RCONClient) Authenticate(password string) error {
+	return c.authenticate(context.Background(), password)
+}
+
+func (c *RCONClient) authenticate(ctx context.Context, password string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setDeadline(ctx)
+
+	id := c.requestID()
+	if err := (&RCONPacket{RequestID: id, Type: RCONTypeAuth, Payload: password}).Pack(c.conn); err != nil {
+		return err
+	}
+
+	var p RCONPacket
+	for {
+		if err := p.Unpack(c.conn); err != nil {
+			return err
+		}
+		// Some servers send an empty response before the authentication response
+		if p.Type != RCONTypeAuthResponse {
+			continue
+		}
+		if p.RequestID != id {
+			return ErrRCONAuth
+		}
+		return nil
+	}
+}
+
+// Command executes the command on the server and returns its output.
+// Responses split in several packets are reassembled.
+func (c *RCONClient) Command(command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setDeadline(context.Background())
+
+	id := c.requestID()
+	if err := (&RCONPacket{RequestID: id, Type: RCONTypeCommand, Payload: command}).Pack(c.conn); err != nil {
+		return "", err
+	}
+	// The server answers this invalid request after the end of the response
+	end := c.requestID()
+	if err := (&RCONPacket{RequestID: end, Type: RCONTypeResponse}).Pack(c.conn); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	var p RCONPacket
+	for {
+		if err := p.Unpack(c.conn); err != nil {
+			return "", err
+		}
+		switch p.RequestID {
+		case id:
+			output.WriteString(p.Payload)
+		case end:
+			return output.String(), nil
+		case RCONAuthFailedID:
+			return "", ErrRCONAuth
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *RCONClient) Close() error {
+	return c.conn.Close()
+}
+
+// requestID returns a new request ID, never negative
+func (c *RCONClient) requestID() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+	return id
+}
+
+// setDeadline sets the deadline of the next exchange
+func (c *RCONClient) setDeadline(ctx context.Context) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultRCONTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+}
@@ -0,0 +1,212 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRCONPacket(t *testing.T) {
+	p := RCONPacket{RequestID: 5, Type: RCONTypeCommand, Payload: "list"}
+	var buf bytes.Buffer
+	if err := p.Pack(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{14, 0, 0, 0, 5, 0, 0, 0, 2, 0, 0, 0, 'l', 'i', 's', 't', 0, 0}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got % X, want % X", buf.Bytes(), want)
+	}
+	var got RCONPacket
+	if err := got.Unpack(&buf); err != nil || got != p {
+		t.Errorf("got %+v %v, want %+v", got, err, p)
+	}
+
+	// The padding may be omitted
+	if err := got.Unpack(bytes.NewReader([]byte{9, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0})); err != nil || got != (RCONPacket{RequestID: 1}) {
+		t.Errorf("no padding: got %+v %v", got, err)
+	}
+
+	if err := got.Unpack(bytes.NewReader([]byte{9, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 'a'})); err == nil {
+		t.Error("not null-terminated: no error")
+	}
+	if err := got.Unpack(bytes.NewReader(want[:10])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: got %v, want io.ErrUnexpectedEOF", err)
+	}
+	var limitErr *LimitError
+	if err := got.Unpack(bytes.NewReader([]byte{0x0B, 0x10, 0, 0})); !errors.As(err, &limitErr) || limitErr.Value != 4107 {
+		t.Errorf("too long: got %v, want LimitError", err)
+	}
+	if err := got.Unpack(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF})); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("negative length: got %v, want ErrLimitExceeded", err)
+	}
+	if err := got.Unpack(bytes.NewReader([]byte{8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0})); err == nil || errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("too short: got %v", err)
+	}
+}
+
+// rconPipe returns both sides of a loopback TCP connection.
+// Unlike net.Pipe, it is buffered, as the client writes two packets before reading.
+func rconPipe(t *testing.T) (client, server net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	client, err = net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err = l.Accept()
+	if err != nil {
+		client.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+// serveRCON serves the RCON server on a connection and returns the client side
+func serveRCON(t *testing.T, s *RCONServer) net.Conn {
+	client, server := rconPipe(t)
+	go s.ServeConn(server)
+	return client
+}
+
+func TestRCONServerSplit(t *testing.T) {
+	long := strings.Repeat("0123456789", 1000)
+	conn := serveRCON(t, &RCONServer{Password: "secret", Handler: func(command string) string {
+		return map[string]string{"long": long, "exact": long[:4096], "empty": ""}[command]
+	}})
+
+	send := func(p RCONPacket) {
+		if err := p.Pack(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive := func() RCONPacket {
+		var p RCONPacket
+		if err := p.Unpack(conn); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	send(RCONPacket{RequestID: 1, Type: RCONTypeAuth, Payload: "secret"})
+	if p := receive(); p != (RCONPacket{RequestID: 1, Type: RCONTypeAuthResponse}) {
+		t.Fatalf("got %+v", p)
+	}
+
+	tests := []struct {
+		command string
+		lengths []int
+	}{
+		{"long", []int{4096, 4096, 1808}},
+		{"exact", []int{4096}},
+		{"empty", []int{0}},
+	}
+	for _, test := range tests {
+		// The end of the response is detected by an invalid request
+		send(RCONPacket{RequestID: 2, Type: RCONTypeCommand, Payload: test.command})
+		send(RCONPacket{RequestID: 3, Type: RCONTypeResponse})
+		var output string
+		for _, length := range test.lengths {
+			p := receive()
+			if p.RequestID != 2 || p.Type != RCONTypeResponse || len(p.Payload) != length {
+				t.Errorf("%s: got ID %d, type %d, %d bytes, want %d bytes", test.command, p.RequestID, p.Type, len(p.Payload), length)
+			}
+			output += p.Payload
+		}
+		if want := map[string]string{"long": long, "exact": long[:4096]}[test.command]; output != want {
+			t.Errorf("%s: got %d bytes, want %d", test.command, len(output), len(want))
+		}
+		if p := receive(); p != (RCONPacket{RequestID: 3, Type: RCONTypeResponse, Payload: "Unknown request 0"}) {
+			t.Errorf("%s: got end %+v", test.command, p)
+		}
+	}
+}
+
+func TestRCONServerAuth(t *testing.T) {
+	for _, password := range []string{"secret", ""} {
+		conn := serveRCON(t, &RCONServer{Password: password, Handler: func(string) string { return "done" }})
+		for _, p := range []RCONPacket{
+			{RequestID: 1, Type: RCONTypeCommand, Payload: "list"},
+			{RequestID: 2, Type: RCONTypeAuth, Payload: "wrong"},
+			{RequestID: 3, Type: RCONTypeCommand, Payload: "list"},
+		} {
+			p.Pack(conn)
+			var got RCONPacket
+			if err := got.Unpack(conn); err != nil {
+				t.Fatal(err)
+			}
+			if got != (RCONPacket{RequestID: RCONAuthFailedID, Type: RCONTypeAuthResponse}) {
+				t.Errorf("password %q, request %d: got %+v", password, p.RequestID, got)
+			}
+		}
+	}
+}
+
+func TestRCONClient(t *testing.T) {
+	long := strings.Repeat("a", 10000)
+	conn := serveRCON(t, &RCONServer{Password: "secret", Handler: func(command string) string {
+		if command == "long" {
+			return long
+		}
+		return "ran " + command
+	}})
+
+	c := NewRCONClient(conn)
+	if err := c.Authenticate("secret"); err != nil {
+		t.Fatal(err)
+	}
+	for command, want := range map[string]string{"long": long, "list": "ran list", "": "ran "} {
+		if got, err := c.Command(command); err != nil || got != want {
+			t.Errorf("%q: got %d bytes %v, want %d bytes", command, len(got), err, len(want))
+		}
+	}
+
+	c = NewRCONClient(serveRCON(t, &RCONServer{Password: "secret"}))
+	if err := c.Authenticate("wrong"); err != ErrRCONAuth {
+		t.Errorf("wrong password: got %v, want ErrRCONAuth", err)
+	}
+	if _, err := c.Command("list"); err != ErrRCONAuth {
+		t.Errorf("not authenticated: got %v, want ErrRCONAuth", err)
+	}
+}
+
+func TestRCONClientServerQuirks(t *testing.T) {
+	client, server := rconPipe(t)
+	go func() {
+		var p RCONPacket
+		if p.Unpack(server) != nil {
+			return
+		}
+		// An empty response before the authentication response
+		(&RCONPacket{RequestID: p.RequestID, Type: RCONTypeResponse}).Pack(server)
+		(&RCONPacket{RequestID: p.RequestID, Type: RCONTypeAuthResponse}).Pack(server)
+
+		var command, end RCONPacket
+		if command.Unpack(server) != nil || end.Unpack(server) != nil {
+			return
+		}
+		// Fragments of any size, and packets of other requests, are reassembled
+		for _, payload := range []string{"frag", "men", "ted"} {
+			(&RCONPacket{RequestID: command.RequestID, Type: RCONTypeResponse, Payload: payload}).Pack(server)
+			(&RCONPacket{RequestID: 1000, Type: RCONTypeResponse, Payload: "other"}).Pack(server)
+		}
+		(&RCONPacket{RequestID: end.RequestID, Type: RCONTypeResponse}).Pack(server)
+	}()
+
+	c := NewRCONClient(client)
+	if err := c.Authenticate("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Command("list"); err != nil || got != "fragmented" {
+		t.Errorf("got %q %v, want fragmented", got, err)
+	}
+}
@@ -0,0 +1,120 @@
+package proto
+
+// Registries are sent to the client before the play state: from 1.16.2 to 1.20.1
+// as a single registry codec in the Join Game packet, from 1.20.2 to 1.20.4 as
+// the same codec in a RegistryData packet, and from 1.20.5 as a RegistryData
+// packet per registry.
+
+// Identifiers of the registries sent to the client.
+const (
+	DimensionTypeRegistry   = "minecraft:dimension_type"
+	BiomeRegistry           = "minecraft:worldgen/biome"
+	ChatTypeRegistry        = "minecraft:chat_type"
+	DamageTypeRegistry      = "minecraft:damage_type"
+	TrimPatternRegistry     = "minecraft:trim_pattern"
+	TrimMaterialRegistry    = "minecraft:trim_material"
+	BannerPatternRegistry   = "minecraft:banner_pattern"
+	WolfVariantRegistry     = "minecraft:wolf_variant"
+	PaintingVariantRegistry = "minecraft:painting_variant"
+	JukeboxSongRegistry     = "minecraft:jukebox_song"
+	EnchantmentRegistry     = "minecraft:enchantment"
+)
+
+// RegistryElement is an element of a registry, such as a DimensionType.
+type RegistryElement interface {
+	// NBT returns the element as an NBT compound, in the layout of the given protocol version.
+	NBT(protocol int32) NBTCompound
+}
+
+// RawElement is a registry element given as NBT, as sent to the client.
+// It is used for the registries without model, such as minecraft:enchantment.
+type RawElement NBTCompound
+
+// NBT returns the element, whatever the protocol version.
+func (e RawElement) NBT(protocol int32) NBTCompound {
+	return NBTCompound(e)
+}
+
+// NamedElement is an element of a registry with its name, such as minecraft:overworld.
+type NamedElement struct {
+	Name    string
+	Element RegistryElement
+}
+
+// --- Registry ---
+
+// Registry is a registry sent to the client.
+// The numeric ID of an element is its index in Entries.
+type Registry struct {
+	ID      string
+	Entries []NamedElement
+}
+
+// Element returns the element with the given name and its numeric ID,
+// or nil and -1 if there is none.
+func (r *Registry) Element(name string) (RegistryElement, int) {
+	for i, e := range r.Entries {
+		if e.Name == name {
+			return e.Element, i
+		}
+	}
+	return nil, -1
+}
+
+// RegistryData returns the RegistryData packet of the registry, in the
+// layout of the given protocol version, 1.20.5 or later.
+func (r *Registry) RegistryData(protocol int32) *RegistryData {
+	entries := make(RegistryEntries, len(r.Entries))
+	for i, e := range r.Entries {
+		entries[i] = RegistryEntry{ID: Identifier(e.Name), Data: NBTTag{Value: e.Element.NBT(protocol)}}
+	}
+	return &RegistryData{RegistryID: Identifier(r.ID), Entries: entries}
+}
+
+// --- Registries ---
+
+// Registries are the registries sent to the client.
+type Registries []Registry
+
+// Registry returns the registry with the given identifier, or nil if there is none.
+func (rs Registries) Registry(id string) *Registry {
+	for i := range rs {
+		if rs[i].ID == id {
+			return &rs[i]
+		}
+	}
+	return nil
+}
+
+// Codec returns the registry codec of the registries, in the layout of the
+// given protocol version, from 1.16.2 to 1.20.4.
+// Before 1.20.2, it is sent in a NamedNBTTag with an empty name.
+func (rs Registries) Codec(protocol int32) NBTCompound {
+	codec := make(NBTCompound, len(rs))
+	for _, r := range rs {
+		values := make(NBTList, len(r.Entries))
+		for i, e := range r.Entries {
+			values[i] = NBTCompound{
+				"name":    e.Name,
+				"id":      int32(i),
+				"element": e.Element.NBT(protocol),
+			}
+		}
+		codec[r.ID] = NBTCompound{"type": r.ID, "value": values}
+	}
+	return codec
+}
+
+// RegistryData returns the RegistryData packets of the registries, in the
+// layout of the given protocol version, 1.20.2 or later: before 1.20.5, a
+// single packet with the registry codec.
+func (rs Registries) RegistryData(protocol int32) []RegistryData {
+	if protocol != 0 && protocol < Protocol1_20_5 {
+		return []RegistryData{{Codec: NBTTag{Value: rs.Codec(protocol)}}}
+	}
+	packets := make([]RegistryData, len(rs))
+	for i := range rs {
+		packets[i] = *rs[i].RegistryData(protocol)
+	}
+	return packets
+}
@@ -0,0 +1,297 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// registryProtocols are the protocol versions with vanilla registries, and
+// the registries they require
+var registryProtocols = []struct {
+	protocol   int32
+	registries []string
+}{
+	{Protocol1_16_2, []string{DimensionTypeRegistry, BiomeRegistry}},
+	{Protocol1_17, []string{DimensionTypeRegistry, BiomeRegistry}},
+	{Protocol1_18, []string{DimensionTypeRegistry, BiomeRegistry}},
+	{Protocol1_18_2, []string{DimensionTypeRegistry, BiomeRegistry}},
+	{Protocol1_19, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry}},
+	{Protocol1_19_1, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry}},
+	{Protocol1_19_3, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry}},
+	{Protocol1_19_4, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry}},
+	{Protocol1_20, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry, TrimPatternRegistry, TrimMaterialRegistry}},
+	{Protocol1_20_2, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry, TrimPatternRegistry, TrimMaterialRegistry}},
+	{Protocol1_20_3, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry, TrimPatternRegistry, TrimMaterialRegistry}},
+	{Protocol1_20_5, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry, TrimPatternRegistry, TrimMaterialRegistry,
+		BannerPatternRegistry, WolfVariantRegistry}},
+	{Protocol1_21, []string{DimensionTypeRegistry, BiomeRegistry, ChatTypeRegistry, DamageTypeRegistry, TrimPatternRegistry, TrimMaterialRegistry,
+		BannerPatternRegistry, WolfVariantRegistry, PaintingVariantRegistry, JukeboxSongRegistry, EnchantmentRegistry}},
+}
+
+// sentElement is a registry element, as decoded by the client
+type sentElement struct {
+	name string
+	nbt  NBTCompound
+}
+
+// sentRegistries encodes the vanilla registries of the protocol version as they
+// are sent to the client, and returns the decoded elements by registry
+func sentRegistries(t *testing.T, protocol int32) map[string][]sentElement {
+	t.Helper()
+	registries, err := VanillaRegistries(protocol)
+	if err != nil {
+		t.Fatalf("protocol %d: %v", protocol, err)
+	}
+
+	sent := make(map[string][]sentElement)
+	if protocol >= Protocol1_20_5 {
+		for _, packet := range registries.RegistryData(protocol) {
+			var data RegistryData
+			roundTrip(t, protocol, &packet, &data)
+			elements := make([]sentElement, len(data.Entries))
+			for i, e := range data.Entries {
+				elements[i].name = string(e.ID)
+				if elements[i].nbt, _ = e.Data.Value.(NBTCompound); elements[i].nbt == nil {
+					t.Errorf("protocol %d: %s %s: got %#v", protocol, data.RegistryID, e.ID, e.Data.Value)
+				}
+			}
+			sent[string(data.RegistryID)] = elements
+		}
+		return sent
+	}
+
+	// Before 1.20.5, the registries are sent in the codec
+	var codec interface{}
+	if protocol >= Protocol1_20_2 {
+		packets := registries.RegistryData(protocol)
+		if len(packets) != 1 {
+			t.Fatalf("protocol %d: got %d RegistryData packets, want 1", protocol, len(packets))
+		}
+		var data RegistryData
+		roundTrip(t, protocol, &packets[0], &data)
+		codec = data.Codec.Value
+	} else {
+		var buf bytes.Buffer
+		if _, err := (NamedNBTTag{Value: registries.Codec(protocol)}).WriteTo(&buf); err != nil {
+			t.Fatalf("protocol %d: %v", protocol, err)
+		}
+		var tag NamedNBTTag
+		if _, err := tag.ReadFrom(&buf); err != nil {
+			t.Fatalf("protocol %d: %v", protocol, err)
+		}
+		codec = tag.Value
+	}
+
+	compound, ok := codec.(NBTCompound)
+	if !ok {
+		t.Fatalf("protocol %d: got codec %#v", protocol, codec)
+	}
+	for id, r := range compound {
+		registry, _ := r.(NBTCompound)
+		values, _ := registry["value"].(NBTList)
+		if registry["type"] != id {
+			t.Errorf("protocol %d: %s: got type %#v", protocol, id, registry["type"])
+		}
+		elements := make([]sentElement, len(values))
+		for i, v := range values {
+			entry, _ := v.(NBTCompound)
+			elements[i].name, _ = entry["name"].(string)
+			elements[i].nbt, _ = entry["element"].(NBTCompound)
+			if entry["id"] != int32(i) || elements[i].name == "" || elements[i].nbt == nil {
+				t.Errorf("protocol %d: %s: got entry %d %#v", protocol, id, i, entry)
+			}
+		}
+		sent[id] = elements
+	}
+	return sent
+}
+
+func TestVanillaRegistries(t *testing.T) {
+	for _, test := range registryProtocols {
+		sent := sentRegistries(t, test.protocol)
+		ids := make([]string, 0, len(sent))
+		for id := range sent {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		want := append([]string(nil), test.registries...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("protocol %d: got registries %v, want %v", test.protocol, ids, want)
+		}
+
+		// The client needs the vanilla elements of the registries it uses
+		for _, id := range []string{DimensionTypeRegistry, BiomeRegistry} {
+			if len(sent[id]) == 0 {
+				t.Errorf("protocol %d: no element in %s", test.protocol, id)
+			}
+		}
+	}
+
+	for _, protocol := range []int32{Protocol1_16, Protocol1_21_2} {
+		if _, err := VanillaRegistries(protocol); !errors.Is(err, ErrUnsupportedProtocol) {
+			t.Errorf("protocol %d: got %v, want ErrUnsupportedProtocol", protocol, err)
+		}
+	}
+}
+
+func TestVanillaDamageTypes(t *testing.T) {
+	// The damage types added by each version are looked up by the client
+	tests := []struct {
+		protocol int32
+		present  []string
+		absent   []string
+	}{
+		{Protocol1_19_4, []string{"minecraft:in_fire", "minecraft:sonic_boom"}, []string{"minecraft:generic_kill", "minecraft:outside_border"}},
+		{Protocol1_20, []string{"minecraft:generic_kill", "minecraft:outside_border"}, []string{"minecraft:spit", "minecraft:wind_charge"}},
+		{Protocol1_20_5, []string{"minecraft:spit", "minecraft:wind_charge"}, []string{"minecraft:mace_smash"}},
+		{Protocol1_21, []string{"minecraft:mace_smash"}, nil},
+	}
+	for _, test := range tests {
+		names := make(map[string]bool)
+		for _, e := range sentRegistries(t, test.protocol)[DamageTypeRegistry] {
+			names[e.name] = true
+		}
+		for _, name := range test.present {
+			if !names[name] {
+				t.Errorf("protocol %d: no %s", test.protocol, name)
+			}
+		}
+		for _, name := range test.absent {
+			if names[name] {
+				t.Errorf("protocol %d: unexpected %s", test.protocol, name)
+			}
+		}
+	}
+}
+
+func TestVanillaRegistryElements(t *testing.T) {
+	tests := []struct {
+		protocol int32
+		registry string
+		element  string
+		// path are the keys of the field, separated by dots
+		path string
+		// want is the decoded value of the field, nil if it must not be sent
+		want interface{}
+	}{
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:overworld", "infiniburn", "minecraft:infiniburn_overworld"},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:overworld", "has_skylight", int8(1)},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:overworld", "logical_height", int32(256)},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:overworld", "min_y", nil},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:overworld", "fixed_time", nil},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:the_nether", "fixed_time", int64(18000)},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:the_nether", "coordinate_scale", float64(8)},
+		{Protocol1_16_2, DimensionTypeRegistry, "minecraft:the_nether", "monster_spawn_light_level", nil},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:plains", "precipitation", "rain"},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:plains", "category", "plains"},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:plains", "depth", float32(0.125)},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:plains", "effects.mood_sound.sound", "minecraft:ambient.cave"},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:nether_wastes", "precipitation", "none"},
+		{Protocol1_16_2, BiomeRegistry, "minecraft:nether_wastes", "has_precipitation", nil},
+
+		{Protocol1_19, DimensionTypeRegistry, "minecraft:overworld", "infiniburn", "#minecraft:infiniburn_overworld"},
+		{Protocol1_19, DimensionTypeRegistry, "minecraft:overworld", "min_y", int32(-64)},
+		{Protocol1_19, DimensionTypeRegistry, "minecraft:overworld", "height", int32(384)},
+		{Protocol1_19, DimensionTypeRegistry, "minecraft:the_nether", "monster_spawn_light_level", int32(7)},
+		{Protocol1_19, DimensionTypeRegistry, "minecraft:the_nether", "monster_spawn_block_light_limit", int32(15)},
+		{Protocol1_19, BiomeRegistry, "minecraft:plains", "precipitation", "rain"},
+		{Protocol1_19, BiomeRegistry, "minecraft:plains", "category", nil},
+		{Protocol1_19, BiomeRegistry, "minecraft:plains", "depth", nil},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:chat", "chat.decoration.translation_key", "chat.type.text"},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:chat", "narration.priority", "chat"},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:system", "narration.priority", "system"},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:system", "chat.decoration", nil},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:game_info", "chat", nil},
+		{Protocol1_19, ChatTypeRegistry, "minecraft:msg_command", "chat.decoration.style.color", "gray"},
+
+		{Protocol1_19_4, BiomeRegistry, "minecraft:plains", "has_precipitation", int8(1)},
+		{Protocol1_19_4, BiomeRegistry, "minecraft:plains", "precipitation", nil},
+		{Protocol1_19_4, ChatTypeRegistry, "minecraft:chat", "chat.translation_key", "chat.type.text"},
+		{Protocol1_19_4, ChatTypeRegistry, "minecraft:chat", "narration.translation_key", "chat.type.text.narrate"},
+		{Protocol1_19_4, ChatTypeRegistry, "minecraft:chat", "narration.priority", nil},
+		{Protocol1_19_4, ChatTypeRegistry, "minecraft:msg_command_incoming", "chat.style.italic", int8(1)},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:in_fire", "message_id", "inFire"},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:in_fire", "scaling", "when_caused_by_living_non_player"},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:in_fire", "exhaustion", float32(0.1)},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:in_fire", "effects", "burning"},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:fall", "death_message_type", "fall_variants"},
+		{Protocol1_19_4, DamageTypeRegistry, "minecraft:generic", "effects", nil},
+
+		{Protocol1_20_5, TrimPatternRegistry, "minecraft:coast", "asset_id", "minecraft:coast"},
+		{Protocol1_20_5, TrimPatternRegistry, "minecraft:coast", "decal", int8(0)},
+		{Protocol1_20_5, TrimPatternRegistry, "minecraft:coast", "description.translate", "trim_pattern.minecraft.coast"},
+		{Protocol1_20_5, TrimMaterialRegistry, "minecraft:gold", "item_model_index", float32(0.6)},
+		{Protocol1_20_5, TrimMaterialRegistry, "minecraft:gold", "description.color", "#DEB12D"},
+		{Protocol1_20_5, BannerPatternRegistry, "minecraft:base", "translation_key", "block.minecraft.banner.base"},
+		{Protocol1_20_5, WolfVariantRegistry, "minecraft:pale", "wild_texture", "minecraft:entity/wolf/wolf"},
+		{Protocol1_20_5, WolfVariantRegistry, "minecraft:pale", "biomes", "minecraft:plains"},
+
+		{Protocol1_21, PaintingVariantRegistry, "minecraft:kebab", "asset_id", "minecraft:kebab"},
+		{Protocol1_21, PaintingVariantRegistry, "minecraft:backyard", "height", int32(4)},
+		{Protocol1_21, PaintingVariantRegistry, "minecraft:kebab", "title", nil},
+		{Protocol1_21, BannerPatternRegistry, "minecraft:flow", "asset_id", "minecraft:flow"},
+		{Protocol1_21, TrimPatternRegistry, "minecraft:bolt", "template_item", "minecraft:bolt_armor_trim_smithing_template"},
+	}
+
+	sent := make(map[int32]map[string][]sentElement)
+	for _, test := range tests {
+		if sent[test.protocol] == nil {
+			sent[test.protocol] = sentRegistries(t, test.protocol)
+		}
+		var value interface{}
+		for _, e := range sent[test.protocol][test.registry] {
+			if e.name == test.element {
+				value = e.nbt
+			}
+		}
+		if value == nil {
+			t.Errorf("protocol %d: no %s in %s", test.protocol, test.element, test.registry)
+			continue
+		}
+		for _, key := range strings.Split(test.path, ".") {
+			compound, _ := value.(NBTCompound)
+			value = compound[key]
+		}
+		if !reflect.DeepEqual(value, test.want) {
+			t.Errorf("protocol %d: %s %s: got %s %#v, want %#v", test.protocol, test.registry, test.element, test.path, value, test.want)
+		}
+	}
+
+	// The registries only used by items are sent empty
+	for _, id := range []string{JukeboxSongRegistry, EnchantmentRegistry} {
+		if elements := sent[Protocol1_21][id]; len(elements) != 0 {
+			t.Errorf("%s: got %d elements", id, len(elements))
+		}
+	}
+}
+
+func TestRegistryElement(t *testing.T) {
+	registries, err := VanillaRegistries(Protocol1_21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dimensions := registries.Registry(DimensionTypeRegistry)
+	if dimensions == nil || dimensions != &registries[0] {
+		t.Fatalf("got %v", dimensions)
+	}
+	if e, id := dimensions.Element("minecraft:the_nether"); id != 3 || e != dimensions.Entries[3].Element {
+		t.Errorf("got %v %d", e, id)
+	}
+	if e, id := dimensions.Element("minecraft:unknown"); e != nil || id != -1 {
+		t.Errorf("got %v %d, want nil -1", e, id)
+	}
+	if r := registries.Registry("minecraft:unknown"); r != nil {
+		t.Errorf("got %v, want nil", r)
+	}
+
+	// Raw elements are sent as is
+	raw := RawElement{"exclusive_set": "#minecraft:exclusive_set/armor"}
+	if got := raw.NBT(Protocol1_21); !reflect.DeepEqual(got, NBTCompound(raw)) {
+		t.Errorf("got %v", got)
+	}
+}
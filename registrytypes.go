@@ -0,0 +1,451 @@
+package proto
+
+// --- DimensionType ---
+
+// DimensionType is an element of the minecraft:dimension_type registry.
+type DimensionType struct {
+	// FixedTime is the time of day always shown, if not nil.
+	FixedTime          *int64
+	HasSkylight        bool
+	HasCeiling         bool
+	Ultrawarm          bool
+	Natural            bool
+	CoordinateScale    float64
+	BedWorks           bool
+	RespawnAnchorWorks bool
+	// MinY and Height are the vertical bounds of the dimension, sent from 1.17.
+	MinY          int32
+	Height        int32
+	LogicalHeight int32
+	// Infiniburn is the block tag of the blocks burning forever,
+	// such as #minecraft:infiniburn_overworld.
+	Infiniburn string
+	// Effects are the sky effects of the dimension, such as minecraft:overworld.
+	Effects      string
+	AmbientLight float32
+	PiglinSafe   bool
+	HasRaids     bool
+	// MonsterSpawnLightLevel and MonsterSpawnBlockLightLimit are sent from 1.19.
+	MonsterSpawnLightLevel      int32
+	MonsterSpawnBlockLightLimit int32
+}
+
+// NBT returns the dimension type in the layout of the given protocol version.
+func (d *DimensionType) NBT(protocol int32) NBTCompound {
+	infiniburn := d.Infiniburn
+	if protocol < Protocol1_18_2 && len(infiniburn) > 0 && infiniburn[0] == '#' {
+		// The tag was given by its name only
+		infiniburn = infiniburn[1:]
+	}
+
+	c := NBTCompound{
+		"has_skylight":         d.HasSkylight,
+		"has_ceiling":          d.HasCeiling,
+		"ultrawarm":            d.Ultrawarm,
+		"natural":              d.Natural,
+		"coordinate_scale":     d.CoordinateScale,
+		"bed_works":            d.BedWorks,
+		"respawn_anchor_works": d.RespawnAnchorWorks,
+		"logical_height":       d.LogicalHeight,
+		"infiniburn":           infiniburn,
+		"effects":              d.Effects,
+		"ambient_light":        d.AmbientLight,
+		"piglin_safe":          d.PiglinSafe,
+		"has_raids":            d.HasRaids,
+	}
+	if d.FixedTime != nil {
+		c["fixed_time"] = *d.FixedTime
+	}
+	if protocol >= Protocol1_17 {
+		c["min_y"], c["height"] = d.MinY, d.Height
+	}
+	if protocol >= Protocol1_19 {
+		c["monster_spawn_light_level"] = d.MonsterSpawnLightLevel
+		c["monster_spawn_block_light_limit"] = d.MonsterSpawnBlockLightLimit
+	}
+	return c
+}
+
+// --- Biome ---
+
+// Biome is an element of the minecraft:worldgen/biome registry.
+type Biome struct {
+	// HasPrecipitation is sent from 1.19.3. Before, the precipitation is
+	// rain or snow depending on Temperature, or none.
+	HasPrecipitation bool
+	Temperature      float32
+	// TemperatureModifier is none or frozen. If empty, it is not sent.
+	TemperatureModifier string
+	Downfall            float32
+	// Category is sent before 1.19, such as plains.
+	Category string
+	// Depth and Scale are sent before 1.18.
+	Depth   float32
+	Scale   float32
+	Effects BiomeEffects
+}
+
+// BiomeEffects are the visual and sound effects of a biome.
+type BiomeEffects struct {
+	FogColor      int32
+	WaterColor    int32
+	WaterFogColor int32
+	SkyColor      int32
+	// FoliageColor and GrassColor override the colormaps, if not nil.
+	FoliageColor *int32
+	GrassColor   *int32
+	// GrassColorModifier is none, dark_forest or swamp. If empty, it is not sent.
+	GrassColorModifier string
+	// AmbientSound is the sound event played in loop, if not empty.
+	AmbientSound string
+	MoodSound    *BiomeMoodSound
+}
+
+// BiomeMoodSound is the sound played randomly in dark places of a biome.
+type BiomeMoodSound struct {
+	Sound             string
+	TickDelay         int32
+	BlockSearchExtent int32
+	Offset            float64
+}
+
+// NBT returns the biome in the layout of the given protocol version.
+func (b *Biome) NBT(protocol int32) NBTCompound {
+	c := NBTCompound{
+		"temperature": b.Temperature,
+		"downfall":    b.Downfall,
+		"effects":     b.Effects.nbt(),
+	}
+	if b.TemperatureModifier != "" {
+		c["temperature_modifier"] = b.TemperatureModifier
+	}
+
+	if protocol >= Protocol1_19_3 {
+		c["has_precipitation"] = b.HasPrecipitation
+	} else {
+		precipitation := "none"
+		if b.HasPrecipitation {
+			precipitation = "rain"
+			if b.Temperature < 0.15 {
+				precipitation = "snow"
+			}
+		}
+		c["precipitation"] = precipitation
+	}
+	if protocol < Protocol1_19 {
+		c["category"] = b.Category
+	}
+	if protocol < Protocol1_18 {
+		c["depth"], c["scale"] = b.Depth, b.Scale
+	}
+	return c
+}
+
+func (e *BiomeEffects) nbt() NBTCompound {
+	c := NBTCompound{
+		"fog_color":       e.FogColor,
+		"water_color":     e.WaterColor,
+		"water_fog_color": e.WaterFogColor,
+		"sky_color":       e.SkyColor,
+	}
+	if e.FoliageColor != nil {
+		c["foliage_color"] = *e.FoliageColor
+	}
+	if e.GrassColor != nil {
+		c["grass_color"] = *e.GrassColor
+	}
+	if e.GrassColorModifier != "" {
+		c["grass_color_modifier"] = e.GrassColorModifier
+	}
+	if e.AmbientSound != "" {
+		c["ambient_sound"] = e.AmbientSound
+	}
+	if e.MoodSound != nil {
+		c["mood_sound"] = NBTCompound{
+			"sound":               e.MoodSound.Sound,
+			"tick_delay":          e.MoodSound.TickDelay,
+			"block_search_extent": e.MoodSound.BlockSearchExtent,
+			"offset":              e.MoodSound.Offset,
+		}
+	}
+	return c
+}
+
+// --- ChatType ---
+
+// ChatType is an element of the minecraft:chat_type registry.
+//
+// In 1.19, the messages may be shown in the chat or above the hotbar, and
+// narrated, each with an optional decoration: the messages are shown in the
+// chat unless Overlay is set, narrated if NarrationPriority is set, and the
+// decorations without TranslationKey are not sent.
+type ChatType struct {
+	Chat      ChatDecoration
+	Narration ChatDecoration
+
+	// Overlay is the decoration of the messages shown above the hotbar, in 1.19 only.
+	Overlay *ChatDecoration
+	// NarrationPriority is the priority of the narration, chat or system, in 1.19 only.
+	NarrationPriority string
+}
+
+// ChatDecoration is the way a chat message is shown or narrated.
+type ChatDecoration struct {
+	// TranslationKey is the key of the translation, such as chat.type.text.
+	TranslationKey string
+	// Parameters are the arguments of the translation: sender, target or content,
+	// and team_name instead of target in 1.19.
+	Parameters []string
+	// Style is the style of the message, if not nil. Its content is ignored.
+	Style *TextComponent
+}
+
+// NBT returns the chat type in the layout of the given protocol version.
+func (t *ChatType) NBT(protocol int32) NBTCompound {
+	if protocol != 0 && protocol < Protocol1_19_1 {
+		return t.nbt119()
+	}
+	return NBTCompound{
+		"chat":      t.Chat.nbt(),
+		"narration": t.Narration.nbt(),
+	}
+}
+
+// nbt119 returns the chat type in the layout of 1.19.
+func (t *ChatType) nbt119() NBTCompound {
+	display := func(d *ChatDecoration) NBTCompound {
+		c := NBTCompound{}
+		if d.TranslationKey != "" {
+			c["decoration"] = d.nbt()
+		}
+		return c
+	}
+
+	c := NBTCompound{}
+	if t.Overlay != nil {
+		c["overlay"] = display(t.Overlay)
+	} else {
+		c["chat"] = display(&t.Chat)
+	}
+	if t.NarrationPriority != "" {
+		narration := display(&t.Narration)
+		narration["priority"] = t.NarrationPriority
+		c["narration"] = narration
+	}
+	return c
+}
+
+func (d *ChatDecoration) nbt() NBTCompound {
+	parameters := make(NBTList, len(d.Parameters))
+	for i, p := range d.Parameters {
+		parameters[i] = p
+	}
+	// The style is required before 1.20.5.
+	var style interface{} = NBTCompound{}
+	if d.Style != nil {
+		style = textNBT(*d.Style)
+		if compound, ok := style.(NBTCompound); ok {
+			delete(compound, "text")
+		}
+	}
+	return NBTCompound{
+		"translation_key": d.TranslationKey,
+		"parameters":      parameters,
+		"style":           style,
+	}
+}
+
+// --- DamageType ---
+
+// DamageType is an element of the minecraft:damage_type registry.
+type DamageType struct {
+	// MessageID is the suffix of the translation keys of death messages.
+	MessageID string
+	// Scaling is never, always or when_caused_by_living_non_player.
+	Scaling    string
+	Exhaustion float32
+	// Effects is the sound played on damage, such as burning. If empty, it is not sent.
+	Effects string
+	// DeathMessageType is fall_variants or intentional_game_design. If empty, it is not sent.
+	DeathMessageType string
+}
+
+// NBT returns the damage type, whatever the protocol version.
+func (t *DamageType) NBT(protocol int32) NBTCompound {
+	c := NBTCompound{
+		"message_id": t.MessageID,
+		"scaling":    t.Scaling,
+		"exhaustion": t.Exhaustion,
+	}
+	if t.Effects != "" {
+		c["effects"] = t.Effects
+	}
+	if t.DeathMessageType != "" {
+		c["death_message_type"] = t.DeathMessageType
+	}
+	return c
+}
+
+// --- TrimPattern ---
+
+// TrimPattern is an element of the minecraft:trim_pattern registry.
+type TrimPattern struct {
+	AssetID      string
+	TemplateItem string
+	Description  TextComponent
+	// Decal is sent from 1.20.2.
+	Decal bool
+}
+
+// NBT returns the trim pattern in the layout of the given protocol version.
+func (t *TrimPattern) NBT(protocol int32) NBTCompound {
+	c := NBTCompound{
+		"asset_id":      t.AssetID,
+		"template_item": t.TemplateItem,
+		"description":   textNBT(t.Description),
+	}
+	if protocol >= Protocol1_20_2 {
+		c["decal"] = t.Decal
+	}
+	return c
+}
+
+// --- TrimMaterial ---
+
+// TrimMaterial is an element of the minecraft:trim_material registry.
+type TrimMaterial struct {
+	AssetName      string
+	Ingredient     string
+	ItemModelIndex float32
+	// OverrideArmorMaterials maps armor materials to the asset names used instead of AssetName.
+	OverrideArmorMaterials map[string]string
+	Description            TextComponent
+}
+
+// NBT returns the trim material, whatever the protocol version.
+func (t *TrimMaterial) NBT(protocol int32) NBTCompound {
+	c := NBTCompound{
+		"asset_name":       t.AssetName,
+		"ingredient":       t.Ingredient,
+		"item_model_index": t.ItemModelIndex,
+		"description":      textNBT(t.Description),
+	}
+	if len(t.OverrideArmorMaterials) > 0 {
+		overrides := make(NBTCompound, len(t.OverrideArmorMaterials))
+		for material, asset := range t.OverrideArmorMaterials {
+			overrides[material] = asset
+		}
+		c["override_armor_materials"] = overrides
+	}
+	return c
+}
+
+// --- BannerPattern ---
+
+// BannerPattern is an element of the minecraft:banner_pattern registry.
+type BannerPattern struct {
+	AssetID        string
+	TranslationKey string
+}
+
+// NBT returns the banner pattern, whatever the protocol version.
+func (p *BannerPattern) NBT(protocol int32) NBTCompound {
+	return NBTCompound{
+		"asset_id":        p.AssetID,
+		"translation_key": p.TranslationKey,
+	}
+}
+
+// --- WolfVariant ---
+
+// WolfVariant is an element of the minecraft:wolf_variant registry, in the
+// layout of 1.20.5 to 1.21.4.
+type WolfVariant struct {
+	WildTexture  string
+	TameTexture  string
+	AngryTexture string
+	// Biomes are the biomes the variant spawns in: biome names, or a single
+	// biome tag such as #minecraft:is_savanna.
+	Biomes []string
+}
+
+// NBT returns the wolf variant, whatever the protocol version.
+func (v *WolfVariant) NBT(protocol int32) NBTCompound {
+	var biomes interface{}
+	if len(v.Biomes) == 1 {
+		biomes = v.Biomes[0]
+	} else {
+		list := make(NBTList, len(v.Biomes))
+		for i, b := range v.Biomes {
+			list[i] = b
+		}
+		biomes = list
+	}
+	return NBTCompound{
+		"wild_texture":  v.WildTexture,
+		"tame_texture":  v.TameTexture,
+		"angry_texture": v.AngryTexture,
+		"biomes":        biomes,
+	}
+}
+
+// --- PaintingVariant ---
+
+// PaintingVariant is an element of the minecraft:painting_variant registry.
+type PaintingVariant struct {
+	AssetID string
+	// Width and Height are the size of the painting, in blocks.
+	Width  int32
+	Height int32
+	// Title and Author are sent from 1.21.2, if not nil.
+	Title  *TextComponent
+	Author *TextComponent
+}
+
+// NBT returns the painting variant in the layout of the given protocol version.
+func (v *PaintingVariant) NBT(protocol int32) NBTCompound {
+	c := NBTCompound{
+		"asset_id": v.AssetID,
+		"width":    v.Width,
+		"height":   v.Height,
+	}
+	if protocol >= Protocol1_21_2 {
+		if v.Title != nil {
+			c["title"] = textNBT(*v.Title)
+		}
+		if v.Author != nil {
+			c["author"] = textNBT(*v.Author)
+		}
+	}
+	return c
+}
+
+// --- JukeboxSong ---
+
+// JukeboxSong is an element of the minecraft:jukebox_song registry.
+type JukeboxSong struct {
+	SoundEvent      string
+	Description     TextComponent
+	LengthInSeconds float32
+	// ComparatorOutput is the redstone signal of a jukebox playing the song, from 0 to 15.
+	ComparatorOutput int32
+}
+
+// NBT returns the jukebox song, whatever the protocol version.
+func (s *JukeboxSong) NBT(protocol int32) NBTCompound {
+	return NBTCompound{
+		"sound_event":       s.SoundEvent,
+		"description":       textNBT(s.Description),
+		"length_in_seconds": s.LengthInSeconds,
+		"comparator_output": s.ComparatorOutput,
+	}
+}
+
+// textNBT returns the NBT value of a text component
+func textNBT(c TextComponent) interface{} {
+	tag, err := c.NBT()
+	if err != nil {
+		return c.Text
+	}
+	return tag.Value
+}
@@ -0,0 +1,322 @@
+package proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultScanConcurrency is the number of concurrent pings of a zero Scanner.
+const defaultScanConcurrency = 64
+
+// defaultScanTimeout is the timeout of each ping of a zero Scanner.
+const defaultScanTimeout = 5 * time.Second
+
+// scanMaxWaiting is the number of addresses, beyond the concurrency, that may
+// wait for their host to be pinged again.
+const scanMaxWaiting = 1024
+
+// ScanStatus classifies the result of a ping sent by a Scanner.
+type ScanStatus int
+
+const (
+	// ScanOK means that the server answered a modern status.
+	ScanOK ScanStatus = iota
+	// ScanLegacyOnly means that the server only answered a legacy ping.
+	ScanLegacyOnly
+	// ScanRefused means that the connection was refused.
+	ScanRefused
+	// ScanTimeout means that the server did not answer in time.
+	ScanTimeout
+	// ScanNetworkError means that the address could not be resolved or
+	// that the connection failed or was closed.
+	ScanNetworkError
+	// ScanProtocolError means that the server answered something else than a status.
+	ScanProtocolError
+	// ScanCanceled means that the scan was canceled before the ping completed.
+	ScanCanceled
+)
+
+var scanStatusNames = [...]string{
+	ScanOK:            "ok",
+	ScanLegacyOnly:    "legacy only",
+	ScanRefused:       "refused",
+	ScanTimeout:       "timeout",
+	ScanNetworkError:  "network error",
+	ScanProtocolError: "protocol error",
+	ScanCanceled:      "canceled",
+}
+
+func (s ScanStatus) String() string {
+	if s >= 0 && int(s) < len(scanStatusNames) {
+		return scanStatusNames[s]
+	}
+	return "unknown"
+}
+
+// ClassifyPingError returns the status of a ping that failed with err.
+func ClassifyPingError(err error) ScanStatus {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ScanCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ScanTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ScanRefused
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ScanTimeout
+	case errors.As(err, &dnsErr), isClosedByPeer(err):
+		return ScanNetworkError
+	case errors.As(err, new(*net.OpError)):
+		return ScanNetworkError
+	default:
+		return ScanProtocolError
+	}
+}
+
+// --- Scanner ---
+
+// Scanner pings many servers concurrently.
+// The zero value is ready to use.
+type Scanner struct {
+	// Pinger pings the servers. If nil, a zero Pinger is used.
+	Pinger *Pinger
+	// Concurrency is the maximum number of concurrent pings. If zero, 64 is used.
+	Concurrency int
+	// Timeout is the timeout of each ping, including the SRV resolution
+	// and the legacy fallback. If zero, 5 seconds is used.
+	Timeout time.Duration
+	// HostInterval is the minimum interval between the start of two pings
+	// of the same host, whatever its port. If zero, hosts are not rate limited.
+	// The addresses waiting for their host do not count in Concurrency, so
+	// the pings of other hosts go on meanwhile.
+	HostInterval time.Duration
+}
+
+// ScanResult is the result of the ping of an address by a Scanner.
+type ScanResult struct {
+	// Address is the scanned address, as received by the Scanner.
+	Address string
+	Status  ScanStatus
+	// Result is the result of the ping, nil if it failed.
+	Result *PingResult
+	// Err is the error of the ping, nil if it succeeded.
+	Err error
+}
+
+// Scan pings the addresses received from the channel, and sends the results
+// to the returned channel as they complete, in any order.
+// The returned channel is closed once the addresses channel is closed and all
+// the pings completed, or once the context is done.
+func (s *Scanner) Scan(ctx context.Context, addresses <-chan string) <-chan ScanResult {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultScanConcurrency
+	}
+
+	results := make(chan ScanResult)
+	limiter := &hostLimiter{interval: s.HostInterval, next: make(map[string]time.Time)}
+	// slots bounds the concurrent pings, pending the addresses being handled,
+	// including the ones waiting for their host
+	slots := make(chan struct{}, concurrency)
+	pending := make(chan struct{}, concurrency+scanMaxWaiting)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for {
+			select {
+			case pending <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			var address string
+			var ok bool
+			select {
+			case address, ok = <-addresses:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-pending
+					wg.Done()
+				}()
+				result := s.scan(ctx, limiter, slots, address)
+				select {
+				case results <- result:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}()
+	return results
+}
+
+// ScanAll pings all the addresses and returns the results in the order of the addresses.
+func (s *Scanner) ScanAll(ctx context.Context, addresses []string) []ScanResult {
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, address := range addresses {
+			select {
+			case in <- address:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	index := make(map[string][]int, len(addresses))
+	for i, address := range addresses {
+		index[address] = append(index[address], i)
+	}
+
+	results := make([]ScanResult, len(addresses))
+	done := make([]bool, len(addresses))
+	for result := range s.Scan(ctx, in) {
+		i := index[result.Address][0]
+		index[result.Address] = index[result.Address][1:]
+		results[i], done[i] = result, true
+	}
+
+	// Addresses not scanned because the context is done
+	for i, address := range addresses {
+		if !done[i] {
+			results[i] = ScanResult{Address: address, Status: ScanCanceled, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// scan pings the address once its host can be pinged again and a slot is free
+func (s *Scanner) scan(ctx context.Context, limiter *hostLimiter, slots chan struct{}, address string) ScanResult {
+	for {
+		if err := limiter.wait(ctx, address); err != nil {
+			return ScanResult{Address: address, Status: ClassifyPingError(err), Err: err}
+		}
+		// The slot is only taken once the host is ready, so that a rate limited
+		// host does not hold the pings of the others
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ScanResult{Address: address, Status: ClassifyPingError(ctx.Err()), Err: ctx.Err()}
+		}
+		// The start time is reserved once the slot is acquired, as waiting for
+		// the slot may have taken longer than the interval
+		if limiter.reserve(address) {
+			break
+		}
+		// Another ping of the host started meanwhile
+		<-slots
+	}
+	defer func() { <-slots }()
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pinger := s.Pinger
+	if pinger == nil {
+		pinger = new(Pinger)
+	}
+
+	result, err := pinger.Ping(ctx, address)
+	switch {
+	case err != nil:
+		return ScanResult{Address: address, Status: ClassifyPingError(err), Err: err}
+	case result.Legacy:
+		return ScanResult{Address: address, Status: ScanLegacyOnly, Result: result}
+	default:
+		return ScanResult{Address: address, Status: ScanOK, Result: result}
+	}
+}
+
+// hostLimiterCleanup is the number of hosts above which a hostLimiter forgets the idle ones.
+const hostLimiterCleanup = 1024
+
+// hostLimiter spaces out the pings of the same host
+type hostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait waits until the host of the address can be pinged again.
+// The start time must then be reserved with reserve.
+func (l *hostLimiter) wait(ctx context.Context, address string) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	host := limiterHost(address)
+	l.mu.Lock()
+	delay := time.Until(l.next[host])
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve reserves the start of a ping of the host of the address now,
+// reporting false if the host cannot be pinged yet
+func (l *hostLimiter) reserve(address string) bool {
+	if l.interval <= 0 {
+		return true
+	}
+
+	host := limiterHost(address)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.next[host]) {
+		return false
+	}
+	l.next[host] = now.Add(l.interval)
+	if len(l.next) > hostLimiterCleanup {
+		// Forget the hosts that can be pinged right away
+		for h, next := range l.next {
+			if next.Before(now) {
+				delete(l.next, h)
+			}
+		}
+	}
+	return true
+}
+
+// limiterHost returns the host of the address, whatever its port
+func limiterHost(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
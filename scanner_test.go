@@ -0,0 +1,92 @@
+package proto
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// refusingDialer records the start of each ping per host, and refuses the
+// connections after the delay of the host
+type refusingDialer struct {
+	delays map[string]time.Duration
+
+	mu     sync.Mutex
+	starts map[string][]time.Time
+}
+
+func (d *refusingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(address)
+	d.mu.Lock()
+	if d.starts == nil {
+		d.starts = make(map[string][]time.Time)
+	}
+	d.starts[host] = append(d.starts[host], time.Now())
+	d.mu.Unlock()
+
+	time.Sleep(d.delays[host])
+	return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+}
+
+func TestScannerHostInterval(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	dialer := &refusingDialer{delays: map[string]time.Duration{
+		"10.0.0.1": 100 * time.Millisecond,
+		"10.0.0.2": 100 * time.Millisecond,
+		"10.0.0.3": 5 * time.Millisecond,
+	}}
+	s := Scanner{Pinger: &Pinger{Dialer: dialer}, Concurrency: 2, HostInterval: interval}
+
+	// The slow hosts hold the slots while the pings of the last host wait for
+	// them, longer than the interval
+	addresses := make(chan string)
+	go func() {
+		defer close(addresses)
+		addresses <- "10.0.0.1:25565"
+		addresses <- "10.0.0.2:25565"
+		time.Sleep(10 * time.Millisecond)
+		for _, port := range []string{"25565", "25566", "25567", "25568"} {
+			addresses <- net.JoinHostPort("10.0.0.3", port)
+		}
+	}()
+	n := 0
+	for result := range s.Scan(context.Background(), addresses) {
+		if result.Status != ScanRefused {
+			t.Errorf("%s: got %v, want refused", result.Address, result.Status)
+		}
+		n++
+	}
+	if n != 6 {
+		t.Errorf("got %d results, want 6", n)
+	}
+
+	starts := dialer.starts["10.0.0.3"]
+	if len(starts) != 4 {
+		t.Fatalf("pinged %d times, want 4", len(starts))
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for i := 1; i < len(starts); i++ {
+		// The dialer is called shortly after the start is reserved
+		if gap := starts[i].Sub(starts[i-1]); gap < interval-time.Millisecond {
+			t.Errorf("pings started %v apart, want at least %v", gap, interval)
+		}
+	}
+}
+
+func TestScannerCanceled(t *testing.T) {
+	s := Scanner{Pinger: &Pinger{Dialer: &refusingDialer{}}, HostInterval: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The second ping of the host waits for the interval
+	results := s.ScanAll(ctx, []string{"10.0.0.1:25565", "10.0.0.1:25566"})
+	statuses := []ScanStatus{results[0].Status, results[1].Status}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+	if statuses[0] != ScanRefused || (statuses[1] != ScanTimeout && statuses[1] != ScanCanceled) {
+		t.Errorf("got %v, want one refused and one timed out", statuses)
+	}
+}
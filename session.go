@@ -0,0 +1,134 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultSessionServer is the URL of the Mojang session server.
+const DefaultSessionServer = "https://sessionserver.mojang.com"
+
+// ErrUnverifiedUsername is returned when the session server did not
+// authenticate the player.
+var ErrUnverifiedUsername = errors.New("failed to verify username")
+
+// SessionVerifier checks, on the server side, that a player joined the
+// server through the session server.
+type SessionVerifier interface {
+	// HasJoined returns the profile of the player that joined with the
+	// server hash, or ErrUnverifiedUsername.
+	HasJoined(ctx context.Context, username, serverHash string) (*GameProfile, error)
+}
+
+// --- SessionServer ---
+
+// SessionServer is a client of the Mojang session server API.
+// The zero value uses the Mojang session server.
+type SessionServer struct {
+	// URL is the base URL of the session server. If empty, DefaultSessionServer is used.
+	URL string
+	// Client sends the HTTP requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// HasJoined implements SessionVerifier.
+func (s *SessionServer) HasJoined(ctx context.Context, username, serverHash string) (*GameProfile, error) {
+	query := url.Values{"username": {username}, "serverId": {serverHash}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url()+"/session/minecraft/hasJoined?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, ErrUnverifiedUsername
+	default:
+		return nil, fmt.Errorf("session server: %s", resp.Status)
+	}
+
+	profile := new(GameProfile)
+	if err := json.NewDecoder(resp.Body).Decode(profile); err != nil {
+		return nil, fmt.Errorf("session server: %w", err)
+	}
+	return profile, nil
+}
+
+func (s *SessionServer) url() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return DefaultSessionServer
+}
+
+func (s *SessionServer) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// SessionAuthenticator joins, on the client side, a server through the session server.
+type SessionAuthenticator interface {
+	// Join tells the session server that the player is joining the server with the server hash.
+	Join(ctx context.Context, profile *GameProfile, serverHash string) error
+}
+
+// Join tells the session server that the player with the access token joins the server with the server hash.
+func (s *SessionServer) Join(ctx context.Context, accessToken string, profileID UUID, serverHash string) error {
+	body, err := json.Marshal(map[string]string{
+		"accessToken":     accessToken,
+		"selectedProfile": strings.Replace(profileID.String(), "-", "", -1),
+		"serverId":        serverHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url()+"/session/minecraft/join", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("session server: %s", resp.Status)
+	}
+	return nil
+}
+
+// --- AccessTokenAuth ---
+
+// AccessTokenAuth is a SessionAuthenticator joining servers with the access token of a player.
+type AccessTokenAuth struct {
+	AccessToken string
+	// Server is the session server. If nil, the Mojang session server is used.
+	Server *SessionServer
+}
+
+// Join implements SessionAuthenticator.
+func (a *AccessTokenAuth) Join(ctx context.Context, profile *GameProfile, serverHash string) error {
+	server := a.Server
+	if server == nil {
+		server = &SessionServer{}
+	}
+	return server.Join(ctx, a.AccessToken, profile.ID, serverHash)
+}
@@ -1,6 +1,6 @@
 package proto
 
-import "fmt"
+import "encoding/json"
 
 // --- Response ---
 
@@ -9,6 +9,10 @@ import "fmt"
 // Implements proto.Packet interface.
 type Response struct {
 	JSONResponse String
+	// Status is the typed form of JSONResponse.
+	// If set, ToRaw encodes it to JSONResponse. FromRaw decodes it from JSONResponse,
+	// or leaves it nil if JSONResponse is not a valid status: see DecodeStatus.
+	Status *StatusResponse
 }
 
 // Response_ID is the Response packet ID.
@@ -16,6 +20,13 @@ const Response_ID = 0x00
 
 // ToRaw marshals the Response Packet to the given RawPacket.
 func (pi *Response) ToRaw(p *RawPacket) (err error) {
+	if pi.Status != nil {
+		b, err := json.Marshal(pi.Status)
+		if err != nil {
+			return err
+		}
+		pi.JSONResponse = String(b)
+	}
 	p.ID = Response_ID
 	return p.Marshal(&pi.JSONResponse)
 }
@@ -23,9 +34,133 @@ func (pi *Response) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the Response Packet from the given RawPacket.
 func (pi *Response) FromRaw(p *RawPacket) (err error) {
 	if p.ID != Response_ID {
-		return fmt.Errorf("invalid packet ID for Response: %d", p.ID)
+		return &PacketIDError{Packet: "Response", Expect: Response_ID, Get: p.ID}
+	}
+	if err := p.unmarshalPacket(pi, &pi.JSONResponse); err != nil {
+		return err
+	}
+
+	pi.Status, _ = pi.DecodeStatus()
+	return nil
+}
+
+// DecodeStatus decodes the StatusResponse from JSONResponse.
+// A JSONResponse that is not a valid status is reported as a *FieldError.
+func (pi *Response) DecodeStatus() (*StatusResponse, error) {
+	status := new(StatusResponse)
+	if err := json.Unmarshal([]byte(pi.JSONResponse), status); err != nil {
+		return nil, &FieldError{Packet: "Response", Field: "JSONResponse", Err: err}
 	}
-	return p.Unmarshal(&pi.JSONResponse)
+	return status, nil
+}
+
+// --- StatusResponse ---
+
+// StatusResponse is the status of a server, sent as JSON in a Response.
+// It is decoded leniently, to cope with the quirks of real servers.
+type StatusResponse struct {
+	Version     StatusVersion  `json:"version"`
+	Players     *StatusPlayers `json:"players,omitempty"`
+	Description TextComponent  `json:"description"`
+	// Favicon is a data URI of a 64x64 PNG image.
+	Favicon            string `json:"favicon,omitempty"`
+	EnforcesSecureChat bool   `json:"enforcesSecureChat,omitempty"`
+	PreviewsChat       bool   `json:"previewsChat,omitempty"`
+
+	// ModInfo is sent by Forge servers up to 1.12.
+	ModInfo *ForgeModInfo `json:"modinfo,omitempty"`
+	// ForgeData is sent by Forge servers from 1.13.
+	ForgeData *ForgeData `json:"forgeData,omitempty"`
+}
+
+// StatusVersion is the version of a server.
+type StatusVersion struct {
+	Name     string `json:"name"`
+	Protocol int    `json:"protocol"`
+}
+
+// UnmarshalJSON decodes the version leniently.
+func (v *StatusVersion) UnmarshalJSON(b []byte) (err error) {
+	var version struct {
+		Name     json.RawMessage `json:"name"`
+		Protocol json.RawMessage `json:"protocol"`
+	}
+	if err := json.Unmarshal(b, &version); err != nil {
+		return err
+	}
+	v.Name = lenientString(version.Name)
+	v.Protocol, err = lenientInt(version.Protocol)
+	return err
+}
+
+// StatusPlayers is the player count of a server, with a sample of the connected players.
+type StatusPlayers struct {
+	Max    int            `json:"max"`
+	Online int            `json:"online"`
+	Sample []StatusPlayer `json:"sample,omitempty"`
+}
+
+// UnmarshalJSON decodes the player count leniently.
+func (ps *StatusPlayers) UnmarshalJSON(b []byte) (err error) {
+	var players struct {
+		Max    json.RawMessage `json:"max"`
+		Online json.RawMessage `json:"online"`
+		Sample []StatusPlayer  `json:"sample"`
+	}
+	if err := json.Unmarshal(b, &players); err != nil {
+		return err
+	}
+	if ps.Max, err = lenientInt(players.Max); err != nil {
+		return err
+	}
+	if ps.Online, err = lenientInt(players.Online); err != nil {
+		return err
+	}
+	ps.Sample = players.Sample
+	return nil
+}
+
+// StatusPlayer is a player in the sample of a status.
+// Servers often use the sample to show extra lines of text, with names that
+// are not player names and IDs that are not valid UUIDs.
+type StatusPlayer struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// ForgeModInfo lists the mods of a Forge server up to 1.12.
+type ForgeModInfo struct {
+	Type    string     `json:"type"`
+	ModList []ForgeMod `json:"modList"`
+}
+
+// ForgeMod is a mod in ForgeModInfo.
+type ForgeMod struct {
+	ModID   string `json:"modid"`
+	Version string `json:"version"`
+}
+
+// ForgeData lists the mods and network channels of a Forge server from 1.13.
+type ForgeData struct {
+	Channels          []ForgeChannel `json:"channels"`
+	Mods              []ForgeModData `json:"mods"`
+	FMLNetworkVersion int            `json:"fmlNetworkVersion"`
+	Truncated         bool           `json:"truncated,omitempty"`
+	// D is the compact encoding of the channels and mods used from 1.18.2.
+	D string `json:"d,omitempty"`
+}
+
+// ForgeChannel is a network channel in ForgeData.
+type ForgeChannel struct {
+	Res      string `json:"res"`
+	Version  string `json:"version"`
+	Required bool   `json:"required"`
+}
+
+// ForgeModData is a mod in ForgeData.
+type ForgeModData struct {
+	ModID     string `json:"modId"`
+	ModMarker string `json:"modmarker"`
 }
 
 // --- Pong ---
@@ -49,9 +184,9 @@ func (pi *Pong) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the Pong Packet from the given RawPacket.
 func (pi *Pong) FromRaw(p *RawPacket) (err error) {
 	if p.ID != Pong_ID {
-		return fmt.Errorf("invalid packet ID for Pong: %d", p.ID)
+		return &PacketIDError{Packet: "Pong", Expect: Pong_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.Payload)
+	return p.unmarshalPacket(pi, &pi.Payload)
 }
 
 // --- Request ---
@@ -74,9 +209,9 @@ func (pi *Request) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the Request Packet from the given RawPacket.
 func (pi *Request) FromRaw(p *RawPacket) (err error) {
 	if p.ID != Request_ID {
-		return fmt.Errorf("invalid packet ID for Request: %d", p.ID)
+		return &PacketIDError{Packet: "Request", Expect: Request_ID, Get: p.ID}
 	}
-	return nil
+	return p.unmarshalPacket(pi)
 }
 
 // --- Ping ---
@@ -100,7 +235,7 @@ func (pi *Ping) ToRaw(p *RawPacket) (err error) {
 // FromRaw unmarshals the Ping Packet from the given RawPacket.
 func (pi *Ping) FromRaw(p *RawPacket) (err error) {
 	if p.ID != Ping_ID {
-		return fmt.Errorf("invalid packet ID for Ping: %d", p.ID)
+		return &PacketIDError{Packet: "Ping", Expect: Ping_ID, Get: p.ID}
 	}
-	return p.Unmarshal(&pi.Payload)
+	return p.unmarshalPacket(pi, &pi.Payload)
 }
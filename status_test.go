@@ -0,0 +1,96 @@
+package proto
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStatusResponseLenient(t *testing.T) {
+	tests := []struct {
+		json string
+		want StatusResponse
+	}{
+		{`{"version": {"name": "1.21", "protocol": 767}, "players": {"max": 20, "online": 1, "sample": [{"name": "Steve", "id": "069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},
+			"description": {"text": "A ", "extra": [{"text": "server", "color": "gold"}]}, "favicon": "data:image/png;base64,AA==", "enforcesSecureChat": true}`,
+			StatusResponse{
+				Version:            StatusVersion{Name: "1.21", Protocol: 767},
+				Players:            &StatusPlayers{Max: 20, Online: 1, Sample: []StatusPlayer{{Name: "Steve", ID: "069a79f4-44e9-4726-a5be-fca90e38aaf5"}}},
+				Description:        TextComponent{Text: "A ", Extra: []TextComponent{{Text: "server", Color: "gold"}}},
+				Favicon:            "data:image/png;base64,AA==",
+				EnforcesSecureChat: true,
+			}},
+		// Quirks of real servers: strings for numbers, numbers for strings,
+		// descriptions as strings, samples used for text
+		{`{"version": {"name": 1.8, "protocol": "47"}, "players": {"max": "100", "online": 5.0, "sample": [{"name": "§aWelcome", "id": "00000000-0000-0000-0000-000000000000"}]}, "description": "§aA server"}`,
+			StatusResponse{
+				Version:     StatusVersion{Name: "1.8", Protocol: 47},
+				Players:     &StatusPlayers{Max: 100, Online: 5, Sample: []StatusPlayer{{Name: "§aWelcome", ID: "00000000-0000-0000-0000-000000000000"}}},
+				Description: TextComponent{Text: "§aA server"},
+			}},
+		{`{"version": {"name": null, "protocol": null}, "players": null, "description": ["a", "b"], "previewsChat": true}`,
+			StatusResponse{Description: TextComponent{Text: "a", Extra: []TextComponent{{Text: "b"}}}, PreviewsChat: true}},
+		// Forge servers
+		{`{"version": {"name": "1.12.2", "protocol": 340}, "description": "", "modinfo": {"type": "FML", "modList": [{"modid": "forge", "version": "14.23.5.2860"}]}}`,
+			StatusResponse{
+				Version: StatusVersion{Name: "1.12.2", Protocol: 340},
+				ModInfo: &ForgeModInfo{Type: "FML", ModList: []ForgeMod{{ModID: "forge", Version: "14.23.5.2860"}}},
+			}},
+		{`{"version": {"name": "1.16.5", "protocol": 754}, "description": "", "forgeData": {"channels": [{"res": "forge:handshake", "version": "1", "required": true}],
+			"mods": [{"modId": "forge", "modmarker": "36.2.39"}], "fmlNetworkVersion": 2}}`,
+			StatusResponse{
+				Version: StatusVersion{Name: "1.16.5", Protocol: 754},
+				ForgeData: &ForgeData{
+					Channels:          []ForgeChannel{{Res: "forge:handshake", Version: "1", Required: true}},
+					Mods:              []ForgeModData{{ModID: "forge", ModMarker: "36.2.39"}},
+					FMLNetworkVersion: 2,
+				},
+			}},
+	}
+	for _, test := range tests {
+		var got StatusResponse
+		if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+			t.Errorf("%s: %v", test.json, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.json, got, test.want)
+		}
+	}
+
+	for _, s := range []string{
+		`{"version": {"name": "1.21", "protocol": "new"}}`,
+		`{"players": {"max": "many", "online": 1}}`,
+		`{"players": {"max": 1, "online": [1]}}`,
+		`{"version": []}`,
+	} {
+		var got StatusResponse
+		if err := json.Unmarshal([]byte(s), &got); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	status := &StatusResponse{
+		Version:     StatusVersion{Name: "1.21", Protocol: 767},
+		Players:     &StatusPlayers{Max: 20},
+		Description: TextComponent{Text: "A server"},
+	}
+	in := Response{Status: status}
+	var out Response
+	roundTrip(t, Protocol1_21, &in, &out)
+	want := `{"version":{"name":"1.21","protocol":767},"players":{"max":20,"online":0},"description":{"text":"A server"}}`
+	if out.JSONResponse != String(want) {
+		t.Errorf("got %s, want %s", out.JSONResponse, want)
+	}
+	if !reflect.DeepEqual(out.Status, status) {
+		t.Errorf("got %+v, want %+v", out.Status, status)
+	}
+
+	// A response that is not a status is still decoded
+	in = Response{JSONResponse: "not a status"}
+	out = Response{Status: status}
+	roundTrip(t, Protocol1_21, &in, &out)
+	if out.JSONResponse != "not a status" || out.Status != nil {
+		t.Errorf("got %q, status %+v", out.JSONResponse, out.Status)
+	}
+}
@@ -0,0 +1,138 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultStatusTimeout is the timeout of the status exchange of a StatusHandler.
+const defaultStatusTimeout = 10 * time.Second
+
+// StatusFunc returns the status of the server for the client that sent the Handshake.
+type StatusFunc func(handshake *Handshake) (*StatusResponse, error)
+
+// --- StatusHandler ---
+
+// StatusHandler answers the Server List Ping of clients in the status state.
+//
+// The last Response is cached by its JSON content: while the status does not
+// change, the packet is not built again.
+type StatusHandler struct {
+	// Status returns the status sent to the clients.
+	Status StatusFunc
+	// Timeout is the maximum duration of the status exchange. If zero, 10 seconds is used.
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// json and data are the JSON content and the packet data of the last Response
+	json []byte
+	data []byte
+}
+
+// NewStatusHandler creates a StatusHandler answering with the status returned by the function.
+func NewStatusHandler(status StatusFunc) *StatusHandler {
+	return &StatusHandler{Status: status}
+}
+
+// HandleConn reads the Handshake of the client, answers its Server List Ping
+// and closes the connection.
+// It returns an error if the client does not ask for the status.
+func (h *StatusHandler) HandleConn(conn *Conn) error {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(h.timeout()))
+
+	var handshake Handshake
+	if err := conn.Receive(&handshake); err != nil {
+		return err
+	}
+	if handshake.NextState != StateStatus {
+		return fmt.Errorf("%w: handshake to state %d instead of status", ErrUnexpectedPacket, handshake.NextState)
+	}
+	return h.ServeStatus(conn, &handshake)
+}
+
+// ServeStatus answers the Server List Ping of a client whose Handshake was
+// already read, and closes the connection.
+// The client may send a Request, answered with a Response, then a Ping,
+// echoed as a Pong. Any other packet order is an error.
+func (h *StatusHandler) ServeStatus(conn *Conn, handshake *Handshake) error {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(h.timeout()))
+
+	var p RawPacket
+	requested := false
+	for {
+		if err := conn.ReadPacket(&p); err != nil {
+			if requested && isClosedByPeer(err) {
+				// The client does not measure the latency
+				return nil
+			}
+			return err
+		}
+
+		switch {
+		case p.ID == Request_ID && !requested:
+			var request Request
+			if err := request.FromRaw(&p); err != nil {
+				return err
+			}
+			if err := h.writeResponse(conn, handshake); err != nil {
+				return err
+			}
+			requested = true
+
+		case p.ID == Ping_ID:
+			var ping Ping
+			if err := ping.FromRaw(&p); err != nil {
+				return err
+			}
+			return conn.Send(&Pong{Payload: ping.Payload})
+
+		default:
+			return fmt.Errorf("%w: packet 0x%02X in status state", ErrUnexpectedPacket, p.ID)
+		}
+	}
+}
+
+func (h *StatusHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultStatusTimeout
+}
+
+// writeResponse sends the status returned by the StatusFunc, reusing the
+// last Response if its content is the same
+func (h *StatusHandler) writeResponse(conn *Conn, handshake *Handshake) error {
+	status, err := h.Status(handshake)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		return errors.New("status handler: nil status")
+	}
+	b, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	data := h.data
+	if !bytes.Equal(b, h.json) {
+		response := String(b)
+		p := RawPacket{ID: Response_ID}
+		if err := p.Marshal(&response); err != nil {
+			h.mu.Unlock()
+			return err
+		}
+		data = p.Data
+		h.json, h.data = b, data
+	}
+	h.mu.Unlock()
+
+	return conn.WritePacket(&RawPacket{ID: Response_ID, Data: data})
+}
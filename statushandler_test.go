@@ -0,0 +1,160 @@
+package proto
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveStatusHandler runs the handler on one end of a pipe, returning the
+// other end and the channel receiving the result of HandleConn
+func serveStatusHandler(h *StatusHandler) (*Conn, <-chan error) {
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- h.HandleConn(NewConn(server)) }()
+	return NewConn(client), done
+}
+
+func statusHandshake(nextState VarInt) *Handshake {
+	return &Handshake{ProtocolVersion: VarInt(Protocol1_21), ServerAddress: "example.com", ServerPort: 25565, NextState: nextState}
+}
+
+func TestStatusHandler(t *testing.T) {
+	h := NewStatusHandler(func(handshake *Handshake) (*StatusResponse, error) {
+		return &StatusResponse{
+			Version:     StatusVersion{Name: "1.21", Protocol: int(handshake.ProtocolVersion)},
+			Description: TextComponent{Text: string(handshake.ServerAddress)},
+		}, nil
+	})
+	conn, done := serveStatusHandler(h)
+	defer conn.Close()
+
+	result, err := requestStatus(conn, statusHandshake(StateStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status.Version.Protocol != int(Protocol1_21) || result.Status.Description.Text != "example.com" {
+		t.Errorf("got %+v, want a status for example.com in protocol %d", result.Status, Protocol1_21)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("handler: %v", err)
+	}
+
+	// The connection is closed after the Pong
+	var p RawPacket
+	if err := conn.ReadPacket(&p); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v after the Pong, want io.EOF", err)
+	}
+}
+
+func TestStatusHandlerPackets(t *testing.T) {
+	status := &StatusResponse{Description: TextComponent{Text: "A Minecraft Server"}}
+	h := NewStatusHandler(func(*Handshake) (*StatusResponse, error) { return status, nil })
+
+	// The client may ping without requesting the status, or close after the
+	// Response without pinging
+	conn, done := serveStatusHandler(h)
+	conn.Send(statusHandshake(StateStatus))
+	conn.Send(&Ping{Payload: 42})
+	var pong Pong
+	if err := conn.Receive(&pong); err != nil || pong.Payload != 42 {
+		t.Errorf("got %v %v, want the payload 42", pong.Payload, err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ping only: %v", err)
+	}
+
+	conn, done = serveStatusHandler(h)
+	conn.Send(statusHandshake(StateStatus))
+	conn.Send(&Request{})
+	var response Response
+	if err := conn.Receive(&response); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Errorf("request only: %v", err)
+	}
+
+	// Other orders are errors
+	for _, test := range []struct {
+		name      string
+		nextState VarInt
+		packets   []*RawPacket
+	}{
+		{"two requests", StateStatus, []*RawPacket{{ID: Request_ID}, {ID: Request_ID}}},
+		{"unknown packet", StateStatus, []*RawPacket{{ID: 0x05}}},
+		{"login", StateLogin, []*RawPacket{{ID: Request_ID}}},
+	} {
+		conn, done := serveStatusHandler(h)
+		// The Response is drained while the packets are sent
+		go io.Copy(io.Discard, conn.conn)
+		go func(handshake *Handshake, packets []*RawPacket) {
+			conn.Send(handshake)
+			for _, p := range packets {
+				conn.WritePacket(p)
+			}
+		}(statusHandshake(test.nextState), test.packets)
+		if err := <-done; !errors.Is(err, ErrUnexpectedPacket) {
+			t.Errorf("%s: got %v, want ErrUnexpectedPacket", test.name, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestStatusHandlerTimeout(t *testing.T) {
+	h := NewStatusHandler(func(*Handshake) (*StatusResponse, error) { return &StatusResponse{}, nil })
+	h.Timeout = 50 * time.Millisecond
+
+	conn, done := serveStatusHandler(h)
+	defer conn.Close()
+	conn.Send(statusHandshake(StateStatus))
+
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("got %v, want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler did not time out")
+	}
+}
+
+func TestStatusHandlerCache(t *testing.T) {
+	status := &StatusResponse{Description: TextComponent{Text: "first"}}
+	h := NewStatusHandler(func(*Handshake) (*StatusResponse, error) { return status, nil })
+	request := func() (string, []byte) {
+		t.Helper()
+		conn, done := serveStatusHandler(h)
+		defer conn.Close()
+		result, err := requestStatus(conn, statusHandshake(StateStatus))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return result.Status.Description.Text, h.data
+	}
+
+	text, data := request()
+	if text != "first" {
+		t.Errorf("got %q, want first", text)
+	}
+	// The same content is not built again, even from another pointer
+	status = &StatusResponse{Description: TextComponent{Text: "first"}}
+	if _, cached := request(); &cached[0] != &data[0] {
+		t.Error("equal status built again")
+	}
+
+	// A status modified in place is not answered from the cache
+	status.Description.Text = "second"
+	if text, _ := request(); text != "second" {
+		t.Errorf("got %q, want second", text)
+	}
+}
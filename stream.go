@@ -0,0 +1,193 @@
+package proto
+
+import (
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// PacketReader streams the body of a packet, decompressing it on the fly,
+// instead of materializing it in memory like RawPacket does.
+// It is meant for huge packets (chunks, registries...) that can be decoded or
+// skipped without buffering the whole frame.
+// Types read from a PacketReader are subject to its Limits.
+type PacketReader struct {
+	// ID is the packet ID.
+	ID int32
+
+	// Limits restricts the sizes accepted by Unpack and by the types read.
+	// If nil, DefaultLimits are used.
+	Limits *Limits
+
+	// frame is the remaining of the frame, as sent over the network
+	frame io.LimitedReader
+	// zlibReader inflates the frame, if it is compressed
+	zlibReader io.ReadCloser
+	// body is the remaining of the packet data
+	body io.Reader
+	// size is the length of the packet data
+	size int64
+	// remaining is the number of bytes left in the packet data
+	remaining int64
+}
+
+// NewPacketReader creates a new PacketReader
+func NewPacketReader() *PacketReader {
+	return &PacketReader{}
+}
+
+// Unpack reads the header of the next packet from the reader.
+// Its data must then be read from r, or discarded with Close,
+// before the next packet can be read from the reader.
+func (r *PacketReader) Unpack(reader io.Reader, threshold int) error {
+	if r.zlibReader != nil {
+		r.zlibReader.Close()
+		r.zlibReader = nil
+	}
+
+	limits := r.limits()
+
+	var length VarInt
+	_, err := length.ReadFrom(reader)
+	if err != nil {
+		return err
+	}
+
+	err = checkFrameLength(length, limits)
+	if err != nil {
+		return err
+	}
+
+	r.frame = io.LimitedReader{R: reader, N: int64(length)}
+	r.body = &r.frame
+
+	var dataLength VarInt
+	if threshold >= 0 {
+		_, err = dataLength.ReadFrom(&r.frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	if dataLength != 0 {
+		if int(dataLength) < threshold {
+			return &CompressionError{fmt.Errorf("size of %d is below threshold of %d", dataLength, threshold)}
+		}
+
+		if int(dataLength) > limits.MaxDataLength {
+			return &LimitError{Limit: "MaxDataLength", Value: int64(dataLength), Max: int64(limits.MaxDataLength)}
+		}
+
+		r.zlibReader, err = zlib.NewReader(&r.frame)
+		if err != nil {
+			return &CompressionError{err}
+		}
+		r.body = r.zlibReader
+	}
+
+	var id VarInt
+	idLength, err := id.ReadFrom(r.body)
+	if err != nil {
+		if dataLength != 0 {
+			err = &CompressionError{err}
+		}
+		return err
+	}
+	r.ID = int32(id)
+
+	if dataLength != 0 {
+		r.remaining = int64(dataLength) - idLength
+	} else {
+		r.remaining = r.frame.N
+	}
+
+	if r.remaining < 0 {
+		return &CompressionError{fmt.Errorf("size of %d is too small for packet ID", dataLength)}
+	}
+	r.size = r.remaining
+
+	return nil
+}
+
+// limits returns the limits that apply to the packet reader
+func (r *PacketReader) limits() *Limits {
+	if r.Limits != nil {
+		return r.Limits
+	}
+	return &DefaultLimits
+}
+
+// Read reads up to len(b) bytes of packet data.
+// It returns io.EOF at the end of the packet.
+func (r *PacketReader) Read(b []byte) (n int, err error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+
+	n, err = r.body.Read(b)
+	r.remaining -= int64(n)
+
+	if err == io.EOF && r.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+		if r.zlibReader != nil {
+			err = &CompressionError{err}
+		}
+	}
+	return n, err
+}
+
+// ReadByte reads one byte of packet data.
+func (r *PacketReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+	return b[0], err
+}
+
+// Len returns the number of bytes of packet data left to read.
+func (r *PacketReader) Len() int {
+	return int(r.remaining)
+}
+
+// Close discards the packet data left to read, without decompressing it,
+// so that the next packet can be read from the underlying reader.
+func (r *PacketReader) Close() error {
+	r.remaining = 0
+
+	if r.zlibReader != nil {
+		r.zlibReader.Close()
+		r.zlibReader = nil
+	}
+
+	_, err := io.Copy(io.Discard, &r.frame)
+	return err
+}
+
+// Decode reads the given types from the packet data.
+// A type that cannot be decoded is reported as a *FieldError.
+func (r *PacketReader) Decode(types ...Type) error {
+	reader := limitedPacketReader{r}
+	for i, t := range types {
+		offset := r.size - r.remaining
+		_, err := t.ReadFrom(reader)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrTruncated
+			}
+			return &FieldError{Index: i, Offset: offset, Err: err}
+		}
+	}
+	return nil
+}
+
+// limitedPacketReader is a PacketReader that carries its Limits to the types read from it.
+type limitedPacketReader struct {
+	*PacketReader
+}
+
+// Limits returns the Limits to apply.
+func (r limitedPacketReader) Limits() *Limits {
+	return r.limits()
+}
@@ -0,0 +1,540 @@
+package proto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tags are named sets of entries of a registry, such as the blocks of
+// #minecraft:logs. They are sent to the client with UpdateTags in the
+// configuration state, and with PlayUpdateTags in the play state when the
+// data packs are reloaded. Before 1.20.2, they are only sent in the play state.
+
+// --- UpdateTags ---
+
+// UpdateTags is a packet sending the tags of registries to the client,
+// such as the blocks of minecraft:logs.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type UpdateTags struct {
+	Registries TagRegistries
+}
+
+// UpdateTags_ID is the UpdateTags packet ID.
+const UpdateTags_ID = 0x0D
+
+// updateTagsIDs are the UpdateTags packet IDs by protocol version, newest first.
+var updateTagsIDs = []versionedID{{Protocol1_20_5, UpdateTags_ID}, {Protocol1_20_3, 0x09}, {Protocol1_20_2, 0x08}}
+
+// ToRaw marshals the UpdateTags Packet to the given RawPacket.
+func (pi *UpdateTags) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("UpdateTags", p.Protocol, updateTagsIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Registries)
+}
+
+// FromRaw unmarshals the UpdateTags Packet from the given RawPacket.
+func (pi *UpdateTags) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("UpdateTags", updateTagsIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Registries)
+}
+
+// TagRegistry is the tags of a registry, sent in UpdateTags.
+type TagRegistry struct {
+	Registry Identifier
+	Tags     []Tag
+}
+
+// Tag is a named set of entries of a registry, given by their numeric IDs.
+type Tag struct {
+	Name    Identifier
+	Entries []VarInt
+}
+
+// --- TagRegistries ---
+
+// TagRegistries is a length-prefixed array of the tags of registries.
+// Each registry is encoded as its identifier and a length-prefixed array of
+// tags, each tag as its name and a length-prefixed array of VarInt IDs.
+// Implements proto.Type interface (Minecraft protocol data type).
+type TagRegistries []TagRegistry
+
+// ReadFrom reads TagRegistries data from r until an error occurs.
+// The return value n is the number of bytes read.
+// Any error encountered during the read is also returned.
+func (rs *TagRegistries) ReadFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = count.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	// A registry is at least 2 bytes long: an empty identifier and no tags
+	if err := checkArrayLength(r, count, 2); err != nil {
+		return n, err
+	}
+
+	registries := make(TagRegistries, count)
+	for i := range registries {
+		nn, err := registries[i].readFrom(r)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	*rs = registries
+	return n, nil
+}
+
+func (tr *TagRegistry) readFrom(r io.Reader) (n int64, err error) {
+	var count VarInt
+	n, err = readTypes(r, &tr.Registry, &count)
+	if err != nil {
+		return n, err
+	}
+	// A tag is at least 2 bytes long: an empty name and no entries
+	if err := checkArrayLength(r, count, 2); err != nil {
+		return n, err
+	}
+
+	tr.Tags = make([]Tag, count)
+	for i := range tr.Tags {
+		tag := &tr.Tags[i]
+		var length VarInt
+		nn, err := readTypes(r, &tag.Name, &length)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		if err := checkArrayLength(r, length, 1); err != nil {
+			return n, err
+		}
+
+		tag.Entries = make([]VarInt, length)
+		for j := range tag.Entries {
+			nn, err := tag.Entries[j].ReadFrom(r)
+			n += nn
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// WriteTo writes TagRegistries data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (rs TagRegistries) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = VarInt(len(rs)).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	for _, tr := range rs {
+		nn, err := writeTypes(w, tr.Registry, VarInt(len(tr.Tags)))
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		for _, tag := range tr.Tags {
+			nn, err := writeTypes(w, tag.Name, VarInt(len(tag.Entries)))
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			for _, id := range tag.Entries {
+				nn, err := id.WriteTo(w)
+				n += nn
+				if err != nil {
+					return n, err
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+// --- PlayUpdateTags ---
+
+// PlayUpdateTags is the UpdateTags packet of the play state, from 1.17.
+// Its ID depends on the protocol version of the RawPacket.
+// Clientbound (S -> C)
+// Implements proto.Packet interface.
+type PlayUpdateTags struct {
+	Registries TagRegistries
+}
+
+// PlayUpdateTags_ID is the PlayUpdateTags packet ID from 1.21.2, also used
+// when the protocol version is zero.
+const PlayUpdateTags_ID = 0x7F
+
+// playUpdateTagsIDs are the PlayUpdateTags packet IDs by protocol version, newest first.
+// Before 1.17, the tags had a fixed set of registries and another layout.
+var playUpdateTagsIDs = []versionedID{
+	{Protocol1_21_2, PlayUpdateTags_ID},
+	{Protocol1_20_5, 0x78},
+	{Protocol1_20_3, 0x74},
+	{Protocol1_20_2, 0x70},
+	{Protocol1_19_4, 0x6E},
+	{Protocol1_19_3, 0x6A},
+	{Protocol1_19_1, 0x6B},
+	{Protocol1_19, 0x68},
+	{Protocol1_18, 0x67},
+	{Protocol1_17, 0x66},
+}
+
+// ToRaw marshals the PlayUpdateTags Packet to the given RawPacket.
+func (pi *PlayUpdateTags) ToRaw(p *RawPacket) (err error) {
+	if p.ID, err = packetID("PlayUpdateTags", p.Protocol, playUpdateTagsIDs); err != nil {
+		return err
+	}
+	return p.Marshal(&pi.Registries)
+}
+
+// FromRaw unmarshals the PlayUpdateTags Packet from the given RawPacket.
+func (pi *PlayUpdateTags) FromRaw(p *RawPacket) (err error) {
+	if err = p.checkID("PlayUpdateTags", playUpdateTagsIDs); err != nil {
+		return err
+	}
+	return p.unmarshalPacket(pi, &pi.Registries)
+}
+
+// --- TagResolver ---
+
+// TagResolver resolves the tags of registries to their entries, expanding the
+// tags they reference, and builds the TagRegistries sent to the client.
+//
+// The tags are defined with Define or loaded from data packs with LoadDatapack,
+// and the entries of a registry, with their numeric IDs, are given with AddRegistry.
+// The entries of a registry without entries are not checked, and its tags are not
+// sent to the client.
+//
+// Registries, tags and entries are identifiers: the minecraft namespace may be
+// omitted, and tags may be prefixed with #.
+// The zero TagResolver is empty and ready to use. It is safe for concurrent use.
+type TagResolver struct {
+	mu       sync.Mutex
+	entries  map[string]map[string]int32      // registry → entry → numeric ID
+	tags     map[string]map[string][]tagValue // registry → tag → values
+	resolved map[string]map[string]*resolvedTag
+}
+
+// tagValue is a value of a tag definition: an entry, or a tag if tag is set
+type tagValue struct {
+	id       string
+	tag      bool
+	required bool
+}
+
+// resolvedTag is the entries of a tag, in the order of its definition
+type resolvedTag struct {
+	entries []string
+	set     map[string]bool
+}
+
+func (t *resolvedTag) add(entry string) {
+	if !t.set[entry] {
+		t.set[entry] = true
+		t.entries = append(t.entries, entry)
+	}
+}
+
+// AddRegistry sets the entries of the registry. The numeric ID of an entry
+// is its index in entries.
+func (r *TagResolver) AddRegistry(registry string, entries []string) {
+	ids := make(map[string]int32, len(entries))
+	for i, e := range entries {
+		ids[normalizeIdentifier(e)] = int32(i)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entries == nil {
+		r.entries = make(map[string]map[string]int32)
+	}
+	r.entries[normalizeIdentifier(registry)] = ids
+	r.resolved = nil
+}
+
+// Define adds values to the tag of the registry, or replaces its values if replace
+// is set. A value is an entry, or a tag if prefixed with #. All the values are required.
+func (r *TagResolver) Define(registry, tag string, values []string, replace bool) {
+	tv := make([]tagValue, len(values))
+	for i, v := range values {
+		tv[i] = parseTagValue(v, true)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.define(normalizeIdentifier(registry), normalizeTag(tag), tv, replace)
+}
+
+func (r *TagResolver) define(registry, tag string, values []tagValue, replace bool) {
+	if r.tags == nil {
+		r.tags = make(map[string]map[string][]tagValue)
+	}
+	tags := r.tags[registry]
+	if tags == nil {
+		tags = make(map[string][]tagValue)
+		r.tags[registry] = tags
+	}
+	if replace {
+		tags[tag] = values
+	} else {
+		tags[tag] = append(tags[tag], values...)
+	}
+	r.resolved = nil
+}
+
+// Resolve returns the entries of the tag of the registry, in the order of their
+// definition. The entries of the tags it references are expanded.
+// An error wrapping ErrInvalidTag is returned if the tag, or a required tag or
+// entry it references, is unknown, or if the tag references itself.
+func (r *TagResolver) Resolve(registry, tag string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, err := r.resolve(normalizeIdentifier(registry), normalizeTag(tag))
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), t.entries...), nil
+}
+
+// Contains reports whether the entry of the registry is in the tag, such as
+// the block minecraft:oak_log in #minecraft:logs.
+// It returns false if the tag cannot be resolved.
+func (r *TagResolver) Contains(registry, tag, entry string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, err := r.resolve(normalizeIdentifier(registry), normalizeTag(tag))
+	return err == nil && t.set[normalizeIdentifier(entry)]
+}
+
+// resolve returns the resolved tag from the cache, or resolves it. r.mu must be held.
+func (r *TagResolver) resolve(registry, tag string) (*resolvedTag, error) {
+	if _, ok := r.tags[registry][tag]; !ok {
+		return nil, fmt.Errorf("%w: unknown tag #%s of %s", ErrInvalidTag, tag, registry)
+	}
+	return r.resolveTag(registry, tag, make(map[string]bool))
+}
+
+func (r *TagResolver) resolveTag(registry, tag string, visiting map[string]bool) (*resolvedTag, error) {
+	if t, ok := r.resolved[registry][tag]; ok {
+		return t, nil
+	}
+	if visiting[tag] {
+		return nil, fmt.Errorf("%w: #%s of %s references itself", ErrInvalidTag, tag, registry)
+	}
+	visiting[tag] = true
+	defer delete(visiting, tag)
+
+	tags, ids := r.tags[registry], r.entries[registry]
+	t := &resolvedTag{set: make(map[string]bool)}
+	for _, v := range tags[tag] {
+		if v.tag {
+			if _, ok := tags[v.id]; !ok {
+				if v.required {
+					return nil, fmt.Errorf("%w: #%s of %s references unknown tag #%s", ErrInvalidTag, tag, registry, v.id)
+				}
+				continue
+			}
+			nested, err := r.resolveTag(registry, v.id, visiting)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range nested.entries {
+				t.add(e)
+			}
+			continue
+		}
+
+		if ids != nil {
+			if _, ok := ids[v.id]; !ok {
+				if v.required {
+					return nil, fmt.Errorf("%w: #%s of %s references unknown entry %s", ErrInvalidTag, tag, registry, v.id)
+				}
+				continue
+			}
+		}
+		t.add(v.id)
+	}
+
+	if r.resolved == nil {
+		r.resolved = make(map[string]map[string]*resolvedTag)
+	}
+	if r.resolved[registry] == nil {
+		r.resolved[registry] = make(map[string]*resolvedTag)
+	}
+	r.resolved[registry][tag] = t
+	return t, nil
+}
+
+// TagRegistries resolves the tags of the registries with entries, to be sent
+// to the client in UpdateTags or PlayUpdateTags.
+// The registries and their tags are sorted by identifier.
+func (r *TagResolver) TagRegistries() (TagRegistries, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registries := make([]string, 0, len(r.tags))
+	for registry := range r.tags {
+		if r.entries[registry] != nil {
+			registries = append(registries, registry)
+		}
+	}
+	sort.Strings(registries)
+
+	result := make(TagRegistries, 0, len(registries))
+	for _, registry := range registries {
+		names := make([]string, 0, len(r.tags[registry]))
+		for name := range r.tags[registry] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		ids := r.entries[registry]
+		tr := TagRegistry{Registry: Identifier(registry), Tags: make([]Tag, len(names))}
+		for i, name := range names {
+			t, err := r.resolveTag(registry, name, make(map[string]bool))
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]VarInt, len(t.entries))
+			for j, e := range t.entries {
+				entries[j] = VarInt(ids[e])
+			}
+			tr.Tags[i] = Tag{Name: Identifier(name), Entries: entries}
+		}
+		result = append(result, tr)
+	}
+	return result, nil
+}
+
+// legacyTagDirectories are the directories of the tags of registries named in
+// the plural before 1.21.
+var legacyTagDirectories = map[string]string{
+	"blocks":       "block",
+	"items":        "item",
+	"fluids":       "fluid",
+	"entity_types": "entity_type",
+	"game_events":  "game_event",
+}
+
+// tagFile is a tag file of a data pack
+type tagFile struct {
+	Replace bool              `json:"replace"`
+	Values  []json.RawMessage `json:"values"`
+}
+
+// LoadDatapack loads the tags of the data pack in fsys, such as os.DirFS(dir),
+// from the files data/<namespace>/tags/<registry>/<tag>.json. The registries
+// named in the plural before 1.21, such as blocks, are loaded as the singular.
+// Data packs are loaded on top of the previous ones: their tags replace the
+// previous definitions if they set replace, and are added to them otherwise.
+func (r *TagResolver) LoadDatapack(fsys fs.FS) error {
+	namespaces, err := fs.ReadDir(fsys, "data")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ns := range namespaces {
+		if !ns.IsDir() {
+			continue
+		}
+		root := path.Join("data", ns.Name(), "tags")
+		err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if name == root && errors.Is(err, fs.ErrNotExist) {
+					return fs.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() || path.Ext(name) != ".json" {
+				return nil
+			}
+			return r.loadTagFile(fsys, name, ns.Name(), strings.TrimPrefix(name, root+"/"))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTagFile loads the tag file at name, whose path in the tags directory of the namespace is rel
+func (r *TagResolver) loadTagFile(fsys fs.FS, name, namespace, rel string) error {
+	// The registries with a path of two segments are those of worldgen
+	segments := 1
+	if strings.HasPrefix(rel, "worldgen/") {
+		segments = 2
+	}
+	parts := strings.SplitN(strings.TrimSuffix(rel, ".json"), "/", segments+1)
+	if len(parts) <= segments {
+		return fmt.Errorf("%w: %s: no registry directory", ErrInvalidTag, name)
+	}
+	registry := strings.Join(parts[:segments], "/")
+	if singular, ok := legacyTagDirectories[registry]; ok {
+		registry = singular
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	var file tagFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidTag, name, err)
+	}
+
+	values := make([]tagValue, len(file.Values))
+	for i, raw := range file.Values {
+		var id string
+		if err := json.Unmarshal(raw, &id); err == nil {
+			values[i] = parseTagValue(id, true)
+			continue
+		}
+		value := struct {
+			ID       string `json:"id"`
+			Required *bool  `json:"required"`
+		}{}
+		if err := json.Unmarshal(raw, &value); err != nil || value.ID == "" {
+			return fmt.Errorf("%w: %s: invalid value %s", ErrInvalidTag, name, raw)
+		}
+		values[i] = parseTagValue(value.ID, value.Required == nil || *value.Required)
+	}
+
+	r.define(normalizeIdentifier(registry), namespace+":"+parts[segments], values, file.Replace)
+	return nil
+}
+
+// parseTagValue parses a value of a tag definition, an entry or a tag prefixed with #
+func parseTagValue(v string, required bool) tagValue {
+	if strings.HasPrefix(v, "#") {
+		return tagValue{id: normalizeIdentifier(v[1:]), tag: true, required: required}
+	}
+	return tagValue{id: normalizeIdentifier(v), required: required}
+}
+
+// normalizeTag returns the identifier of a tag, which may be prefixed with #
+func normalizeTag(tag string) string {
+	return normalizeIdentifier(strings.TrimPrefix(tag, "#"))
+}
+
+// normalizeIdentifier adds the minecraft namespace to an identifier without namespace
+func normalizeIdentifier(id string) string {
+	if strings.Contains(id, ":") {
+		return id
+	}
+	return "minecraft:" + id
+}
@@ -0,0 +1,206 @@
+package proto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var testBlocks = []string{"oak_log", "birch_log", "stone", "minecraft:dirt", "modded:log"}
+
+func TestTagResolverNested(t *testing.T) {
+	var r TagResolver
+	r.AddRegistry("block", testBlocks)
+	r.Define("block", "logs", []string{"oak_log", "minecraft:birch_log"}, false)
+	r.Define("minecraft:block", "#minecraft:mineable", []string{"#logs", "stone", "oak_log", "#modded:logs"}, false)
+	r.Define("block", "modded:logs", []string{"modded:log", "birch_log"}, false)
+
+	got, err := r.Resolve("block", "#mineable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The entries are in the order of the definition, once
+	want := []string{"minecraft:oak_log", "minecraft:birch_log", "minecraft:stone", "modded:log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		tag, entry string
+		want       bool
+	}{
+		{"mineable", "oak_log", true},
+		{"#minecraft:mineable", "minecraft:stone", true},
+		{"mineable", "modded:log", true},
+		{"mineable", "dirt", false},
+		{"logs", "stone", false},
+		{"unknown", "stone", false},
+	} {
+		if got := r.Contains("block", test.tag, test.entry); got != test.want {
+			t.Errorf("%s contains %s: got %v, want %v", test.tag, test.entry, got, test.want)
+		}
+	}
+
+	// The tags of a registry without entries are not checked
+	r.Define("item", "anything", []string{"unknown_item"}, false)
+	if got, err := r.Resolve("item", "anything"); err != nil || !reflect.DeepEqual(got, []string{"minecraft:unknown_item"}) {
+		t.Errorf("got %v %v", got, err)
+	}
+}
+
+func TestTagResolverInvalid(t *testing.T) {
+	var r TagResolver
+	r.AddRegistry("block", testBlocks)
+	r.Define("block", "unknown_entry", []string{"stone", "granite"}, false)
+	r.Define("block", "unknown_tag", []string{"stone", "#missing"}, false)
+	r.Define("block", "self", []string{"stone", "#self"}, false)
+	r.Define("block", "a", []string{"oak_log", "#b"}, false)
+	r.Define("block", "b", []string{"birch_log", "#c"}, false)
+	r.Define("block", "c", []string{"#a"}, false)
+	r.Define("block", "uses_cycle", []string{"#b"}, false)
+
+	for _, tag := range []string{"unknown_entry", "unknown_tag", "self", "a", "b", "c", "uses_cycle", "undefined"} {
+		if got, err := r.Resolve("block", tag); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("%s: got %v %v, want ErrInvalidTag", tag, got, err)
+		}
+	}
+	if _, err := r.TagRegistries(); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("TagRegistries: got %v, want ErrInvalidTag", err)
+	}
+
+	// Breaking the cycle makes the tags valid
+	r.Define("block", "c", []string{"stone"}, true)
+	got, err := r.Resolve("block", "a")
+	if want := []string{"minecraft:oak_log", "minecraft:birch_log", "minecraft:stone"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v %v, want %v", got, err, want)
+	}
+}
+
+func TestTagResolverReplace(t *testing.T) {
+	var r TagResolver
+	r.AddRegistry("block", testBlocks)
+	r.Define("block", "logs", []string{"oak_log"}, false)
+	r.Define("block", "wood", []string{"#logs"}, false)
+	if got, _ := r.Resolve("block", "wood"); !reflect.DeepEqual(got, []string{"minecraft:oak_log"}) {
+		t.Errorf("got %v", got)
+	}
+
+	// The resolved tags are updated by later definitions
+	r.Define("block", "logs", []string{"birch_log"}, false)
+	if got, _ := r.Resolve("block", "wood"); !reflect.DeepEqual(got, []string{"minecraft:oak_log", "minecraft:birch_log"}) {
+		t.Errorf("added: got %v", got)
+	}
+	r.Define("block", "logs", []string{"modded:log"}, true)
+	if got, _ := r.Resolve("block", "wood"); !reflect.DeepEqual(got, []string{"modded:log"}) {
+		t.Errorf("replaced: got %v", got)
+	}
+
+	// And by the entries of the registry
+	r.AddRegistry("block", []string{"stone"})
+	if _, err := r.Resolve("block", "wood"); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("got %v, want ErrInvalidTag", err)
+	}
+}
+
+func TestTagResolverLoadDatapack(t *testing.T) {
+	base := fstest.MapFS{
+		// Before 1.21, the directories of some registries are in the plural
+		"data/minecraft/tags/blocks/logs.json":           {Data: []byte(`{"values": ["oak_log", "minecraft:birch_log"]}`)},
+		"data/minecraft/tags/block/mineable/axe.json":    {Data: []byte(`{"values": ["#minecraft:logs", {"id": "#minecraft:missing", "required": false}]}`)},
+		"data/minecraft/tags/worldgen/biome/is_hot.json": {Data: []byte(`{"values": ["desert", {"id": "minecraft:badlands"}]}`)},
+		"data/minecraft/tags/block/README.txt":           {Data: []byte("not a tag")},
+		"data/modded/tags/block/logs.json":               {Data: []byte(`{"values": [{"id": "modded:log", "required": false}, {"id": "modded:missing", "required": false}]}`)},
+		"data/modded/recipes/log.json":                   {Data: []byte(`{}`)},
+	}
+	var r TagResolver
+	r.AddRegistry("block", testBlocks)
+	if err := r.LoadDatapack(base); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		registry, tag string
+		want          []string
+	}{
+		{"block", "logs", []string{"minecraft:oak_log", "minecraft:birch_log"}},
+		{"block", "mineable/axe", []string{"minecraft:oak_log", "minecraft:birch_log"}},
+		{"block", "modded:logs", []string{"modded:log"}},
+		{"worldgen/biome", "is_hot", []string{"minecraft:desert", "minecraft:badlands"}},
+	} {
+		got, err := r.Resolve(test.registry, test.tag)
+		if err != nil {
+			t.Errorf("%s #%s: %v", test.registry, test.tag, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s #%s: got %v, want %v", test.registry, test.tag, got, test.want)
+		}
+	}
+
+	// The tags of a data pack are added to the previous ones, or replace them
+	overlay := fstest.MapFS{
+		"data/minecraft/tags/block/logs.json":         {Data: []byte(`{"values": ["stone"]}`)},
+		"data/minecraft/tags/block/mineable/axe.json": {Data: []byte(`{"replace": true, "values": ["dirt"]}`)},
+	}
+	if err := r.LoadDatapack(overlay); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.Resolve("block", "logs"); !reflect.DeepEqual(got, []string{"minecraft:oak_log", "minecraft:birch_log", "minecraft:stone"}) {
+		t.Errorf("added: got %v", got)
+	}
+	if got, _ := r.Resolve("block", "mineable/axe"); !reflect.DeepEqual(got, []string{"minecraft:dirt"}) {
+		t.Errorf("replaced: got %v", got)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"malformed":   {"data/minecraft/tags/block/logs.json": {Data: []byte(`{"values": [`)}},
+		"no registry": {"data/minecraft/tags/logs.json": {Data: []byte(`{"values": []}`)}},
+		"no id":       {"data/minecraft/tags/block/logs.json": {Data: []byte(`{"values": [{"required": false}]}`)}},
+		"not a value": {"data/minecraft/tags/block/logs.json": {Data: []byte(`{"values": [1]}`)}},
+	} {
+		if err := new(TagResolver).LoadDatapack(fsys); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("%s: got %v, want ErrInvalidTag", name, err)
+		}
+	}
+	if err := new(TagResolver).LoadDatapack(fstest.MapFS{"pack.mcmeta": {}}); err == nil {
+		t.Error("no data directory: no error")
+	}
+}
+
+func TestTagResolverTagRegistries(t *testing.T) {
+	var r TagResolver
+	r.AddRegistry("item", []string{"stick", "oak_log"})
+	r.AddRegistry("block", testBlocks)
+	r.Define("item", "logs", []string{"oak_log"}, false)
+	r.Define("block", "logs", []string{"birch_log", "oak_log"}, false)
+	r.Define("block", "dirt", []string{"dirt"}, false)
+	r.Define("block", "empty", nil, false)
+	r.Define("block", "all", []string{"#logs", "#dirt", "modded:log"}, false)
+	// The tags of registries without entries are not sent
+	r.Define("fluid", "water", []string{"water"}, false)
+
+	registries, err := r.TagRegistries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TagRegistries{
+		{Registry: "minecraft:block", Tags: []Tag{
+			{Name: "minecraft:all", Entries: []VarInt{1, 0, 3, 4}},
+			{Name: "minecraft:dirt", Entries: []VarInt{3}},
+			{Name: "minecraft:empty", Entries: []VarInt{}},
+			{Name: "minecraft:logs", Entries: []VarInt{1, 0}},
+		}},
+		{Registry: "minecraft:item", Tags: []Tag{
+			{Name: "minecraft:logs", Entries: []VarInt{1}},
+		}},
+	}
+	if !reflect.DeepEqual(registries, want) {
+		t.Errorf("got %+v, want %+v", registries, want)
+	}
+
+	in := UpdateTags{Registries: registries}
+	var out UpdateTags
+	roundTrip(t, Protocol1_21, &in, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
@@ -296,12 +296,22 @@ func (s *String) ReadFrom(r io.Reader) (n int64, err error) {
 	}
 	n += nn
 
+	// A UTF-16 code unit is encoded in at most 3 bytes of UTF-8
+	maxLength := limitsOf(r).MaxStringLength
+	if err := checkLength(r, "MaxStringLength", int(l), maxLength*3, 1); err != nil {
+		return n, err
+	}
+
 	bs := make([]byte, l)
 	if _, err := io.ReadFull(r, bs); err != nil {
 		return n, err
 	}
 	n += int64(l)
 
+	if length := utf16Len(bs); length > maxLength {
+		return n, &LimitError{Limit: "MaxStringLength", Value: int64(length), Max: int64(maxLength)}
+	}
+
 	*s = String(bs)
 	return n, nil
 }
@@ -346,8 +356,8 @@ const MaxVarIntLen = 5
 func (v *VarInt) ReadFrom(r io.Reader) (n int64, err error) {
 	var vi uint32
 	for sec := byte(0x80); sec&0x80 != 0; n++ {
-		if n > MaxVarIntLen {
-			return n, errors.New("VarInt is too big")
+		if n >= MaxVarIntLen {
+			return n, &RangeError{Name: "VarInt length", Value: n + 1, Min: 1, Max: MaxVarIntLen}
 		}
 
 		sec, err = readByte(r)
@@ -399,7 +409,7 @@ func (v *VarLong) ReadFrom(r io.Reader) (n int64, err error) {
 	var V uint64
 	for sec := byte(0x80); sec&0x80 != 0; n++ {
 		if n >= MaxVarLongLen {
-			return n, errors.New("VarLong is too big")
+			return n, &RangeError{Name: "VarLong length", Value: n + 1, Min: 1, Max: MaxVarLongLen}
 		}
 		sec, err = readByte(r)
 		if err != nil {
@@ -480,29 +490,6 @@ func (s *Slot) WriteTo(w io.Writer) (n int64, err error) {
 	return 0, errors.New("proto.Slot is not implemented")
 }
 
-// --- NBTTag ---
-
-// NBTTag represents an item and its associated data.
-// Implements proto.Type interface (Minecraft protocol data type).
-// WARNING: NBTTag is not implemented in proto.
-type NBTTag struct{}
-
-// ReadFrom reads NBTTag data from r until an error occurs.
-// The return value n is the number of bytes read.
-// Any error encountered during the read is also returned.
-// WARNING: NBTTag is not implemented in proto.
-func (t *NBTTag) ReadFrom(r io.Reader) (n int64, err error) {
-	return 0, errors.New("proto.NBTTag is not implemented")
-}
-
-// WriteTo writes NBTTag data to w until an error occurs.
-// The return value n is the number of bytes written.
-// Any error encountered during the write is also returned.
-// WARNING: NBTTag is not implemented in proto.
-func (t *NBTTag) WriteTo(w io.Writer) (n int64, err error) {
-	return 0, errors.New("proto.NBTTag is not implemented")
-}
-
 // --- Position ---
 
 // Position is an integer/block position: x,y,z.
@@ -606,6 +593,26 @@ func (u UUID) WriteTo(w io.Writer) (n int64, err error) {
 	return int64(nn), err
 }
 
+// String returns the dashed form of the UUID.
+func (u UUID) String() string {
+	return uuid.UUID(u).String()
+}
+
+// MarshalText encodes the UUID in its dashed form.
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText decodes the UUID in its dashed or undashed form.
+func (u *UUID) UnmarshalText(text []byte) error {
+	id, err := uuid.ParseBytes(text)
+	if err != nil {
+		return err
+	}
+	*u = UUID(id)
+	return nil
+}
+
 // --- ByteArray ---
 
 // ByteArray is just a sequence of zero or more bytes.
@@ -622,6 +629,9 @@ func (b *ByteArray) ReadFrom(r io.Reader) (n int64, err error) {
 	if err != nil {
 		return n1, err
 	}
+	if err := checkLength(r, "MaxByteArrayLength", int(Len), limitsOf(r).MaxByteArrayLength, 1); err != nil {
+		return n1, err
+	}
 	buf := bytes.NewBuffer(*b)
 	buf.Reset()
 	n2, err := io.CopyN(buf, r, int64(Len))
@@ -640,3 +650,28 @@ func (b ByteArray) WriteTo(w io.Writer) (n int64, err error) {
 	n2, err := w.Write(b)
 	return n1 + int64(n2), err
 }
+
+// --- RemainingBytes ---
+
+// RemainingBytes is a sequence of bytes that extends to the end of the packet.
+// Unlike ByteArray, it is not prefixed with its length.
+// Implements proto.Type interface (Minecraft protocol data type).
+type RemainingBytes []byte
+
+// ReadFrom reads RemainingBytes data from r until EOF or an error occurs.
+// The return value n is the number of bytes read.
+// Any error except io.EOF encountered during the read is also returned.
+func (b *RemainingBytes) ReadFrom(r io.Reader) (n int64, err error) {
+	buf := bytes.NewBuffer((*b)[:0])
+	n, err = buf.ReadFrom(r)
+	*b = buf.Bytes()
+	return n, err
+}
+
+// WriteTo writes RemainingBytes data to w until an error occurs.
+// The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (b RemainingBytes) WriteTo(w io.Writer) (n int64, err error) {
+	nn, err := w.Write(b)
+	return int64(nn), err
+}
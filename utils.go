@@ -11,3 +11,37 @@ func readByte(r io.Reader) (byte, error) {
 	_, err := io.ReadFull(r, v[:])
 	return v[0], err
 }
+
+// appendVarInt appends the VarInt encoding of v to b
+func appendVarInt(b []byte, v int32) []byte {
+	num := uint32(v)
+	for num >= 0x80 {
+		b = append(b, byte(num)|0x80)
+		num >>= 7
+	}
+	return append(b, byte(num))
+}
+
+// readTypes reads the given types from r in order
+func readTypes(r io.Reader, types ...Type) (n int64, err error) {
+	for _, t := range types {
+		nn, err := t.ReadFrom(r)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// writeTypes writes the given types to w in order
+func writeTypes(w io.Writer, types ...io.WriterTo) (n int64, err error) {
+	for _, t := range types {
+		nn, err := t.WriteTo(w)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
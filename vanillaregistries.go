@@ -0,0 +1,422 @@
+package proto
+
+import "fmt"
+
+// VanillaRegistries returns the registries a vanilla client of the given
+// protocol version needs to join a world: the vanilla dimension types, the
+// plains, nether wastes and the end biomes, and the vanilla elements of the
+// other registries of the version.
+// The versions supported are 1.16.2 to 1.21.1.
+func VanillaRegistries(protocol int32) (Registries, error) {
+	if protocol < Protocol1_16_2 || protocol > Protocol1_21 {
+		return nil, fmt.Errorf("%w: no vanilla registries for protocol %d", ErrUnsupportedProtocol, protocol)
+	}
+
+	registries := Registries{
+		{ID: DimensionTypeRegistry, Entries: vanillaDimensionTypes(protocol)},
+		{ID: BiomeRegistry, Entries: vanillaBiomes()},
+	}
+	if protocol >= Protocol1_19_1 {
+		registries = append(registries, Registry{ID: ChatTypeRegistry, Entries: vanillaChatTypes()})
+	} else if protocol >= Protocol1_19 {
+		registries = append(registries, Registry{ID: ChatTypeRegistry, Entries: vanillaChatTypes119()})
+	}
+	if protocol >= Protocol1_19_4 {
+		registries = append(registries, Registry{ID: DamageTypeRegistry, Entries: vanillaDamageTypes(protocol)})
+	}
+	if protocol >= Protocol1_20 {
+		registries = append(registries,
+			Registry{ID: TrimPatternRegistry, Entries: vanillaTrimPatterns(protocol)},
+			Registry{ID: TrimMaterialRegistry, Entries: vanillaTrimMaterials()},
+		)
+	}
+	if protocol >= Protocol1_20_5 {
+		registries = append(registries,
+			Registry{ID: BannerPatternRegistry, Entries: vanillaBannerPatterns(protocol)},
+			Registry{ID: WolfVariantRegistry, Entries: vanillaWolfVariants()},
+		)
+	}
+	if protocol >= Protocol1_21 {
+		// The jukebox songs and enchantments are only used by items
+		registries = append(registries,
+			Registry{ID: PaintingVariantRegistry, Entries: vanillaPaintingVariants()},
+			Registry{ID: JukeboxSongRegistry},
+			Registry{ID: EnchantmentRegistry},
+		)
+	}
+	return registries, nil
+}
+
+func vanillaDimensionTypes(protocol int32) []NamedElement {
+	netherTime, endTime := int64(18000), int64(6000)
+
+	overworld := &DimensionType{
+		HasSkylight:     true,
+		Natural:         true,
+		CoordinateScale: 1,
+		BedWorks:        true,
+		Height:          256,
+		LogicalHeight:   256,
+		Infiniburn:      "#minecraft:infiniburn_overworld",
+		Effects:         "minecraft:overworld",
+		HasRaids:        true,
+	}
+	if protocol >= Protocol1_18 {
+		overworld.MinY, overworld.Height, overworld.LogicalHeight = -64, 384, 384
+	}
+	overworldCaves := *overworld
+	overworldCaves.HasCeiling = true
+
+	return []NamedElement{
+		{"minecraft:overworld", overworld},
+		{"minecraft:overworld_caves", &overworldCaves},
+		{"minecraft:the_end", &DimensionType{
+			FixedTime:       &endTime,
+			CoordinateScale: 1,
+			Height:          256,
+			LogicalHeight:   256,
+			Infiniburn:      "#minecraft:infiniburn_end",
+			Effects:         "minecraft:the_end",
+			HasRaids:        true,
+		}},
+		{"minecraft:the_nether", &DimensionType{
+			FixedTime:                   &netherTime,
+			HasCeiling:                  true,
+			Ultrawarm:                   true,
+			CoordinateScale:             8,
+			RespawnAnchorWorks:          true,
+			Height:                      256,
+			LogicalHeight:               128,
+			Infiniburn:                  "#minecraft:infiniburn_nether",
+			Effects:                     "minecraft:the_nether",
+			AmbientLight:                0.1,
+			PiglinSafe:                  true,
+			MonsterSpawnLightLevel:      7,
+			MonsterSpawnBlockLightLimit: 15,
+		}},
+	}
+}
+
+func vanillaBiomes() []NamedElement {
+	caveMood := &BiomeMoodSound{Sound: "minecraft:ambient.cave", TickDelay: 6000, BlockSearchExtent: 8, Offset: 2}
+	return []NamedElement{
+		{"minecraft:plains", &Biome{
+			HasPrecipitation: true,
+			Temperature:      0.8,
+			Downfall:         0.4,
+			Category:         "plains",
+			Depth:            0.125,
+			Scale:            0.05,
+			Effects: BiomeEffects{
+				FogColor:      12638463,
+				WaterColor:    4159204,
+				WaterFogColor: 329011,
+				SkyColor:      7907327,
+				MoodSound:     caveMood,
+			},
+		}},
+		{"minecraft:nether_wastes", &Biome{
+			Temperature: 2,
+			Category:    "nether",
+			Depth:       0.1,
+			Scale:       0.2,
+			Effects: BiomeEffects{
+				FogColor:      3344392,
+				WaterColor:    4159204,
+				WaterFogColor: 329011,
+				SkyColor:      7254527,
+				AmbientSound:  "minecraft:ambient.nether_wastes.loop",
+				MoodSound: &BiomeMoodSound{
+					Sound:             "minecraft:ambient.nether_wastes.mood",
+					TickDelay:         6000,
+					BlockSearchExtent: 8,
+					Offset:            2,
+				},
+			},
+		}},
+		{"minecraft:the_end", &Biome{
+			Temperature: 0.5,
+			Downfall:    0.5,
+			Category:    "the_end",
+			Depth:       0.1,
+			Scale:       0.2,
+			Effects: BiomeEffects{
+				FogColor:      10518688,
+				WaterColor:    4159204,
+				WaterFogColor: 329011,
+				SkyColor:      0,
+				MoodSound:     caveMood,
+			},
+		}},
+	}
+}
+
+func vanillaChatTypes() []NamedElement {
+	narration := ChatDecoration{TranslationKey: "chat.type.text.narrate", Parameters: []string{"sender", "content"}}
+	italic := true
+	whisper := &TextComponent{Color: "gray", Italic: &italic}
+
+	return []NamedElement{
+		{"minecraft:chat", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "chat.type.text", Parameters: []string{"sender", "content"}},
+			Narration: narration,
+		}},
+		{"minecraft:emote_command", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "chat.type.emote", Parameters: []string{"sender", "content"}},
+			Narration: ChatDecoration{TranslationKey: "chat.type.emote", Parameters: []string{"sender", "content"}},
+		}},
+		{"minecraft:msg_command_incoming", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "commands.message.display.incoming", Parameters: []string{"sender", "content"}, Style: whisper},
+			Narration: narration,
+		}},
+		{"minecraft:msg_command_outgoing", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "commands.message.display.outgoing", Parameters: []string{"target", "content"}, Style: whisper},
+			Narration: narration,
+		}},
+		{"minecraft:say_command", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "chat.type.announcement", Parameters: []string{"sender", "content"}},
+			Narration: narration,
+		}},
+		{"minecraft:team_msg_command_incoming", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "chat.type.team.text", Parameters: []string{"target", "sender", "content"}},
+			Narration: narration,
+		}},
+		{"minecraft:team_msg_command_outgoing", &ChatType{
+			Chat:      ChatDecoration{TranslationKey: "chat.type.team.sent", Parameters: []string{"target", "sender", "content"}},
+			Narration: narration,
+		}},
+	}
+}
+
+// vanillaChatTypes119 returns the chat types of 1.19, whose layout and names differ
+func vanillaChatTypes119() []NamedElement {
+	narration := ChatDecoration{TranslationKey: "chat.type.text.narrate", Parameters: []string{"sender", "content"}}
+	italic := true
+	whisper := &TextComponent{Color: "gray", Italic: &italic}
+
+	return []NamedElement{
+		{"minecraft:chat", &ChatType{
+			Chat:              ChatDecoration{TranslationKey: "chat.type.text", Parameters: []string{"sender", "content"}},
+			Narration:         narration,
+			NarrationPriority: "chat",
+		}},
+		{"minecraft:system", &ChatType{NarrationPriority: "system"}},
+		{"minecraft:game_info", &ChatType{Overlay: &ChatDecoration{}}},
+		{"minecraft:say_command", &ChatType{
+			Chat:              ChatDecoration{TranslationKey: "chat.type.announcement", Parameters: []string{"sender", "content"}},
+			Narration:         narration,
+			NarrationPriority: "chat",
+		}},
+		{"minecraft:msg_command", &ChatType{
+			Chat:              ChatDecoration{TranslationKey: "commands.message.display.incoming", Parameters: []string{"sender", "content"}, Style: whisper},
+			Narration:         narration,
+			NarrationPriority: "chat",
+		}},
+		{"minecraft:team_msg_command", &ChatType{
+			Chat:              ChatDecoration{TranslationKey: "chat.type.team.text", Parameters: []string{"team_name", "sender", "content"}},
+			Narration:         narration,
+			NarrationPriority: "chat",
+		}},
+		{"minecraft:emote_command", &ChatType{
+			Chat:              ChatDecoration{TranslationKey: "chat.type.emote", Parameters: []string{"sender", "content"}},
+			Narration:         ChatDecoration{TranslationKey: "chat.type.emote", Parameters: []string{"sender", "content"}},
+			NarrationPriority: "chat",
+		}},
+		{"minecraft:tellraw_command", &ChatType{NarrationPriority: "chat"}},
+	}
+}
+
+func vanillaDamageTypes(protocol int32) []NamedElement {
+	const (
+		living = "when_caused_by_living_non_player"
+		always = "always"
+		never  = "never"
+	)
+	types := []struct {
+		name   string
+		since  int32
+		damage DamageType
+	}{
+		{"arrow", 0, DamageType{MessageID: "arrow", Scaling: living, Exhaustion: 0.1}},
+		{"bad_respawn_point", 0, DamageType{MessageID: "badRespawnPoint", Scaling: always, Exhaustion: 0.1, DeathMessageType: "intentional_game_design"}},
+		{"cactus", 0, DamageType{MessageID: "cactus", Scaling: living, Exhaustion: 0.1}},
+		{"cramming", 0, DamageType{MessageID: "cramming", Scaling: living}},
+		{"dragon_breath", 0, DamageType{MessageID: "dragonBreath", Scaling: living}},
+		{"drown", 0, DamageType{MessageID: "drown", Scaling: living, Effects: "drowning"}},
+		{"dry_out", 0, DamageType{MessageID: "dryout", Scaling: living, Exhaustion: 0.1}},
+		{"explosion", 0, DamageType{MessageID: "explosion", Scaling: always, Exhaustion: 0.1}},
+		{"fall", 0, DamageType{MessageID: "fall", Scaling: living, DeathMessageType: "fall_variants"}},
+		{"falling_anvil", 0, DamageType{MessageID: "anvil", Scaling: living, Exhaustion: 0.1}},
+		{"falling_block", 0, DamageType{MessageID: "fallingBlock", Scaling: living, Exhaustion: 0.1}},
+		{"falling_stalactite", 0, DamageType{MessageID: "fallingStalactite", Scaling: living, Exhaustion: 0.1}},
+		{"fireball", 0, DamageType{MessageID: "fireball", Scaling: living, Exhaustion: 0.1, Effects: "burning"}},
+		{"fireworks", 0, DamageType{MessageID: "fireworks", Scaling: living, Exhaustion: 0.1}},
+		{"fly_into_wall", 0, DamageType{MessageID: "flyIntoWall", Scaling: living}},
+		{"freeze", 0, DamageType{MessageID: "freeze", Scaling: living, Effects: "freezing"}},
+		{"generic", 0, DamageType{MessageID: "generic", Scaling: living}},
+		{"generic_kill", Protocol1_20, DamageType{MessageID: "genericKill", Scaling: living}},
+		{"hot_floor", 0, DamageType{MessageID: "hotFloor", Scaling: living, Exhaustion: 0.1, Effects: "burning"}},
+		{"in_fire", 0, DamageType{MessageID: "inFire", Scaling: living, Exhaustion: 0.1, Effects: "burning"}},
+		{"in_wall", 0, DamageType{MessageID: "inWall", Scaling: living}},
+		{"indirect_magic", 0, DamageType{MessageID: "indirectMagic", Scaling: living}},
+		{"lava", 0, DamageType{MessageID: "lava", Scaling: living, Exhaustion: 0.1, Effects: "burning"}},
+		{"lightning_bolt", 0, DamageType{MessageID: "lightningBolt", Scaling: living, Exhaustion: 0.1}},
+		{"mace_smash", Protocol1_21, DamageType{MessageID: "mace_smash", Scaling: living, Exhaustion: 0.1}},
+		{"magic", 0, DamageType{MessageID: "magic", Scaling: living}},
+		{"mob_attack", 0, DamageType{MessageID: "mob", Scaling: living, Exhaustion: 0.1}},
+		{"mob_attack_no_aggro", 0, DamageType{MessageID: "mob", Scaling: living, Exhaustion: 0.1}},
+		{"mob_projectile", 0, DamageType{MessageID: "mob", Scaling: living, Exhaustion: 0.1}},
+		{"on_fire", 0, DamageType{MessageID: "onFire", Scaling: living, Effects: "burning"}},
+		{"out_of_world", 0, DamageType{MessageID: "outOfWorld", Scaling: never}},
+		{"outside_border", Protocol1_20, DamageType{MessageID: "outsideBorder", Scaling: living}},
+		{"player_attack", 0, DamageType{MessageID: "player", Scaling: living, Exhaustion: 0.1}},
+		{"player_explosion", 0, DamageType{MessageID: "explosion.player", Scaling: always, Exhaustion: 0.1}},
+		{"sonic_boom", 0, DamageType{MessageID: "sonic_boom", Scaling: always}},
+		{"spit", Protocol1_20_5, DamageType{MessageID: "mob", Scaling: living, Exhaustion: 0.1}},
+		{"stalagmite", 0, DamageType{MessageID: "stalagmite", Scaling: living}},
+		{"starve", 0, DamageType{MessageID: "starve", Scaling: living}},
+		{"sting", 0, DamageType{MessageID: "sting", Scaling: living, Exhaustion: 0.1}},
+		{"sweet_berry_bush", 0, DamageType{MessageID: "sweetBerryBush", Scaling: living, Exhaustion: 0.1, Effects: "poking"}},
+		{"thorns", 0, DamageType{MessageID: "thorns", Scaling: living, Exhaustion: 0.1, Effects: "thorns"}},
+		{"thrown", 0, DamageType{MessageID: "thrown", Scaling: living, Exhaustion: 0.1}},
+		{"trident", 0, DamageType{MessageID: "trident", Scaling: living, Exhaustion: 0.1}},
+		{"unattributed_fireball", 0, DamageType{MessageID: "onFire", Scaling: living, Exhaustion: 0.1, Effects: "burning"}},
+		{"wind_charge", Protocol1_20_5, DamageType{MessageID: "mob", Scaling: living, Exhaustion: 0.1}},
+		{"wither", 0, DamageType{MessageID: "wither", Scaling: living}},
+		{"wither_skull", 0, DamageType{MessageID: "witherSkull", Scaling: living, Exhaustion: 0.1}},
+	}
+
+	entries := make([]NamedElement, 0, len(types))
+	for i := range types {
+		if protocol >= types[i].since {
+			entries = append(entries, NamedElement{"minecraft:" + types[i].name, &types[i].damage})
+		}
+	}
+	return entries
+}
+
+func vanillaTrimPatterns(protocol int32) []NamedElement {
+	names := []string{
+		"coast", "dune", "eye", "host", "raiser", "rib", "sentry", "shaper",
+		"silence", "snout", "spire", "tide", "vex", "ward", "wayfinder", "wild",
+	}
+	if protocol >= Protocol1_21 {
+		names = append(names, "bolt", "flow")
+	}
+
+	entries := make([]NamedElement, len(names))
+	for i, name := range names {
+		entries[i] = NamedElement{"minecraft:" + name, &TrimPattern{
+			AssetID:      "minecraft:" + name,
+			TemplateItem: "minecraft:" + name + "_armor_trim_smithing_template",
+			Description:  TextComponent{Translate: "trim_pattern.minecraft." + name},
+		}}
+	}
+	return entries
+}
+
+func vanillaTrimMaterials() []NamedElement {
+	materials := []struct {
+		name, ingredient, color string
+		index                   float32
+	}{
+		{"amethyst", "minecraft:amethyst_shard", "#9A5CC6", 1.0},
+		{"copper", "minecraft:copper_ingot", "#B4684D", 0.5},
+		{"diamond", "minecraft:diamond", "#6EECD2", 0.8},
+		{"emerald", "minecraft:emerald", "#11A036", 0.7},
+		{"gold", "minecraft:gold_ingot", "#DEB12D", 0.6},
+		{"iron", "minecraft:iron_ingot", "#ECECEC", 0.2},
+		{"lapis", "minecraft:lapis_lazuli", "#416E97", 0.9},
+		{"netherite", "minecraft:netherite_ingot", "#625859", 0.3},
+		{"quartz", "minecraft:quartz", "#E3D4C4", 0.1},
+		{"redstone", "minecraft:redstone", "#971607", 0.4},
+	}
+
+	entries := make([]NamedElement, len(materials))
+	for i, m := range materials {
+		entries[i] = NamedElement{"minecraft:" + m.name, &TrimMaterial{
+			AssetName:      m.name,
+			Ingredient:     m.ingredient,
+			ItemModelIndex: m.index,
+			Description:    TextComponent{Translate: "trim_material.minecraft." + m.name, Color: m.color},
+		}}
+	}
+	return entries
+}
+
+func vanillaBannerPatterns(protocol int32) []NamedElement {
+	names := []string{
+		"base", "border", "bricks", "circle", "creeper", "cross", "curly_border",
+		"diagonal_left", "diagonal_right", "diagonal_up_left", "diagonal_up_right",
+		"flower", "globe", "gradient", "gradient_up", "half_horizontal",
+		"half_horizontal_bottom", "half_vertical", "half_vertical_right", "mojang",
+		"piglin", "rhombus", "skull", "small_stripes", "square_bottom_left",
+		"square_bottom_right", "square_top_left", "square_top_right", "straight_cross",
+		"stripe_bottom", "stripe_center", "stripe_downleft", "stripe_downright",
+		"stripe_left", "stripe_middle", "stripe_right", "stripe_top",
+		"triangle_bottom", "triangle_top", "triangles_bottom", "triangles_top",
+	}
+	if protocol >= Protocol1_21 {
+		names = append(names, "flow", "guster")
+	}
+
+	entries := make([]NamedElement, len(names))
+	for i, name := range names {
+		entries[i] = NamedElement{"minecraft:" + name, &BannerPattern{
+			AssetID:        "minecraft:" + name,
+			TranslationKey: "block.minecraft.banner." + name,
+		}}
+	}
+	return entries
+}
+
+func vanillaWolfVariants() []NamedElement {
+	variants := []string{"ashen", "black", "chestnut", "pale", "rusty", "snowy", "spotted", "striped", "woods"}
+
+	entries := make([]NamedElement, len(variants))
+	for i, name := range variants {
+		texture := "minecraft:entity/wolf/wolf_" + name
+		if name == "pale" {
+			texture = "minecraft:entity/wolf/wolf"
+		}
+		// The biomes must be in the biome registry: they all spawn in plains
+		entries[i] = NamedElement{"minecraft:" + name, &WolfVariant{
+			WildTexture:  texture,
+			TameTexture:  texture + "_tame",
+			AngryTexture: texture + "_angry",
+			Biomes:       []string{"minecraft:plains"},
+		}}
+	}
+	return entries
+}
+
+func vanillaPaintingVariants() []NamedElement {
+	paintings := []struct {
+		name          string
+		width, height int32
+	}{
+		{"alban", 1, 1}, {"aztec", 1, 1}, {"aztec2", 1, 1}, {"backyard", 3, 4},
+		{"baroque", 2, 2}, {"bomb", 1, 1}, {"bouquet", 3, 3}, {"burning_skull", 4, 4},
+		{"bust", 2, 2}, {"cavebird", 3, 3}, {"changing", 4, 2}, {"cotan", 3, 3},
+		{"courbet", 2, 1}, {"creebet", 2, 1}, {"donkey_kong", 4, 3}, {"earth", 2, 2},
+		{"endboss", 3, 3}, {"fern", 3, 3}, {"fighters", 4, 2}, {"finding", 4, 2},
+		{"fire", 2, 2}, {"graham", 1, 2}, {"humble", 2, 2}, {"kebab", 1, 1},
+		{"lowmist", 4, 2}, {"match", 2, 2}, {"meditative", 1, 1}, {"orb", 4, 4},
+		{"owlemons", 3, 3}, {"passage", 4, 2}, {"pigscene", 4, 4}, {"plant", 1, 1},
+		{"pointer", 4, 4}, {"pond", 3, 4}, {"pool", 2, 1}, {"prairie_ride", 1, 2},
+		{"sea", 2, 1}, {"skeleton", 4, 3}, {"skull_and_roses", 2, 2}, {"stage", 2, 2},
+		{"sunflowers", 3, 3}, {"sunset", 2, 1}, {"tides", 3, 3}, {"unpacked", 4, 4},
+		{"void", 2, 2}, {"wanderer", 1, 2}, {"wasteland", 1, 1}, {"water", 2, 2},
+		{"wind", 2, 2}, {"wither", 2, 2},
+	}
+
+	entries := make([]NamedElement, len(paintings))
+	for i, p := range paintings {
+		entries[i] = NamedElement{"minecraft:" + p.name, &PaintingVariant{
+			AssetID: "minecraft:" + p.name,
+			Width:   p.width,
+			Height:  p.height,
+		}}
+	}
+	return entries
+}
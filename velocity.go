@@ -0,0 +1,293 @@
+package proto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Velocity modern forwarding sends the player information from a proxy to a
+// backend server during login, through a login plugin message, signed with a
+// secret shared by the proxy and the backend server.
+
+// VelocityPlayerInfoChannel is the login plugin channel used by Velocity modern forwarding.
+const VelocityPlayerInfoChannel = "velocity:player_info"
+
+// Velocity modern forwarding versions.
+const (
+	// VelocityForwardingDefault forwards the address, UUID, name and properties of the player.
+	VelocityForwardingDefault = 1
+	// VelocityForwardingWithKey also forwards the player key (1.19).
+	VelocityForwardingWithKey = 2
+	// VelocityForwardingWithKeyV2 also forwards the UUID the player key is signed for (1.19.1 to 1.19.2).
+	VelocityForwardingWithKeyV2 = 3
+	// VelocityForwardingLazySession does not forward the player key (1.19.3+).
+	VelocityForwardingLazySession = 4
+
+	// VelocityForwardingMaxVersion is the highest supported version.
+	VelocityForwardingMaxVersion = VelocityForwardingLazySession
+)
+
+// velocitySignatureLength is the length of the HMAC-SHA256 signature of the forwarded data.
+const velocitySignatureLength = sha256.Size
+
+var (
+	// ErrVelocityForwardingUnsupported is returned when the proxy did not understand the forwarding request.
+	ErrVelocityForwardingUnsupported = errors.New("velocity forwarding: not supported by the proxy")
+	// ErrVelocityInvalidSignature is returned when the forwarded data are not signed with the expected secret.
+	ErrVelocityInvalidSignature = errors.New("velocity forwarding: invalid signature")
+)
+
+// LoadVelocitySecret reads the forwarding secret shared by a proxy and its
+// backend servers from a file, like Velocity's forwarding.secret.
+func LoadVelocitySecret(filename string) ([]byte, error) {
+	secret, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("velocity forwarding: empty secret in %s", filename)
+	}
+	return secret, nil
+}
+
+// --- VelocityPlayerInfo ---
+
+// VelocityPlayerInfo is the player information forwarded by a Velocity proxy.
+type VelocityPlayerInfo struct {
+	// Version is the forwarding version the data are encoded with.
+	Version int
+	// ClientAddress is the IP address of the player.
+	ClientAddress string
+	UUID          UUID
+	Name          string
+	Properties    []ProfileProperty
+	// Key is the player key, only forwarded by versions 2 and 3.
+	Key *PlayerKey
+	// KeyHolder is the UUID the player key is signed for, if different from UUID.
+	// It is only forwarded by version 3.
+	KeyHolder *UUID
+}
+
+// NewVelocityForwardingRequest creates the login plugin request sent by a
+// backend server to ask the proxy for the player information, with the
+// highest forwarding version it supports.
+func NewVelocityForwardingRequest(messageID int32, maxVersion int) *LoginPluginRequest {
+	return &LoginPluginRequest{
+		MessageID: VarInt(messageID),
+		Channel:   VelocityPlayerInfoChannel,
+		Data:      RemainingBytes{byte(maxVersion)},
+	}
+}
+
+// VelocityRequestedVersion returns the highest forwarding version supported
+// by the backend server that sent the login plugin request.
+func VelocityRequestedVersion(req *LoginPluginRequest) (int, error) {
+	if req.Channel != VelocityPlayerInfoChannel {
+		return 0, fmt.Errorf("velocity forwarding: unexpected channel %q", req.Channel)
+	}
+	if len(req.Data) == 0 {
+		return VelocityForwardingDefault, nil
+	}
+	return int(req.Data[0]), nil
+}
+
+// NegotiateVelocityVersion returns the forwarding version a proxy should use
+// to answer a request for the given version, like Velocity does, given the
+// protocol version of the player's client and the revision of its key, zero if
+// it has none. The player key is only forwarded for clients from 1.19 to 1.19.2:
+// the version 2 keys only with VelocityForwardingWithKeyV2.
+func NegotiateVelocityVersion(requested int, protocol int32, keyRevision PlayerKeyRevision) int {
+	if requested > VelocityForwardingMaxVersion {
+		requested = VelocityForwardingMaxVersion
+	}
+	if requested <= VelocityForwardingDefault {
+		return VelocityForwardingDefault
+	}
+
+	if protocol >= Protocol1_19_3 {
+		if requested >= VelocityForwardingLazySession {
+			return VelocityForwardingLazySession
+		}
+		return VelocityForwardingDefault
+	}
+	switch keyRevision {
+	case PlayerKeyV1:
+		return VelocityForwardingWithKey
+	case PlayerKeyV2:
+		// The version 2 keys cannot be forwarded as version 1 keys
+		if requested >= VelocityForwardingWithKeyV2 {
+			return VelocityForwardingWithKeyV2
+		}
+	}
+	return VelocityForwardingDefault
+}
+
+// NewVelocityForwardingResponse creates the login plugin response sent by a
+// proxy to answer the forwarding request of a backend server.
+// The player information are signed with the secret.
+func NewVelocityForwardingResponse(messageID int32, secret []byte, info *VelocityPlayerInfo) (*LoginPluginResponse, error) {
+	data, err := info.Sign(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginPluginResponse{
+		MessageID:  VarInt(messageID),
+		Successful: true,
+		Data:       data,
+	}, nil
+}
+
+// ParseVelocityForwardingResponse verifies and parses the player information
+// forwarded in the login plugin response.
+func ParseVelocityForwardingResponse(resp *LoginPluginResponse, secret []byte) (*VelocityPlayerInfo, error) {
+	if !resp.Successful {
+		return nil, ErrVelocityForwardingUnsupported
+	}
+	info := new(VelocityPlayerInfo)
+	if err := info.Verify(resp.Data, secret); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Sign encodes the player information and signs them with the secret.
+func (info *VelocityPlayerInfo) Sign(secret []byte) ([]byte, error) {
+	if info.Version < VelocityForwardingDefault || info.Version > VelocityForwardingMaxVersion {
+		return nil, fmt.Errorf("velocity forwarding: unsupported version %d", info.Version)
+	}
+	withKey := info.Version == VelocityForwardingWithKey || info.Version == VelocityForwardingWithKeyV2
+	if withKey && info.Key == nil {
+		return nil, fmt.Errorf("velocity forwarding: version %d requires the player key", info.Version)
+	}
+
+	var payload bytes.Buffer
+	_, err := writeTypes(&payload,
+		VarInt(info.Version),
+		String(info.ClientAddress),
+		info.UUID,
+		String(info.Name),
+		ProfileProperties(info.Properties),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if withKey {
+		if _, err := info.Key.WriteTo(&payload); err != nil {
+			return nil, err
+		}
+	}
+	if info.Version == VelocityForwardingWithKeyV2 {
+		if _, err := Boolean(info.KeyHolder != nil).WriteTo(&payload); err != nil {
+			return nil, err
+		}
+		if info.KeyHolder != nil {
+			if _, err := info.KeyHolder.WriteTo(&payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload.Bytes())
+	return append(mac.Sum(nil), payload.Bytes()...), nil
+}
+
+// Verify checks that the data are signed with the secret and decodes the player information.
+func (info *VelocityPlayerInfo) Verify(data, secret []byte) error {
+	if len(data) < velocitySignatureLength {
+		return ErrVelocityInvalidSignature
+	}
+	signature, payload := data[:velocitySignatureLength], data[velocitySignatureLength:]
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrVelocityInvalidSignature
+	}
+
+	r := bytes.NewReader(payload)
+
+	var version VarInt
+	var address, name String
+	var properties ProfileProperties
+	if _, err := readTypes(r, &version, &address, &info.UUID, &name, &properties); err != nil {
+		return fmt.Errorf("velocity forwarding: %w", err)
+	}
+	if version < VelocityForwardingDefault || version > VelocityForwardingMaxVersion {
+		return fmt.Errorf("velocity forwarding: unsupported version %d", version)
+	}
+
+	info.Version = int(version)
+	info.ClientAddress = string(address)
+	info.Name = string(name)
+	info.Properties = properties
+	info.Key, info.KeyHolder = nil, nil
+
+	if info.Version == VelocityForwardingWithKey || info.Version == VelocityForwardingWithKeyV2 {
+		info.Key = new(PlayerKey)
+		if _, err := info.Key.ReadFrom(r); err != nil {
+			return fmt.Errorf("velocity forwarding: %w", err)
+		}
+	}
+	if info.Version == VelocityForwardingWithKeyV2 {
+		var hasHolder Boolean
+		if _, err := hasHolder.ReadFrom(r); err != nil {
+			return fmt.Errorf("velocity forwarding: %w", err)
+		}
+		if hasHolder {
+			info.KeyHolder = new(UUID)
+			if _, err := info.KeyHolder.ReadFrom(r); err != nil {
+				return fmt.Errorf("velocity forwarding: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RequestVelocityPlayerInfo asks the proxy on the other side of the connection
+// for the player information, during login, and verifies them with the secret.
+// It is used by backend servers.
+func (c *Conn) RequestVelocityPlayerInfo(messageID int32, secret []byte) (*VelocityPlayerInfo, error) {
+	if err := c.Send(NewVelocityForwardingRequest(messageID, VelocityForwardingMaxVersion)); err != nil {
+		return nil, err
+	}
+
+	var resp LoginPluginResponse
+	if err := c.Receive(&resp); err != nil {
+		return nil, err
+	}
+	if int32(resp.MessageID) != messageID {
+		return nil, fmt.Errorf("velocity forwarding: unexpected message ID %d", resp.MessageID)
+	}
+
+	return ParseVelocityForwardingResponse(&resp, secret)
+}
+
+// AnswerVelocityPlayerInfo answers the forwarding request of the backend server
+// on the other side of the connection with the player information, using the
+// negotiated version. It is used by proxies.
+// The protocol is the protocol version of the player's client.
+func (c *Conn) AnswerVelocityPlayerInfo(req *LoginPluginRequest, secret []byte, info VelocityPlayerInfo, protocol int32) error {
+	requested, err := VelocityRequestedVersion(req)
+	if err != nil {
+		return err
+	}
+	var keyRevision PlayerKeyRevision
+	if info.Key != nil {
+		keyRevision = PlayerKeyRevisionOf(protocol)
+	}
+	info.Version = NegotiateVelocityVersion(requested, protocol, keyRevision)
+
+	resp, err := NewVelocityForwardingResponse(int32(req.MessageID), secret, &info)
+	if err != nil {
+		return err
+	}
+	return c.Send(resp)
+}
@@ -0,0 +1,196 @@
+package proto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestVelocityPlayerInfo(t *testing.T) {
+	secret := []byte("secret")
+	id, holder := UUID{1, 2, 3}, UUID{4, 5, 6}
+	key := &PlayerKey{ExpiresAt: 1700000000000, PublicKey: ByteArray{1, 2}, Signature: ByteArray{3}}
+	properties := []ProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}}
+	// header is the payload shared by all the versions, after the version
+	header := append([]byte{9}, "127.0.0.1"...)
+	header = append(header, id[:]...)
+	header = append(header, 5, 'S', 't', 'e', 'v', 'e')
+	header = append(header, 1, 8)
+	header = append(header, "textures"...)
+	header = append(header, 4)
+	header = append(header, "e30="...)
+	header = append(header, 1, 4)
+	header = append(header, "c2ln"...)
+	keyData := []byte{0, 0, 0x01, 0x8B, 0xCF, 0xE5, 0x68, 0x00, 2, 1, 2, 1, 3}
+
+	tests := []struct {
+		info VelocityPlayerInfo
+		// extra is the payload after the properties
+		extra []byte
+		// want is the decoded information, if different from info
+		want *VelocityPlayerInfo
+	}{
+		{VelocityPlayerInfo{Version: VelocityForwardingDefault}, nil, nil},
+		// The key is only sent from version 2
+		{VelocityPlayerInfo{Version: VelocityForwardingDefault, Key: key, KeyHolder: &holder}, nil, &VelocityPlayerInfo{Version: VelocityForwardingDefault}},
+		{VelocityPlayerInfo{Version: VelocityForwardingWithKey, Key: key}, keyData, nil},
+		// The key holder is only sent by version 3
+		{VelocityPlayerInfo{Version: VelocityForwardingWithKey, Key: key, KeyHolder: &holder}, keyData, &VelocityPlayerInfo{Version: VelocityForwardingWithKey, Key: key}},
+		{VelocityPlayerInfo{Version: VelocityForwardingWithKeyV2, Key: key}, append(keyData, 0), nil},
+		{VelocityPlayerInfo{Version: VelocityForwardingWithKeyV2, Key: key, KeyHolder: &holder}, append(append(keyData, 1), holder[:]...), nil},
+		{VelocityPlayerInfo{Version: VelocityForwardingLazySession}, nil, nil},
+	}
+	for _, test := range tests {
+		test.info.ClientAddress, test.info.UUID, test.info.Name, test.info.Properties = "127.0.0.1", id, "Steve", properties
+		data, err := test.info.Sign(secret)
+		if err != nil {
+			t.Fatalf("version %d: %v", test.info.Version, err)
+		}
+
+		payload := append(append([]byte{byte(test.info.Version)}, header...), test.extra...)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(payload)
+		if want := append(mac.Sum(nil), payload...); !bytes.Equal(data, want) {
+			t.Errorf("version %d: got % X, want % X", test.info.Version, data, want)
+		}
+
+		want := test.info
+		if test.want != nil {
+			want = *test.want
+			want.ClientAddress, want.UUID, want.Name, want.Properties = "127.0.0.1", id, "Steve", properties
+		}
+		got := VelocityPlayerInfo{Key: &PlayerKey{}, KeyHolder: &UUID{9}}
+		if err := got.Verify(data, secret); err != nil {
+			t.Errorf("version %d: %v", test.info.Version, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: got %+v, want %+v", test.info.Version, got, want)
+		}
+	}
+}
+
+func TestVelocityPlayerInfoSignature(t *testing.T) {
+	info := VelocityPlayerInfo{Version: VelocityForwardingDefault, ClientAddress: "127.0.0.1", UUID: UUID{1}, Name: "Steve"}
+	data, err := info.Sign([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(i int) []byte {
+		b := append([]byte(nil), data...)
+		b[i] ^= 1
+		return b
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		secret string
+	}{
+		{"wrong secret", data, "secret2"},
+		{"empty secret", data, ""},
+		{"tampered signature", tamper(0), "secret"},
+		{"tampered payload", tamper(len(data) - 1), "secret"},
+		{"truncated payload", data[:len(data)-1], "secret"},
+		{"truncated signature", data[:velocitySignatureLength-1], "secret"},
+	}
+	for _, test := range tests {
+		var got VelocityPlayerInfo
+		if err := got.Verify(test.data, []byte(test.secret)); !errors.Is(err, ErrVelocityInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrVelocityInvalidSignature", test.name, err)
+		}
+	}
+
+	// The version is checked in signed data too
+	for _, version := range []int{0, VelocityForwardingMaxVersion + 1} {
+		info.Version = version
+		if _, err := info.Sign([]byte("secret")); err == nil {
+			t.Errorf("version %d: signed", version)
+		}
+	}
+	info.Version = VelocityForwardingWithKey
+	if _, err := info.Sign([]byte("secret")); err == nil {
+		t.Error("version 2 without key: signed")
+	}
+}
+
+func TestNegotiateVelocityVersion(t *testing.T) {
+	// From Velocity's LoginSessionHandler.findForwardingVersion
+	tests := []struct {
+		requested   int
+		protocol    int32
+		keyRevision PlayerKeyRevision
+		want        int
+	}{
+		{0, Protocol1_21, 0, VelocityForwardingDefault},
+		{1, Protocol1_21, 0, VelocityForwardingDefault},
+		{1, Protocol1_19, PlayerKeyV1, VelocityForwardingDefault},
+		{2, Protocol1_18_2, 0, VelocityForwardingDefault},
+		{2, Protocol1_19, 0, VelocityForwardingDefault},
+		{2, Protocol1_19, PlayerKeyV1, VelocityForwardingWithKey},
+		{4, Protocol1_19, PlayerKeyV1, VelocityForwardingWithKey},
+		{2, Protocol1_19_1, PlayerKeyV2, VelocityForwardingDefault},
+		{3, Protocol1_19_1, PlayerKeyV2, VelocityForwardingWithKeyV2},
+		{4, Protocol1_19_1, PlayerKeyV2, VelocityForwardingWithKeyV2},
+		{3, Protocol1_19_1, 0, VelocityForwardingDefault},
+		{3, Protocol1_19_3, 0, VelocityForwardingDefault},
+		{4, Protocol1_19_3, 0, VelocityForwardingLazySession},
+		{4, Protocol1_19_3, PlayerKeyV2, VelocityForwardingLazySession},
+		{255, Protocol1_21, 0, VelocityForwardingLazySession},
+	}
+	for _, test := range tests {
+		if got := NegotiateVelocityVersion(test.requested, test.protocol, test.keyRevision); got != test.want {
+			t.Errorf("requested %d, protocol %d, key %d: got %d, want %d", test.requested, test.protocol, test.keyRevision, got, test.want)
+		}
+	}
+}
+
+func TestVelocityForwarding(t *testing.T) {
+	secret := []byte("secret")
+	key := &PlayerKey{ExpiresAt: 1, PublicKey: ByteArray{1}, Signature: ByteArray{2}}
+	info := VelocityPlayerInfo{ClientAddress: "127.0.0.1", UUID: UUID{1}, Name: "Steve", Key: key}
+
+	for _, test := range []struct {
+		protocol int32
+		version  int
+	}{
+		{Protocol1_19, VelocityForwardingWithKey},
+		{Protocol1_19_1, VelocityForwardingWithKeyV2},
+		{Protocol1_20_5, VelocityForwardingLazySession},
+	} {
+		backend, proxy := net.Pipe()
+		go func() {
+			defer proxy.Close()
+			conn := NewConn(proxy)
+			var req LoginPluginRequest
+			if err := conn.Receive(&req); err != nil {
+				t.Error(err)
+				return
+			}
+			info := info
+			if test.version == VelocityForwardingLazySession {
+				info.Key = nil
+			}
+			if err := conn.AnswerVelocityPlayerInfo(&req, secret, info, test.protocol); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		got, err := NewConn(backend).RequestVelocityPlayerInfo(7, secret)
+		backend.Close()
+		if err != nil {
+			t.Fatalf("protocol %d: %v", test.protocol, err)
+		}
+		if got.Version != test.version || got.Name != "Steve" {
+			t.Errorf("protocol %d: got %+v, want version %d", test.protocol, got, test.version)
+		}
+	}
+
+	// A proxy without forwarding does not understand the request
+	_, err := ParseVelocityForwardingResponse(&LoginPluginResponse{MessageID: 7}, secret)
+	if !errors.Is(err, ErrVelocityForwardingUnsupported) {
+		t.Errorf("got %v, want ErrVelocityForwardingUnsupported", err)
+	}
+}